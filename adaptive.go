@@ -0,0 +1,31 @@
+package main
+
+import "time"
+
+// Latency thresholds, as fractions of the timeout, for -adaptive-interval
+const (
+	adaptiveSlowFraction = 0.8
+	adaptiveFastFraction = 0.1
+)
+
+// adjustInterval returns the next check interval in seconds given the latest latency
+// Slow responses near the timeout double the interval to ease load on a struggling
+// server; fast responses halve it for quicker detection. The result stays within
+// minInterval and maxInterval.
+func adjustInterval(current int, latency, timeout time.Duration, minInterval, maxInterval int) int {
+	next := current
+	switch {
+	case float64(latency) > adaptiveSlowFraction*float64(timeout):
+		next = current * 2
+	case float64(latency) < adaptiveFastFraction*float64(timeout):
+		next = current / 2
+	}
+
+	if next > maxInterval {
+		next = maxInterval
+	}
+	if next < minInterval {
+		next = minInterval
+	}
+	return next
+}
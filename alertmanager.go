@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// alertmanagerSilencer silences an outage's alerts in Prometheus Alertmanager
+// The ID of the open silence is kept in a state file so a restart during the
+// outage still expires it on recovery
+type alertmanagerSilencer struct {
+	addr      string
+	label     string
+	duration  time.Duration
+	statePath string
+	client    *http.Client
+
+	// id is the open silence, if any
+	id string
+}
+
+// newAlertmanagerSilencer creates a silencer for the Alertmanager at addr that
+// matches alerts whose label equals the URL
+func newAlertmanagerSilencer(addr, label string, duration time.Duration, statePath string, timeout time.Duration) *alertmanagerSilencer {
+	s := &alertmanagerSilencer{
+		addr:      strings.TrimRight(addr, "/"),
+		label:     label,
+		duration:  duration,
+		statePath: statePath,
+		client:    &http.Client{Timeout: timeout},
+	}
+	if data, err := os.ReadFile(statePath); err == nil {
+		s.id = strings.TrimSpace(string(data))
+	}
+	return s
+}
+
+// silence creates a silence for url unless one is already open for this outage
+func (s *alertmanagerSilencer) silence(url, comment string) {
+	if s.id != "" {
+		return
+	}
+	now := time.Now().UTC()
+	body, err := json.Marshal(map[string]any{
+		"matchers": []map[string]any{
+			{"name": s.label, "value": url, "isRegex": false, "isEqual": true},
+		},
+		"startsAt":  now.Format(time.RFC3339),
+		"endsAt":    now.Add(s.duration).Format(time.RFC3339),
+		"createdBy": "websitecheck",
+		"comment":   comment,
+	})
+	if err != nil {
+		log.Printf("WARNING: Cannot create Alertmanager silence for %s: %v", url, err)
+		return
+	}
+
+	resp, err := s.client.Post(s.addr+"/api/v2/silences", "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("WARNING: Cannot create Alertmanager silence for %s: %v", url, err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("WARNING: Cannot create Alertmanager silence for %s: %v", url, alertmanagerError(resp))
+		return
+	}
+	var created struct {
+		SilenceID string `json:"silenceID"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil || created.SilenceID == "" {
+		log.Printf("WARNING: Alertmanager returned no silence ID for %s: %v", url, err)
+		return
+	}
+
+	s.id = created.SilenceID
+	if err := os.WriteFile(s.statePath, []byte(s.id+"\n"), 0644); err != nil {
+		log.Printf("WARNING: Cannot write Alertmanager silence ID to %s: %v", s.statePath, err)
+	}
+	log.Printf("Created Alertmanager silence %s for %s until %s", s.id, url, now.Add(s.duration).Format(time.RFC3339))
+}
+
+// expire ends the open silence early, if there is one
+// The silence is forgotten even if Alertmanager cannot be reached, as it
+// expires on its own after -alertmanager-silence-duration
+func (s *alertmanagerSilencer) expire() {
+	if s.id == "" {
+		return
+	}
+	id := s.id
+	s.id = ""
+	if err := os.Remove(s.statePath); err != nil && !os.IsNotExist(err) {
+		log.Printf("WARNING: Cannot remove Alertmanager silence state %s: %v", s.statePath, err)
+	}
+
+	// Alertmanager serves DELETE on the singular /api/v2/silence path
+	req, err := http.NewRequest(http.MethodDelete, s.addr+"/api/v2/silence/"+url.PathEscape(id), nil)
+	if err != nil {
+		log.Printf("WARNING: Cannot expire Alertmanager silence %s: %v", id, err)
+		return
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		log.Printf("WARNING: Cannot expire Alertmanager silence %s: %v", id, err)
+		return
+	}
+	defer resp.Body.Close()
+	// A silence that already expired or was removed is reported as not found
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		log.Printf("WARNING: Cannot expire Alertmanager silence %s: %v", id, alertmanagerError(resp))
+		return
+	}
+	log.Printf("Expired Alertmanager silence %s", id)
+}
+
+// alertmanagerError describes a failed Alertmanager API response
+func alertmanagerError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if message := strings.TrimSpace(string(body)); message != "" {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, message)
+	}
+	return fmt.Errorf("status %d", resp.StatusCode)
+}
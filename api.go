@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiServer serves the HTTP API alongside the monitoring loop
+type apiServer struct {
+	schedules []checkSchedule
+	status    *statusTracker
+	config    *configSnapshot
+
+	// peers is nil unless -matrix-peers is set
+	peers *peerPoller
+
+	// events streams down and recovery events to GET /events clients
+	events *eventStream
+
+	// results streams every check result to GET /events/sse clients
+	results *resultStream
+
+	// logs holds recent log lines for GET /logs, which requires apiToken
+	logs     *logBuffer
+	apiToken string
+
+	// trigger runs one check for POST /checks/{url}/trigger, waiting up to triggerTimeout
+	// It is nil when the check cannot be run out of cycle
+	trigger        func(ctx context.Context) (*checkResult, error)
+	triggerTimeout time.Duration
+
+	// corsOrigins are the origins allowed to call the API from a browser; "*" allows any
+	corsOrigins []string
+}
+
+// statusReport is the body of GET /status
+type statusReport struct {
+	Checks []urlStatus   `json:"checks"`
+	Peers  []peerStatus  `json:"peers,omitempty"`
+	Matrix []matrixEntry `json:"matrix,omitempty"`
+
+	// Silences are the alerts acknowledged through POST /alerts/{url}/silence or POST /suppress
+	Silences []alertSilence `json:"silences,omitempty"`
+}
+
+// handler returns the routes served by the API
+func (s *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("GET /status.html", s.handleStatusPage)
+	mux.HandleFunc("GET /calendar.ics", s.handleCalendar)
+	mux.HandleFunc("POST /suppress", s.requireToken(s.handleSuppress))
+	mux.HandleFunc("GET /config", s.requireToken(s.handleConfig))
+	mux.HandleFunc("GET /events", s.events.handleEvents)
+	mux.HandleFunc("GET /events/sse", s.results.handleSSE)
+	mux.HandleFunc("GET /logs", s.logs.handleLogs(s.apiToken))
+	mux.HandleFunc("POST /checks/{url}/trigger", s.requireToken(s.handleTrigger))
+	mux.HandleFunc("POST /alerts/{url}/silence", s.requireToken(s.handleSilence))
+	mux.HandleFunc("DELETE /alerts/{url}/silence", s.requireToken(s.handleUnsilence))
+	return s.withCORS(mux)
+}
+
+// requireToken makes next require apiToken as a bearer token
+// Every endpoint that changes state, makes the monitor act or shows its
+// configuration is wrapped in it
+func (s *apiServer) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if requireBearerToken(w, r, s.apiToken, r.Pattern) {
+			next(w, r)
+		}
+	}
+}
+
+// withCORS adds CORS headers for the -api-cors-origins origins and answers preflight requests
+func (s *apiServer) withCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !s.corsAllowed(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, Last-Event-ID")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// corsAllowed reports whether a browser page from origin may call the API
+func (s *apiServer) corsAllowed(origin string) bool {
+	return originAllowed(s.corsOrigins, origin)
+}
+
+// originAllowed reports whether origin is one of origins, or origins holds "*"
+func originAllowed(origins []string, origin string) bool {
+	for _, allowed := range origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// start serves the API on addr in the background
+// Only a triggered check takes the check cycle lock, so the API stays responsive
+// while a cycle runs
+func (s *apiServer) start(addr string) {
+	go func() {
+		log.Printf("API server listening on %s", addr)
+		if err := http.ListenAndServe(addr, s.handler()); err != nil {
+			log.Fatalf("Error: API server failed: %v", err)
+		}
+	}()
+}
+
+// report builds the current status report
+func (s *apiServer) report() statusReport {
+	report := statusReport{Checks: s.status.snapshot(), Silences: alertSuppressions.list()}
+	if s.peers != nil {
+		report.Peers = s.peers.snapshot()
+		report.Matrix = buildMatrix(report.Checks, report.Peers)
+	}
+	return report
+}
+
+// handleStatus returns the status of every check as JSON
+func (s *apiServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.report()); err != nil {
+		log.Printf("Failed to write status: %v", err)
+	}
+}
+
+// handleConfig returns the effective configuration as JSON with secrets redacted
+func (s *apiServer) handleConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.config.get()); err != nil {
+		log.Printf("Failed to write config: %v", err)
+	}
+}
+
+// statusPage renders the status report as HTML
+var statusPage = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta http-equiv="refresh" content="30"><title>websitecheck status</title></head>
+<body>
+<h1>websitecheck status</h1>
+<table border="1" cellpadding="4">
+<tr><th>URL</th><th>Status</th><th>Last check</th><th>Failures</th><th>30-day uptime</th><th>Error</th></tr>
+{{range .Checks}}<tr><td>{{.URL}}</td><td>{{if .Up}}UP{{else}}DOWN{{end}}</td><td>{{or .LastCheckLocal (.LastCheck.Format "2006-01-02 15:04:05 MST")}}</td><td>{{.ConsecutiveFailures}}</td><td>{{with .Uptime30d}}{{printf "%.2f%%" .}}{{end}}</td><td>{{.LastError}}</td></tr>
+{{end}}</table>
+{{if .Matrix}}<h2>Reachability matrix</h2>
+<table border="1" cellpadding="4">
+<tr><th>URL</th><th>Verdict</th><th>Down from</th></tr>
+{{range .Matrix}}<tr><td>{{.URL}}</td><td>{{.Verdict}}</td><td>{{range $i, $s := .DownFrom}}{{if $i}}, {{end}}{{$s}}{{end}}</td></tr>
+{{end}}</table>{{end}}
+{{range .Peers}}{{if .Error}}<p>Peer {{.Peer}} unreachable: {{.Error}}</p>{{end}}{{end}}
+</body>
+</html>
+`))
+
+// handleStatusPage returns the status report as an HTML page
+func (s *apiServer) handleStatusPage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := statusPage.Execute(w, s.report()); err != nil {
+		log.Printf("Failed to render status page: %v", err)
+	}
+}
+
+// handleCalendar returns the check schedule as an iCalendar file
+func (s *apiServer) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="websitecheck.ics"`)
+	if err := writeCalendar(w, s.schedules); err != nil {
+		log.Printf("Failed to write calendar: %v", err)
+	}
+}
+
+// handleSuppress is POST /alerts/{url}/silence with the URL and duration in
+// the query, kept for clients of the older endpoint. duration=0 lifts the silence
+func (s *apiServer) handleSuppress(w http.ResponseWriter, r *http.Request) {
+	url, ok := s.checkedURL(w, r.URL.Query().Get("url"))
+	if !ok {
+		return
+	}
+
+	duration := defaultSuppressDuration
+	if value := r.URL.Query().Get("duration"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			http.Error(w, "duration must be a non-negative number of seconds", http.StatusBadRequest)
+			return
+		}
+		duration = time.Duration(seconds) * time.Second
+	}
+	if duration == 0 {
+		s.unsilence(w, r, url)
+		return
+	}
+	s.silence(w, r, url, duration, "")
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// requireBearerToken reports whether r carries token as its bearer token, and
+// otherwise writes an error response. With no token configured every request
+// is refused, so an endpoint is never left open by a missing -api-token
+func requireBearerToken(w http.ResponseWriter, r *http.Request, token, endpoint string) bool {
+	if token == "" {
+		http.Error(w, endpoint+" requires -api-token", http.StatusForbidden)
+		return false
+	}
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="websitecheck"`)
+		http.Error(w, "missing or invalid bearer token", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// awsRotationRecheck is how soon to fetch again when a rotation is due or in progress
+const awsRotationRecheck = 5 * time.Minute
+
+// awsSecretSource reads HTTP credentials from an AWS Secrets Manager secret
+// The secret string is JSON with username, password and/or bearer_token fields
+type awsSecretSource struct {
+	arn     string
+	timeout time.Duration
+	client  *secretsmanager.Client
+}
+
+// newAWSSecretSource creates a Secrets Manager credential source using the
+// standard AWS credential chain. The region defaults to the one in the ARN.
+func newAWSSecretSource(arn string, timeout time.Duration) (*awsSecretSource, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config: %v", err)
+	}
+	if cfg.Region == "" {
+		// arn:aws:secretsmanager:<region>:<account>:secret:<name>
+		if parts := strings.Split(arn, ":"); len(parts) > 3 {
+			cfg.Region = parts[3]
+		}
+	}
+
+	return &awsSecretSource{
+		arn:     arn,
+		timeout: timeout,
+		client:  secretsmanager.NewFromConfig(cfg),
+	}, nil
+}
+
+// name implements credentialSource
+func (a *awsSecretSource) name() string {
+	return "AWS Secrets Manager " + a.arn
+}
+
+// fetch implements credentialSource
+// The credentials are valid until the secret's next scheduled rotation
+func (a *awsSecretSource) fetch() (httpCredentials, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	value, err := a.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(a.arn),
+	})
+	if err != nil {
+		return httpCredentials{}, 0, err
+	}
+	if value.SecretString == nil {
+		return httpCredentials{}, 0, errors.New("secret has no string value")
+	}
+
+	var secret struct {
+		Username    string `json:"username"`
+		Password    string `json:"password"`
+		BearerToken string `json:"bearer_token"`
+	}
+	if err := json.Unmarshal([]byte(*value.SecretString), &secret); err != nil {
+		return httpCredentials{}, 0, fmt.Errorf("secret is not valid JSON: %v", err)
+	}
+	creds := httpCredentials{
+		Username:    secret.Username,
+		Password:    secret.Password,
+		BearerToken: secret.BearerToken,
+	}
+	if creds == (httpCredentials{}) {
+		return httpCredentials{}, 0, errors.New("secret has no username, password or bearer_token fields")
+	}
+
+	return creds, a.untilRotation(ctx), nil
+}
+
+// untilRotation returns the time until the secret's next scheduled rotation
+// Returns zero if rotation is disabled or cannot be determined
+func (a *awsSecretSource) untilRotation(ctx context.Context) time.Duration {
+	desc, err := a.client.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(a.arn),
+	})
+	if err != nil || !aws.ToBool(desc.RotationEnabled) || desc.NextRotationDate == nil {
+		return 0
+	}
+
+	// A rotation that is due may still be in progress, so check again soon
+	until := time.Until(*desc.NextRotationDate)
+	if until <= 0 {
+		return awsRotationRecheck
+	}
+	return until
+}
@@ -0,0 +1,42 @@
+package main
+
+import "fmt"
+
+// BackoffMode controls how the backoff grows between consecutive failures
+type BackoffMode string
+
+// Supported backoff modes
+const (
+	BackoffExponential BackoffMode = "exponential"
+	BackoffLinear      BackoffMode = "linear"
+	BackoffConstant    BackoffMode = "constant"
+)
+
+// parseBackoffMode validates a -backoff-mode value
+func parseBackoffMode(s string) (BackoffMode, error) {
+	switch mode := BackoffMode(s); mode {
+	case BackoffExponential, BackoffLinear, BackoffConstant:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown backoff mode %q (expected exponential, linear or constant)", s)
+	}
+}
+
+// calculateNextBackoff returns the backoff in seconds following current
+// The result is always capped at max
+func calculateNextBackoff(current, initial, increment int, factor float64, max int, mode BackoffMode) int {
+	var next int
+	switch mode {
+	case BackoffLinear:
+		next = current + increment
+	case BackoffConstant:
+		next = initial
+	default:
+		next = int(float64(current) * factor)
+	}
+
+	if next > max {
+		return max
+	}
+	return next
+}
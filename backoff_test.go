@@ -0,0 +1,80 @@
+package main
+
+import "testing"
+
+func TestCalculateNextBackoff(t *testing.T) {
+	tests := []struct {
+		name      string
+		current   int
+		initial   int
+		increment int
+		factor    float64
+		max       int
+		mode      BackoffMode
+		want      int
+	}{
+		{"exponential doubles", 60, 60, 30, 2, 3600, BackoffExponential, 120},
+		{"exponential uses factor", 100, 60, 30, 1.5, 3600, BackoffExponential, 150},
+		{"exponential capped at max", 2400, 60, 30, 2, 3600, BackoffExponential, 3600},
+		{"exponential stays at max", 3600, 60, 30, 2, 3600, BackoffExponential, 3600},
+		{"unset mode is exponential", 60, 60, 30, 2, 3600, "", 120},
+		{"linear adds increment", 60, 60, 30, 2, 3600, BackoffLinear, 90},
+		{"linear ignores factor", 90, 60, 30, 10, 3600, BackoffLinear, 120},
+		{"linear capped at max", 3590, 60, 30, 2, 3600, BackoffLinear, 3600},
+		{"constant keeps initial", 60, 60, 30, 2, 3600, BackoffConstant, 60},
+		{"constant resets to initial", 600, 60, 30, 2, 3600, BackoffConstant, 60},
+		{"constant capped at max", 60, 120, 30, 2, 90, BackoffConstant, 90},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := calculateNextBackoff(tt.current, tt.initial, tt.increment, tt.factor, tt.max, tt.mode)
+			if got != tt.want {
+				t.Errorf("calculateNextBackoff(%d, %d, %d, %g, %d, %q) = %d, want %d",
+					tt.current, tt.initial, tt.increment, tt.factor, tt.max, tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateNextBackoffGrowth(t *testing.T) {
+	tests := []struct {
+		mode BackoffMode
+		want []int
+	}{
+		{BackoffExponential, []int{20, 40, 80, 100, 100}},
+		{BackoffLinear, []int{15, 20, 25, 30, 35}},
+		{BackoffConstant, []int{10, 10, 10, 10, 10}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			current := 10
+			for i, want := range tt.want {
+				current = calculateNextBackoff(current, 10, 5, 2, 100, tt.mode)
+				if current != want {
+					t.Fatalf("failure %d: backoff %d, want %d", i+1, current, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseBackoffMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    BackoffMode
+		wantErr bool
+	}{
+		{"exponential", BackoffExponential, false},
+		{"linear", BackoffLinear, false},
+		{"constant", BackoffConstant, false},
+		{"", "", true},
+		{"fibonacci", "", true},
+		{"Linear", "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseBackoffMode(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseBackoffMode(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// benchmarkReport summarizes the latencies of a benchmark run
+type benchmarkReport struct {
+	requests  int
+	failures  int
+	elapsed   time.Duration
+	latencies []time.Duration
+}
+
+// percentile returns the latency below which p percent of successful requests completed
+func (r benchmarkReport) percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	i := int(p / 100 * float64(len(r.latencies)-1))
+	return r.latencies[i]
+}
+
+// write prints the report in the style of ab and hey
+func (r benchmarkReport) write(w io.Writer) {
+	fmt.Fprintf(w, "Requests:    %d (%d failed)\n", r.requests, r.failures)
+	fmt.Fprintf(w, "Total time:  %s\n", r.elapsed.Round(time.Millisecond))
+	if r.elapsed > 0 {
+		fmt.Fprintf(w, "Throughput:  %.1f requests/s\n", float64(r.requests)/r.elapsed.Seconds())
+	}
+	if len(r.latencies) == 0 {
+		return
+	}
+
+	var total time.Duration
+	for _, latency := range r.latencies {
+		total += latency
+	}
+	fmt.Fprintf(w, "Latency:     min %s, mean %s, max %s\n",
+		r.latencies[0].Round(time.Microsecond),
+		(total / time.Duration(len(r.latencies))).Round(time.Microsecond),
+		r.latencies[len(r.latencies)-1].Round(time.Microsecond))
+	for _, p := range []float64{50, 90, 95, 99} {
+		fmt.Fprintf(w, "  p%-3g       %s\n", p, r.percentile(p).Round(time.Microsecond))
+	}
+}
+
+// runBenchmark sends requests checks of url from concurrency workers
+// Failed checks are counted but their latency is not included
+func runBenchmark(ctx context.Context, url string, client *http.Client, opts checkOptions, requests, concurrency int) benchmarkReport {
+	// Retrying would hide the latency of the failed attempt
+	opts.retries = 1
+	results := make(chan *checkResult, requests)
+	jobs := make(chan struct{})
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				result, err := checkWebsiteDown(ctx, url, client, opts)
+				if err != nil {
+					result = nil
+				}
+				results <- result
+			}
+		}()
+	}
+	for i := 0; i < requests; i++ {
+		jobs <- struct{}{}
+	}
+	close(jobs)
+	wg.Wait()
+	close(results)
+
+	report := benchmarkReport{requests: requests, elapsed: time.Since(start)}
+	for result := range results {
+		if result == nil {
+			report.failures++
+			continue
+		}
+		report.latencies = append(report.latencies, result.Latency)
+	}
+	sort.Slice(report.latencies, func(i, j int) bool { return report.latencies[i] < report.latencies[j] })
+	return report
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// blocklist is a set of IP prefixes loaded from a file
+// It is safe for concurrent use so it can be reloaded on SIGHUP
+type blocklist struct {
+	path string
+
+	mu       sync.RWMutex
+	prefixes []netip.Prefix
+}
+
+// loadBlocklist reads a blocklist file containing one IP or CIDR per line
+// Blank lines and lines starting with # are ignored
+func loadBlocklist(path string) (*blocklist, error) {
+	b := &blocklist{path: path}
+	if err := b.reload(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// reload re-reads the blocklist file, keeping the old entries if it fails
+func (b *blocklist) reload() error {
+	f, err := os.Open(b.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var prefixes []netip.Prefix
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		prefix, err := parseBlocklistEntry(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", b.path, lineNum, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.prefixes = prefixes
+	b.mu.Unlock()
+	return nil
+}
+
+// parseBlocklistEntry parses a single IP address or CIDR prefix
+func parseBlocklistEntry(entry string) (netip.Prefix, error) {
+	if strings.Contains(entry, "/") {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// match returns the first prefix containing addr
+func (b *blocklist) match(addr netip.Addr) (netip.Prefix, bool) {
+	addr = addr.Unmap()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, prefix := range b.prefixes {
+		if prefix.Contains(addr) {
+			return prefix, true
+		}
+	}
+	return netip.Prefix{}, false
+}
+
+// size returns the number of entries in the blocklist
+func (b *blocklist) size() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.prefixes)
+}
+
+// checkBlocklist resolves host and logs a warning for every address on the blocklist
+// The lookup gives up after timeout or once ctx is done, so a hung resolver
+// cannot stall the check loop. Returns true if any resolved address is blocklisted
+func checkBlocklist(ctx context.Context, host string, timeout time.Duration, b *blocklist) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		log.Printf("Blocklist check: failed to resolve %s: %v", host, err)
+		return false
+	}
+
+	blocked := false
+	for _, ip := range ips {
+		addr, ok := netip.AddrFromSlice(ip.IP)
+		if !ok {
+			continue
+		}
+		if prefix, found := b.match(addr); found {
+			log.Printf("WARNING: %s resolves to blocklisted address %s (matched %s)", host, addr.Unmap(), prefix)
+			blocked = true
+		}
+	}
+	return blocked
+}
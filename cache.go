@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cacheBustParam is the query parameter added to bypass CDN caches
+const cacheBustParam = "_t"
+
+// cacheBustURL adds a unique query parameter to rawURL so CDNs forward the request to the origin
+func cacheBustURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	query.Set(cacheBustParam, strconv.FormatInt(time.Now().UnixNano(), 10))
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// setNoCacheHeaders asks caches along the way not to serve a stored response
+func setNoCacheHeaders(req *http.Request) {
+	req.Header.Set("Cache-Control", "no-cache")
+	req.Header.Set("Pragma", "no-cache")
+}
+
+// cacheStatus returns the cache status reported by the response and whether it was a hit
+// X-Cache is checked first, then the Cloudflare CF-Cache-Status header
+func cacheStatus(header http.Header) (string, bool) {
+	for _, name := range []string{"X-Cache", "CF-Cache-Status"} {
+		if value := header.Get(name); value != "" {
+			return value, strings.Contains(strings.ToUpper(value), "HIT")
+		}
+	}
+	return "", false
+}
+
+// describeCache summarizes the caching headers of a response for logging
+func describeCache(header http.Header) string {
+	var parts []string
+	if value, _ := cacheStatus(header); value != "" {
+		parts = append(parts, "status "+value)
+	}
+	if age := header.Get("Age"); age != "" {
+		parts = append(parts, "age "+age+"s")
+	}
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		if name, value, ok := strings.Cut(strings.TrimSpace(directive), "="); ok && strings.EqualFold(name, "max-age") {
+			parts = append(parts, "max-age "+value+"s")
+		}
+	}
+	if len(parts) == 0 {
+		return "no caching headers"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// cacheHitRate tracks which of the last checks were served from cache
+type cacheHitRate struct {
+	hits []bool
+	size int
+	next int
+}
+
+// newCacheHitRate tracks the last size checks
+func newCacheHitRate(size int) *cacheHitRate {
+	return &cacheHitRate{hits: make([]bool, 0, size), size: size}
+}
+
+// record adds a response, and whether it was a cache hit, replacing the oldest once full
+func (c *cacheHitRate) record(header http.Header) {
+	_, hit := cacheStatus(header)
+	if len(c.hits) < c.size {
+		c.hits = append(c.hits, hit)
+		return
+	}
+	c.hits[c.next] = hit
+	c.next = (c.next + 1) % c.size
+}
+
+// checks returns how many checks the rate is computed over
+func (c *cacheHitRate) checks() int {
+	return len(c.hits)
+}
+
+// rate returns the percentage of the recorded checks that were cache hits
+// It reports false until the window is full, so misses while the cache warms
+// up after startup are not taken as a low rate
+func (c *cacheHitRate) rate() (float64, bool) {
+	if len(c.hits) < c.size {
+		return 0, false
+	}
+	hits := 0
+	for _, hit := range c.hits {
+		if hit {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(c.hits)) * 100, true
+}
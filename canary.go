@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// canaryCheck compares a canary deployment against the primary response
+type canaryCheck struct {
+	url string
+
+	// maxSlowdown is how many percent slower than the primary the canary may respond
+	maxSlowdown float64
+
+	// bodyPattern, if set, must match the canary body instead of it equalling the primary body
+	bodyPattern *regexp.Regexp
+}
+
+// compare fetches the canary and checks it against primary
+// The returned error describes the first mismatch and carries FailureLatencyExceeded
+// when the canary is too slow, FailureContentChanged when its body differs and
+// FailureCanaryMismatch otherwise
+func (c *canaryCheck) compare(ctx context.Context, client *http.Client, opts checkOptions, primary *checkResult) error {
+	// The canary is sent the same request as the primary
+	ctx, cancel := attemptContext(ctx, client.Timeout)
+	defer cancel()
+	req, err := newCheckRequest(ctx, c.url, opts)
+	if err != nil {
+		return withReason(FailureCanaryMismatch, fmt.Errorf("canary request failed: %v", err))
+	}
+
+	// Latency is measured to the response headers, as it is for the primary
+	start := time.Now()
+	resp, err := client.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		return withReason(FailureCanaryMismatch, fmt.Errorf("canary request failed: %v", err))
+	}
+	defer resp.Body.Close()
+	var body []byte
+	if opts.decompress {
+		body, err = readDecodedBody(resp.Body, resp.Header.Get("Content-Encoding"))
+	} else {
+		body, err = io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	}
+	if err != nil {
+		return withReason(FailureCanaryMismatch, fmt.Errorf("reading canary response: %v", err))
+	}
+
+	if resp.StatusCode != primary.StatusCode {
+		return withReason(FailureCanaryMismatch, fmt.Errorf("canary status code %d differs from primary status code %d", resp.StatusCode, primary.StatusCode))
+	}
+	if limit := time.Duration(float64(primary.Latency) * (1 + c.maxSlowdown/100)); primary.Latency > 0 && latency > limit {
+		slowdown := (float64(latency)/float64(primary.Latency) - 1) * 100
+		return withReason(FailureLatencyExceeded, fmt.Errorf("canary took %s, %.0f%% slower than primary %s (limit %.0f%%)",
+			latency.Round(time.Millisecond), slowdown, primary.Latency.Round(time.Millisecond), c.maxSlowdown))
+	}
+	if c.bodyPattern != nil {
+		if !c.bodyPattern.Match(body) {
+			return withReason(FailureContentChanged, fmt.Errorf("canary body does not match %q", c.bodyPattern))
+		}
+	} else if !bytes.Equal(body, primary.Body) {
+		return withReason(FailureContentChanged, fmt.Errorf("canary body (%d bytes) differs from primary body (%d bytes)", len(body), len(primary.Body)))
+	}
+	return nil
+}
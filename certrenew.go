@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// certRenewInterval is the minimum time between runs of the renewal ELF binary
+const certRenewInterval = 24 * time.Hour
+
+// certExpiry returns the expiry time of the leaf certificate in state
+func certExpiry(state *tls.ConnectionState) (time.Time, bool) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return time.Time{}, false
+	}
+	return state.PeerCertificates[0].NotAfter, true
+}
+
+// certDaysLeft returns the whole days until expiry, rounded down
+func certDaysLeft(expiry, now time.Time) int {
+	return int(expiry.Sub(now) / (24 * time.Hour))
+}
+
+// certRenewer runs a renewal ELF binary when the certificate is close to expiry
+// The time of the last run is kept in a state file so restarts do not run it again
+type certRenewer struct {
+	elfPath   string
+	statePath string
+	wait      time.Duration
+
+	mu      sync.Mutex
+	running bool
+}
+
+// newCertRenewer creates a renewer that waits wait after each run before re-checking
+func newCertRenewer(elfPath, statePath string, wait time.Duration) *certRenewer {
+	return &certRenewer{elfPath: elfPath, statePath: statePath, wait: wait}
+}
+
+// lastRun returns the time recorded in the state file, or the zero time if there is none
+func (r *certRenewer) lastRun() time.Time {
+	data, err := os.ReadFile(r.statePath)
+	if err != nil {
+		return time.Time{}
+	}
+	at, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+	if err != nil {
+		log.Printf("WARNING: Ignoring invalid certificate renewal state in %s: %v", r.statePath, err)
+		return time.Time{}
+	}
+	return at
+}
+
+// renew runs the renewal ELF binary in the background unless it already ran in the
+// last day, then waits and re-checks the certificate of url
+func (r *certRenewer) renew(ctx context.Context, url string, expiry time.Time, client *http.Client, opts checkOptions) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running {
+		return
+	}
+	if last := r.lastRun(); time.Since(last) < certRenewInterval {
+		if opts.verbose {
+			log.Printf("Certificate renewal already ran at %s, not running again until %s", last.Format(time.RFC3339), last.Add(certRenewInterval).Format(time.RFC3339))
+		}
+		return
+	}
+
+	// Record the run first so a crash during renewal cannot cause a retry loop
+	now := time.Now().UTC()
+	if err := os.WriteFile(r.statePath, []byte(now.Format(time.RFC3339)+"\n"), 0644); err != nil {
+		log.Printf("WARNING: Cannot write certificate renewal state to %s, not renewing: %v", r.statePath, err)
+		return
+	}
+	r.running = true
+
+	go func() {
+		defer func() {
+			r.mu.Lock()
+			r.running = false
+			r.mu.Unlock()
+		}()
+
+		message := fmt.Sprintf("certificate expires %s (%d days left)", expiry.UTC().Format(time.RFC3339), certDaysLeft(expiry, now))
+		log.Printf("Running certificate renewal %s for %s: %s", r.elfPath, url, message)
+		if exitCode, _ := executeELF(r.elfPath, newEvent(EventCertExpiring, SeverityWarning, url, message)); exitCode != 0 {
+			log.Printf("WARNING: Certificate renewal ELF binary exited with code %d", exitCode)
+		}
+
+		time.Sleep(r.wait)
+		opts.readBody = false
+		result, err := checkWebsiteDown(ctx, url, client, opts)
+		if err != nil {
+			log.Printf("WARNING: Cannot re-check certificate of %s after renewal: %v", url, err)
+			return
+		}
+		renewed, ok := certExpiry(result.TLS)
+		switch {
+		case !ok:
+			log.Printf("WARNING: No certificate presented by %s after renewal", url)
+		case renewed.After(expiry):
+			log.Printf("Certificate for %s renewed, now expires %s", url, renewed.UTC().Format(time.RFC3339))
+		default:
+			log.Printf("WARNING: Certificate for %s was not renewed, still expires %s", url, renewed.UTC().Format(time.RFC3339))
+		}
+	}()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// parseCipherSuites parses a -require-cipher list of Go TLS cipher suite names
+// such as TLS_AES_256_GCM_SHA384 into the set of allowed suite IDs
+func parseCipherSuites(list string) (map[uint16]bool, error) {
+	known := make(map[string]uint16)
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		known[suite.Name] = suite.ID
+	}
+
+	allowed := make(map[uint16]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+		allowed[id] = true
+	}
+	if len(allowed) == 0 {
+		return nil, errors.New("no cipher suites given")
+	}
+	return allowed, nil
+}
+
+// verifyCipherSuite checks that the negotiated cipher suite is one of allowed
+func verifyCipherSuite(state *tls.ConnectionState, allowed map[uint16]bool) error {
+	if state == nil {
+		return withReason(FailureTLSCipherNotAllowed, errors.New("cipher suite not allowed: connection does not use TLS"))
+	}
+	if !allowed[state.CipherSuite] {
+		return withReason(FailureTLSCipherNotAllowed, fmt.Errorf("cipher suite not allowed: negotiated %s", tls.CipherSuiteName(state.CipherSuite)))
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Subcommands of the command line
+const (
+	commandCheck     = "check"
+	commandValidate  = "validate"
+	commandOnce      = "once"
+	commandBenchmark = "benchmark"
+	commandServe     = "serve"
+	commandCompare   = "compare"
+)
+
+// parseCommandLine selects the subcommand and parses its flags into flag.CommandLine
+// Every subcommand accepts the same flags. Running without a subcommand, as
+// before subcommands existed, is the same as check.
+func parseCommandLine(args []string) string {
+	if len(args) < 2 || (strings.HasPrefix(args[1], "-") && !isHelpArg(args[1])) {
+		args = append([]string{args[0], commandCheck}, args[1:]...)
+	}
+
+	var command string
+	parse := func(name string) cli.ActionFunc {
+		return func(ctx *cli.Context) error {
+			command = name
+			return flag.CommandLine.Parse(ctx.Args().Slice())
+		}
+	}
+	subcommand := func(name, usage string) *cli.Command {
+		return &cli.Command{
+			Name:            name,
+			Usage:           usage,
+			ArgsUsage:       "[flags]",
+			SkipFlagParsing: true,
+			Action:          parse(name),
+		}
+	}
+
+	app := &cli.App{
+		Name:      filepath.Base(args[0]),
+		Usage:     "check website uptime and run a program when it is down",
+		UsageText: filepath.Base(args[0]) + " [command] [flags] (run a command with -h to list the flags)",
+		Commands: []*cli.Command{
+			subcommand(commandCheck, "monitor the website until stopped (the default)"),
+			subcommand(commandValidate, "check the flags and exit with status 0 if they are valid"),
+			subcommand(commandOnce, "run a single check and exit with status 1 if the website is down"),
+			subcommand(commandBenchmark, "send -benchmark-requests requests and report latency percentiles"),
+			subcommand(commandServe, "serve an API on -api-addr for adding and removing checks"),
+			subcommand(commandCompare, "check -url and -secondary-url every -interval and report their latency ratio"),
+		},
+		HideHelpCommand: true,
+	}
+	if err := app.Run(args); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	// No command ran when help was shown
+	if command == "" {
+		os.Exit(0)
+	}
+	return command
+}
+
+// isHelpArg reports whether arg asks for the command list
+func isHelpArg(arg string) bool {
+	switch arg {
+	case "-h", "-help", "--help":
+		return true
+	}
+	return false
+}
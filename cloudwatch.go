@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// cloudWatchPublisher sends check results to CloudWatch Metrics
+type cloudWatchPublisher struct {
+	namespace string
+	timeout   time.Duration
+	client    *cloudwatch.Client
+
+	pending sync.WaitGroup
+}
+
+// newCloudWatchPublisher creates a publisher using the standard AWS credential chain
+func newCloudWatchPublisher(namespace string, timeout time.Duration) (*cloudWatchPublisher, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config: %v", err)
+	}
+
+	return &cloudWatchPublisher{
+		namespace: namespace,
+		timeout:   timeout,
+		client:    cloudwatch.NewFromConfig(cfg),
+	}, nil
+}
+
+// publish sends the outcome of a check in the background
+// Failures are logged and never delay or affect the monitoring loop
+func (p *cloudWatchPublisher) publish(url string, result *checkResult, checkErr error, consecutiveFailures int) {
+	now := time.Now()
+	dimensions := []types.Dimension{{Name: aws.String("URL"), Value: aws.String(url)}}
+
+	up := 0.0
+	if checkErr == nil {
+		up = 1
+	}
+	data := []types.MetricDatum{
+		{MetricName: aws.String("IsUp"), Value: aws.Float64(up), Unit: types.StandardUnitNone, Dimensions: dimensions, Timestamp: &now},
+		{MetricName: aws.String("ConsecutiveFailures"), Value: aws.Float64(float64(consecutiveFailures)), Unit: types.StandardUnitCount, Dimensions: dimensions, Timestamp: &now},
+	}
+	if result != nil {
+		latency := float64(result.Latency) / float64(time.Millisecond)
+		data = append(data, types.MetricDatum{MetricName: aws.String("ResponseTime"), Value: aws.Float64(latency), Unit: types.StandardUnitMilliseconds, Dimensions: dimensions, Timestamp: &now})
+	}
+
+	p.pending.Add(1)
+	go func() {
+		defer p.pending.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+		defer cancel()
+
+		_, err := p.client.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(p.namespace),
+			MetricData: data,
+		})
+		if err != nil {
+			log.Printf("Failed to publish CloudWatch metrics for %s: %v", url, err)
+		}
+	}()
+}
+
+// wait blocks until metrics already being published have been sent
+func (p *cloudWatchPublisher) wait() {
+	p.pending.Wait()
+}
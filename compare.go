@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// compareURLFor returns the URL on the compare host with the same path and query as target
+func compareURLFor(target *url.URL, compareURL string) (string, error) {
+	alternate, err := url.Parse(compareURL)
+	if err != nil {
+		return "", err
+	}
+	if alternate.Scheme == "" || alternate.Host == "" {
+		return "", fmt.Errorf("compare URL %q must include a scheme and host", compareURL)
+	}
+
+	u := *target
+	u.Scheme = alternate.Scheme
+	u.Host = alternate.Host
+	u.User = alternate.User
+	return u.String(), nil
+}
+
+// compareResponse fetches compareURL and compares it against the primary response
+// Returns an error describing how the two responses diverge, carrying
+// FailureContentChanged when only the bodies differ
+func compareResponse(client *http.Client, compareURL string, primary *checkResult, compareBody bool) error {
+	resp, err := client.Get(compareURL)
+	if err != nil {
+		return fmt.Errorf("compare request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != primary.StatusCode {
+		return fmt.Errorf("status code %d differs from primary status code %d", resp.StatusCode, primary.StatusCode)
+	}
+
+	if compareBody {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+		if err != nil {
+			return fmt.Errorf("reading compare response: %v", err)
+		}
+		if !bytes.Equal(body, primary.Body) {
+			return withReason(FailureContentChanged, fmt.Errorf("body (%d bytes) differs from primary body (%d bytes)", len(body), len(primary.Body)))
+		}
+	}
+	return nil
+}
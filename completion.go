@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// completionValues are the values suggested after flags that take a fixed set of values
+var completionValues = map[string][]string{
+	"mode":            {string(ModeHTTP), string(ModeGraphQL), string(ModeTCP), string(ModeCompound), string(ModeICMP), string(ModeGRPC), string(ModeWebSocket), string(ModeDNS)},
+	"log-format":      {"common", "combined"},
+	"backoff-mode":    {"exponential", "linear", "constant"},
+	"jitter-mode":     {string(JitterNone), string(JitterRandom)},
+	"eval-mode":       {string(EvalConsecutive), string(EvalWindow)},
+	"min-tls-version": {"1.0", "1.1", "1.2", "1.3"},
+	"method":          {"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+}
+
+// isBoolFlag reports whether f is given without a value
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// sortedFlags returns every registered flag in name order
+func sortedFlags() []*flag.Flag {
+	var flags []*flag.Flag
+	flag.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, f)
+	})
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags
+}
+
+// writeCompletion writes a completion script for shell covering every registered flag
+func writeCompletion(w io.Writer, shell, program string) error {
+	switch shell {
+	case "bash":
+		writeBashCompletion(w, program)
+	case "zsh":
+		writeZshCompletion(w, program)
+	case "fish":
+		writeFishCompletion(w, program)
+	default:
+		return fmt.Errorf("unknown shell %q (expected bash, zsh or fish)", shell)
+	}
+	return nil
+}
+
+// writeBashCompletion writes a script for bash's complete builtin
+func writeBashCompletion(w io.Writer, program string) {
+	var names, valueFlags []string
+	for _, f := range sortedFlags() {
+		names = append(names, "-"+f.Name)
+		if !isBoolFlag(f) {
+			valueFlags = append(valueFlags, f.Name)
+		}
+	}
+	function := "_" + strings.NewReplacer("-", "_", ".", "_").Replace(program)
+
+	fmt.Fprintf(w, "# bash completion for %s\n", program)
+	fmt.Fprintf(w, "%s() {\n", function)
+	fmt.Fprintf(w, "    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	fmt.Fprintf(w, "    local name=\"${prev#-}\"\n")
+	fmt.Fprintf(w, "    name=\"${name#-}\"\n")
+	fmt.Fprintf(w, "    case \"$name\" in\n")
+	for _, name := range sortedKeys(completionValues) {
+		fmt.Fprintf(w, "        %s) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n", name, strings.Join(completionValues[name], " "))
+	}
+	fmt.Fprintf(w, "    esac\n")
+	fmt.Fprintf(w, "    case \" %s \" in\n", strings.Join(valueFlags, " "))
+	fmt.Fprintf(w, "        *\" $name \"*) COMPREPLY=($(compgen -f -- \"$cur\")); return ;;\n")
+	fmt.Fprintf(w, "    esac\n")
+	fmt.Fprintf(w, "    COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+	fmt.Fprintf(w, "}\n")
+	fmt.Fprintf(w, "complete -o default %s %s\n", function, program)
+}
+
+// writeZshCompletion writes a script for zsh's _arguments
+func writeZshCompletion(w io.Writer, program string) {
+	escape := strings.NewReplacer("'", "'\\''", "[", "\\[", "]", "\\]", ":", "\\:")
+
+	fmt.Fprintf(w, "#compdef %s\n\n", program)
+	fmt.Fprintf(w, "_arguments \\\n")
+	for _, f := range sortedFlags() {
+		spec := "-" + f.Name + "[" + escape.Replace(f.Usage) + "]"
+		if values, ok := completionValues[f.Name]; ok {
+			spec += ":" + f.Name + ":(" + strings.Join(values, " ") + ")"
+		} else if !isBoolFlag(f) {
+			spec += ":" + f.Name + ":_default"
+		}
+		fmt.Fprintf(w, "  '%s' \\\n", spec)
+	}
+	fmt.Fprintf(w, "  && return 0\n")
+}
+
+// writeFishCompletion writes complete commands for fish, using old-style single dash options
+func writeFishCompletion(w io.Writer, program string) {
+	escape := strings.NewReplacer("\\", "\\\\", "'", "\\'")
+
+	fmt.Fprintf(w, "# fish completion for %s\n", program)
+	for _, f := range sortedFlags() {
+		line := fmt.Sprintf("complete -c %s -o %s -d '%s'", program, f.Name, escape.Replace(f.Usage))
+		if values, ok := completionValues[f.Name]; ok {
+			line += " -x -a '" + strings.Join(values, " ") + "'"
+		} else if !isBoolFlag(f) {
+			line += " -r"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
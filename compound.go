@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Sub-check kinds usable in -compound-checks
+const (
+	compoundTCP  = "tcp"
+	compoundTLS  = "tls"
+	compoundHTTP = "http"
+	compoundBody = "body"
+)
+
+// compoundStep is one sub-check of a compound check
+type compoundStep struct {
+	kind string
+	arg  string
+}
+
+func (s compoundStep) String() string {
+	if s.arg == "" {
+		return s.kind
+	}
+	return s.kind + " " + s.arg
+}
+
+// parseCompoundSteps parses a comma-separated list of sub-checks such as
+// "tcp:443,tls,http:/health,body:\"status\":\"ok\"". tcp and tls take an optional
+// port, http an optional path and body the text the last HTTP response must contain.
+func parseCompoundSteps(spec string) ([]compoundStep, error) {
+	var steps []compoundStep
+	sawHTTP := false
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		kind, arg, _ := strings.Cut(field, ":")
+		step := compoundStep{kind: strings.ToLower(kind), arg: arg}
+
+		switch step.kind {
+		case compoundTCP, compoundTLS:
+			if step.arg != "" {
+				if _, err := net.LookupPort("tcp", step.arg); err != nil {
+					return nil, fmt.Errorf("%s: invalid port %q", step.kind, step.arg)
+				}
+			}
+		case compoundHTTP:
+			if step.arg != "" && !strings.HasPrefix(step.arg, "/") {
+				return nil, fmt.Errorf("http: path %q must start with /", step.arg)
+			}
+			sawHTTP = true
+		case compoundBody:
+			if !sawHTTP {
+				return nil, fmt.Errorf("body must follow an http sub-check")
+			}
+			if step.arg == "" {
+				return nil, fmt.Errorf("body requires the text to look for")
+			}
+		default:
+			return nil, fmt.Errorf("unknown sub-check %q (expected tcp, tls, http or body)", kind)
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("no sub-checks given")
+	}
+	return steps, nil
+}
+
+// compoundPort returns the port for a tcp or tls sub-check
+// Without an explicit port the target URL's port or its scheme's default is used
+func compoundPort(target *url.URL, port string) string {
+	if port != "" {
+		return port
+	}
+	if target.Port() != "" {
+		return target.Port()
+	}
+	if target.Scheme == "http" {
+		return "80"
+	}
+	return "443"
+}
+
+// checkCompound runs the sub-checks in order and stops at the first failure
+// The service is up only if every sub-check passes. The returned result is
+// from the last HTTP sub-check if there was one, otherwise the last sub-check.
+func checkCompound(ctx context.Context, target *url.URL, steps []compoundStep, client *http.Client, opts checkOptions, timeout time.Duration) (*checkResult, error) {
+	var result, lastHTTP *checkResult
+	start := time.Now()
+	for i, step := range steps {
+		var stepResult *checkResult
+		var err error
+
+		switch step.kind {
+		case compoundTCP:
+			addr := net.JoinHostPort(target.Hostname(), compoundPort(target, step.arg))
+			stepResult, err = checkTCP(addr, tcpProbe{timeout: timeout}, opts)
+		case compoundTLS:
+			addr := net.JoinHostPort(target.Hostname(), compoundPort(target, step.arg))
+			stepResult, err = checkTLS(addr, target.Hostname(), timeout, opts)
+		case compoundHTTP:
+			stepURL := *target
+			if step.arg != "" {
+				stepURL.Path, stepURL.RawQuery = step.arg, ""
+				if path, query, ok := strings.Cut(step.arg, "?"); ok {
+					stepURL.Path, stepURL.RawQuery = path, query
+				}
+			}
+			httpOpts := opts
+			httpOpts.readBody = true
+			stepResult, err = checkWebsiteDown(ctx, stepURL.String(), client, httpOpts)
+			lastHTTP = stepResult
+		case compoundBody:
+			if !bytes.Contains(lastHTTP.Body, []byte(step.arg)) {
+				err = withReason(FailureBodyAssertionFailed, fmt.Errorf("response body does not contain %q", step.arg))
+			}
+			stepResult = lastHTTP
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("sub-check %d/%d (%s) failed: %w", i+1, len(steps), step, err)
+		}
+		result = stepResult
+	}
+
+	if lastHTTP != nil {
+		result = lastHTTP
+	}
+	summary := *result
+	summary.Latency = time.Since(start)
+	return &summary, nil
+}
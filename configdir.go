@@ -0,0 +1,444 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+)
+
+// checkRestartDelay is how long to wait before restarting a check that exited on its own
+const checkRestartDelay = 10 * time.Second
+
+// checkStopTimeout is how long a check may take to exit after SIGTERM before it is killed
+const checkStopTimeout = 10 * time.Second
+
+// supervisorFlags configure -config-dir itself and are not passed to the checks
+var supervisorFlags = map[string]bool{"config-dir": true, "watch-config-dir": true, "validate-config": true, "completion": true, "tenants-file": true, "print-defaults": true, "pprof": true, "pprof-addr": true, "lock-file": true}
+
+// unsharedFlags are only passed to a check when set for that check, since every
+// check sharing them would conflict over an address, a file or a broker client ID
+var unsharedFlags = map[string]bool{
+	"api-addr": true, "dashboard-addr": true, "mqtt-client-id": true,
+	"heartbeat-file": true, "cert-renew-state": true, "alertmanager-state": true,
+}
+
+// tenantSetting names the team that owns a check in the serve API
+// It is not a flag, so it is not passed to the check
+const tenantSetting = "tenant"
+
+// loadCheckFile reads one check definition from a YAML or JSON file
+// Each key is the name of a flag, as in a Kubernetes ConfigMap
+func loadCheckFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseCheckSettings(data)
+}
+
+// parseCheckSettings parses a single check definition
+func parseCheckSettings(data []byte) (map[string]string, error) {
+	settings, err := parseFlagSettings(data)
+	if settings["url"] == "" && settings["tail-log"] == "" && err == nil {
+		err = errors.New("missing url")
+	}
+	return settings, err
+}
+
+// loadFlagSettings reads a YAML or JSON file mapping flag names to values
+func loadFlagSettings(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseFlagSettings(data)
+}
+
+// parseFlagSettings parses a mapping of flag names to single values
+func parseFlagSettings(data []byte) (map[string]string, error) {
+	// YAML is a superset of JSON so this handles both formats
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	entry, ok := normalizeYAML(raw).(map[string]any)
+	if !ok {
+		return nil, errors.New("must define a single check as a mapping of flag names to values")
+	}
+
+	settings := make(map[string]string, len(entry))
+	var errs []error
+	for name, value := range entry {
+		switch {
+		case flag.Lookup(name) == nil && name != tenantSetting:
+			errs = append(errs, fmt.Errorf("unknown setting %q", name))
+		case supervisorFlags[name]:
+			errs = append(errs, fmt.Errorf("setting %q cannot be used in a check file", name))
+		default:
+			switch value.(type) {
+			case map[string]any, []any:
+				errs = append(errs, fmt.Errorf("setting %q must be a single value", name))
+			default:
+				settings[name] = fmt.Sprint(value)
+			}
+		}
+	}
+	return settings, errors.Join(errs...)
+}
+
+// loadCheckDir loads every *.yaml, *.yml and *.json file in dir
+// Invalid files are logged and skipped so one team's mistake cannot stop the other checks
+func loadCheckDir(dir string) (map[string]map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := make(map[string]map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() || !isCheckFile(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		settings, err := loadCheckFile(path)
+		if err != nil {
+			log.Printf("WARNING: Skipping check file %s: %v", path, err)
+			continue
+		}
+		checks[path] = settings
+	}
+	return checks, nil
+}
+
+// isCheckFile reports whether name has a check file extension
+func isCheckFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// checkArgs builds the command line for a check: the shared flags given to the
+// supervisor followed by the file's settings, which take precedence
+func checkArgs(shared []string, settings map[string]string) []string {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := append([]string(nil), shared...)
+	for _, name := range names {
+		if name != tenantSetting {
+			args = append(args, "-"+name+"="+settings[name])
+		}
+	}
+	return args
+}
+
+// sharedArgs returns the flags given on the command line to pass on to every check
+func sharedArgs(commandLine map[string]bool) []string {
+	var args []string
+	flag.Visit(func(f *flag.Flag) {
+		if commandLine[f.Name] && !supervisorFlags[f.Name] && !unsharedFlags[f.Name] {
+			args = append(args, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+	return args
+}
+
+// checkProcess is a websitecheck child process monitoring one check file
+type checkProcess struct {
+	path string
+	args []string
+	stop chan struct{}
+	done chan struct{}
+
+	mu       sync.Mutex
+	running  bool
+	restarts int
+	lastExit string
+}
+
+// processState is what is known about a check's child process
+type processState struct {
+	Running  bool   `json:"running"`
+	Restarts int    `json:"restarts"`
+	LastExit string `json:"last_exit,omitempty"`
+}
+
+// state returns the current state of the child process
+func (p *checkProcess) state() processState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return processState{Running: p.running, Restarts: p.restarts, LastExit: p.lastExit}
+}
+
+// setRunning records that the child started or exited
+func (p *checkProcess) setRunning(running bool, exit error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if running && p.running != running && p.lastExit != "" {
+		p.restarts++
+	}
+	p.running = running
+	if exit != nil {
+		p.lastExit = exit.Error()
+	}
+}
+
+// executablePath is the binary the checks run, resolved once since os.Args[0]
+// may be a name found on PATH or a path relative to a directory since left
+var executablePath = sync.OnceValue(func() string {
+	path, err := os.Executable()
+	if err != nil {
+		log.Printf("WARNING: Cannot resolve the websitecheck binary (%v), running checks with %s", err, os.Args[0])
+		return os.Args[0]
+	}
+	return path
+})
+
+// newCheckProcess creates the child for a check, which is started with run
+func newCheckProcess(path string, args []string) *checkProcess {
+	return &checkProcess{path: path, args: args, stop: make(chan struct{}), done: make(chan struct{})}
+}
+
+// run starts the child and restarts it after checkRestartDelay until stopped
+func (p *checkProcess) run() {
+	defer close(p.done)
+	for {
+		// A check replaced before it was started is never run
+		select {
+		case <-p.stop:
+			return
+		default:
+		}
+		cmd := exec.Command(executablePath(), p.args...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			log.Printf("Failed to start check %s: %v", p.path, err)
+			p.setRunning(false, err)
+		} else {
+			p.setRunning(true, nil)
+			exited := make(chan error, 1)
+			go func() { exited <- cmd.Wait() }()
+
+			select {
+			case err := <-exited:
+				if err == nil {
+					err = errors.New("exit status 0")
+				}
+				p.setRunning(false, err)
+				log.Printf("Check %s exited (%v), restarting in %s", p.path, err, checkRestartDelay)
+			case <-p.stop:
+				cmd.Process.Signal(syscall.SIGTERM)
+				select {
+				case <-exited:
+				case <-time.After(checkStopTimeout):
+					cmd.Process.Kill()
+					<-exited
+				}
+				return
+			}
+		}
+
+		select {
+		case <-time.After(checkRestartDelay):
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// terminate stops the check and waits for it to exit
+func (p *checkProcess) terminate() {
+	close(p.stop)
+	<-p.done
+}
+
+// checkSupervisor runs one checkProcess per check, keyed by file path or API id
+type checkSupervisor struct {
+	shared []string
+
+	mu       sync.Mutex
+	children map[string]*checkProcess
+	settings map[string]map[string]string
+}
+
+// newCheckSupervisor creates a supervisor that passes shared to every check
+func newCheckSupervisor(shared []string) *checkSupervisor {
+	return &checkSupervisor{
+		shared:   shared,
+		children: make(map[string]*checkProcess),
+		settings: make(map[string]map[string]string),
+	}
+}
+
+// start runs a check for key, restarting it if its settings changed
+// Returns false if the same check is already running
+func (s *checkSupervisor) start(key string, settings map[string]string) bool {
+	s.mu.Lock()
+	args := checkArgs(s.shared, settings)
+	old, running := s.children[key]
+	if running && reflect.DeepEqual(old.args, args) {
+		s.mu.Unlock()
+		return false
+	}
+	child := newCheckProcess(key, args)
+	s.children[key] = child
+	s.settings[key] = settings
+	s.mu.Unlock()
+
+	// Stopping can take up to checkStopTimeout, so it is done without holding mu
+	// The old check exits first as both may use the same addresses and files
+	if running {
+		log.Printf("Restarting check %s with new settings", key)
+		old.terminate()
+	} else {
+		log.Printf("Starting check %s for %s", key, settings["url"])
+	}
+	go child.run()
+	return true
+}
+
+// stop stops the check for key, returning false if there is none
+func (s *checkSupervisor) stop(key string) bool {
+	s.mu.Lock()
+	child, ok := s.children[key]
+	delete(s.children, key)
+	delete(s.settings, key)
+	s.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+	log.Printf("Stopping check %s", key)
+	child.terminate()
+	return true
+}
+
+// keys returns the keys of the running checks in order
+func (s *checkSupervisor) keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.children))
+	for key := range s.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// processState returns the state of the child process for key
+func (s *checkSupervisor) processState(key string) (processState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	child, ok := s.children[key]
+	if !ok {
+		return processState{}, false
+	}
+	return child.state(), true
+}
+
+// checkSettings returns the settings of the check for key
+func (s *checkSupervisor) checkSettings(key string) (map[string]string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	settings, ok := s.settings[key]
+	return settings, ok
+}
+
+// stopAll stops every running check
+func (s *checkSupervisor) stopAll() {
+	for _, key := range s.keys() {
+		s.stop(key)
+	}
+}
+
+// reloadCheckDir rescans dir, starting checks for new files, stopping checks
+// for removed files and restarting checks whose file changed
+func reloadCheckDir(s *checkSupervisor, dir string) {
+	checks, err := loadCheckDir(dir)
+	if err != nil {
+		log.Printf("WARNING: Cannot read check directory, keeping current checks: %v", err)
+		return
+	}
+
+	for _, path := range s.keys() {
+		if _, ok := checks[path]; !ok {
+			s.stop(path)
+		}
+	}
+	paths := make([]string, 0, len(checks))
+	for path := range checks {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		s.start(path, checks[path])
+	}
+	log.Printf("Running %d checks from %s", len(s.keys()), dir)
+}
+
+// runCheckDir monitors every check defined in dir until interrupted
+// The directory is rescanned on SIGHUP, and whenever it changes if watch is set
+func runCheckDir(dir string, watch bool, shared []string) {
+	s := newCheckSupervisor(shared)
+	reloadCheckDir(s, dir)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	// Editors write files in several steps, so wait for changes to settle before rescanning
+	var changes <-chan fsnotify.Event
+	if watch {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			log.Fatalf("Error: Cannot watch %s: %v", dir, err)
+		}
+		defer watcher.Close()
+		if err := watcher.Add(dir); err != nil {
+			log.Fatalf("Error: Cannot watch %s: %v", dir, err)
+		}
+		changes = watcher.Events
+	}
+	settle := time.NewTimer(time.Hour)
+	settle.Stop()
+
+	for {
+		select {
+		case <-hup:
+			log.Printf("Received SIGHUP, reloading checks from %s", dir)
+			reloadCheckDir(s, dir)
+		case event := <-changes:
+			if isCheckFile(event.Name) {
+				settle.Reset(time.Second)
+			}
+		case <-settle.C:
+			reloadCheckDir(s, dir)
+		case sig := <-quit:
+			log.Printf("Received %s, stopping %d checks", sig, len(s.keys()))
+			s.stopAll()
+			return
+		}
+	}
+}
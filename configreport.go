@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// redacted replaces sensitive values in GET /config
+const redacted = "***"
+
+// sensitiveFlagWords mark flags whose values are secrets
+var sensitiveFlagWords = []string{"password", "token", "secret", "key", "dsn", "community", "credential", "auth", "webhook"}
+
+// isSensitiveFlag reports whether the value of the named flag must not be shown
+func isSensitiveFlag(name string) bool {
+	for _, word := range sensitiveFlagWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactValue hides the value of a sensitive flag and any password in a URL
+func redactValue(name, value string) string {
+	if value == "" {
+		return value
+	}
+	if isSensitiveFlag(name) {
+		return redacted
+	}
+	return redactURL(value)
+}
+
+// redactURL hides the password in value if it is a URL with one
+func redactURL(value string) string {
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			username := url.User(u.User.Username()).String()
+			return strings.Replace(value, u.User.String()+"@", username+":"+redacted+"@", 1)
+		}
+	}
+	return value
+}
+
+// effectiveConfig returns the value of every flag after the defaults, the
+// config file, Kubernetes and the command line have been applied, with
+// secrets redacted
+func effectiveConfig() map[string]string {
+	config := make(map[string]string)
+	flag.VisitAll(func(f *flag.Flag) {
+		config[f.Name] = redactValue(f.Name, f.Value.String())
+	})
+	return config
+}
+
+// configSnapshot holds the effective configuration for GET /config
+// The monitoring loop updates it when settings change so the API never reads
+// flags while they are being set
+type configSnapshot struct {
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+// update records the current flag values
+func (c *configSnapshot) update() {
+	values := effectiveConfig()
+	c.mu.Lock()
+	c.values = values
+	c.mu.Unlock()
+}
+
+// get returns the recorded flag values
+func (c *configSnapshot) get() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.values
+}
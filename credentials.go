@@ -0,0 +1,118 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// httpCredentials are added to every request sent to the monitored host
+type httpCredentials struct {
+	Username    string
+	Password    string
+	BearerToken string
+}
+
+// apply sets the Authorization header on req, preferring a bearer token
+func (c httpCredentials) apply(req *http.Request) {
+	switch {
+	case c.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+c.BearerToken)
+	case c.Username != "" || c.Password != "":
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+}
+
+// credentialSource fetches credentials and reports how long they are valid
+// A zero lifetime means the credentials do not expire
+type credentialSource interface {
+	name() string
+	fetch() (httpCredentials, time.Duration, error)
+}
+
+// credentialStore caches credentials from a source and refreshes them before they expire
+type credentialStore struct {
+	source      credentialSource
+	renewBefore time.Duration
+
+	mu      sync.RWMutex
+	creds   httpCredentials
+	expires time.Time
+}
+
+// newCredentialStore fetches the initial credentials from source
+func newCredentialStore(source credentialSource, renewBefore time.Duration) (*credentialStore, error) {
+	s := &credentialStore{source: source, renewBefore: renewBefore}
+	if err := s.fetch(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// fetch replaces the cached credentials with fresh ones from the source
+func (s *credentialStore) fetch() error {
+	creds, lifetime, err := s.source.fetch()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.creds = creds
+	s.expires = time.Time{}
+	if lifetime > 0 {
+		s.expires = time.Now().Add(lifetime)
+	}
+	return nil
+}
+
+// refresh fetches new credentials if the current ones expire within renewBefore
+// Failures are logged and the current credentials are kept, so they never cause a down alert
+func (s *credentialStore) refresh() {
+	s.mu.RLock()
+	expires := s.expires
+	s.mu.RUnlock()
+	if expires.IsZero() || time.Until(expires) > s.renewBefore {
+		return
+	}
+
+	if err := s.fetch(); err != nil {
+		log.Printf("Failed to refresh credentials from %s: %v", s.source.name(), err)
+		return
+	}
+	log.Printf("Refreshed credentials from %s", s.source.name())
+}
+
+// current returns the cached credentials
+func (s *credentialStore) current() httpCredentials {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.creds
+}
+
+// authTransport adds credentials to requests for a single host
+// Requests to other hosts, such as cross-host redirects, are sent without them
+type authTransport struct {
+	base  http.RoundTripper
+	host  string
+	store *credentialStore
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == t.host && req.Header.Get("Authorization") == "" {
+		req = req.Clone(req.Context())
+		t.store.current().apply(req)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// withCredentials makes client send credentials from store to host
+func withCredentials(client *http.Client, host string, store *credentialStore) {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	client.Transport = &authTransport{base: base, host: host, store: store}
+}
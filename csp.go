@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// cspPolicy holds the requirements for the Content-Security-Policy header
+type cspPolicy struct {
+	minDirectives int
+	expected      string
+}
+
+// checkCSP validates the Content-Security-Policy header against policy
+func checkCSP(header http.Header, policy cspPolicy) error {
+	value := strings.TrimSpace(header.Get("Content-Security-Policy"))
+	if value == "" {
+		return errors.New("Content-Security-Policy header is missing or empty")
+	}
+
+	if policy.minDirectives > 0 {
+		if n := countCSPDirectives(value); n < policy.minDirectives {
+			return fmt.Errorf("policy has %d directives, expected at least %d", n, policy.minDirectives)
+		}
+	}
+
+	if policy.expected != "" && value != strings.TrimSpace(policy.expected) {
+		return fmt.Errorf("policy %q does not match expected %q", value, policy.expected)
+	}
+	return nil
+}
+
+// countCSPDirectives counts the non-empty semicolon-separated directives in a policy
+func countCSPDirectives(policy string) int {
+	count := 0
+	for _, directive := range strings.Split(policy, ";") {
+		if strings.TrimSpace(directive) != "" {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	_ "embed"
+	"log"
+	"net/http"
+)
+
+// dashboardPage is the single page served on -dashboard-addr
+// It follows GET /events/sse and needs no other assets
+//
+//go:embed dashboard.html
+var dashboardPage []byte
+
+// dashboardHandler returns the routes served on -dashboard-addr: the page and
+// the status and result stream it reads, on the same origin
+func dashboardHandler(status *statusTracker) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(dashboardPage)
+	})
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, statusReport{Checks: status.snapshot(), Silences: alertSuppressions.list()})
+	})
+	mux.HandleFunc("GET /events/sse", status.results.handleSSE)
+	return mux
+}
+
+// startDashboard serves the dashboard on addr in the background
+func startDashboard(addr string, status *statusTracker) {
+	go func() {
+		log.Printf("Dashboard listening on %s", addr)
+		if err := http.ListenAndServe(addr, dashboardHandler(status)); err != nil {
+			log.Fatalf("Error: Dashboard server failed: %v", err)
+		}
+	}()
+}
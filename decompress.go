@@ -0,0 +1,46 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// decompressEncodings is the Accept-Encoding sent with -decompress
+const decompressEncodings = "gzip, br"
+
+// decodeBody returns a reader that decompresses body according to its Content-Encoding
+// Unencoded bodies are returned unchanged
+func decodeBody(body io.Reader, encoding string) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		reader, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("corrupted %s encoding: %v", encoding, err)
+		}
+		return reader, nil
+	case "br":
+		return brotli.NewReader(body), nil
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding %q", encoding)
+	}
+}
+
+// readDecodedBody reads up to maxBodySize bytes of the decompressed body
+// A corrupted body fails with an error naming the encoding
+func readDecodedBody(body io.Reader, encoding string) ([]byte, error) {
+	reader, err := decodeBody(body, encoding)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, maxBodySize))
+	if err != nil && encoding != "" {
+		return nil, fmt.Errorf("corrupted %s encoding: %v", encoding, err)
+	}
+	return data, err
+}
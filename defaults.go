@@ -0,0 +1,46 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// embeddedDefaults are the lowest layer of configuration, below the -config
+// file and the command line
+//
+//go:embed defaults.yaml
+var embeddedDefaults []byte
+
+// applyConfigLayers sets every flag not given on the command line from the
+// embedded defaults and then from the config file at path, if set
+func applyConfigLayers(path string, commandLine map[string]bool) {
+	defaults, err := parseFlagSettings(embeddedDefaults)
+	if err != nil {
+		log.Fatalf("Error: Invalid embedded defaults: %v", err)
+	}
+	if _, err := applySettings(defaults, commandLine); err != nil {
+		log.Fatalf("Error: Invalid embedded defaults: %v", err)
+	}
+	if path == "" {
+		return
+	}
+
+	settings, err := loadFlagSettings(path)
+	if err != nil {
+		log.Fatalf("Error: Invalid config file %s: %v", path, err)
+	}
+	applied, err := applySettings(settings, commandLine)
+	if err != nil {
+		log.Fatalf("Error: Invalid config file %s: %v", path, err)
+	}
+	if len(applied) > 0 {
+		log.Printf("Applied settings from %s: %s", path, strings.Join(applied, ", "))
+	}
+}
+
+// printDefaults writes the embedded defaults file
+func printDefaults() {
+	fmt.Print(string(embeddedDefaults))
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// flagDefaults returns the default of every flag defined in main.go, as written
+// in its flag.Bool, flag.Int, flag.Float64 or flag.String call
+func flagDefaults(t *testing.T) map[string]string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "main.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaults := make(map[string]string)
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 3 {
+			return true
+		}
+		fun, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if pkg, ok := fun.X.(*ast.Ident); !ok || pkg.Name != "flag" {
+			return true
+		}
+		name, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || name.Kind != token.STRING {
+			return true
+		}
+		key, _ := strconv.Unquote(name.Value)
+		switch value := call.Args[1].(type) {
+		case *ast.BasicLit:
+			if value.Kind == token.STRING {
+				defaults[key], _ = strconv.Unquote(value.Value)
+			} else {
+				defaults[key] = value.Value
+			}
+		case *ast.Ident:
+			defaults[key] = value.Name
+		}
+		return true
+	})
+	return defaults
+}
+
+func TestEmbeddedDefaultsMatchFlags(t *testing.T) {
+	// The flags are defined in main, so the file is read without checking them
+	var settings map[string]string
+	if err := yaml.Unmarshal(embeddedDefaults, &settings); err != nil {
+		t.Fatalf("parsing defaults.yaml: %v", err)
+	}
+	defaults := flagDefaults(t)
+	if len(defaults) == 0 {
+		t.Fatal("no flag definitions found in main.go")
+	}
+	for name, value := range settings {
+		want, ok := defaults[name]
+		if !ok {
+			t.Errorf("defaults.yaml sets %q, which is not a flag with a literal default", name)
+			continue
+		}
+		if value == want {
+			continue
+		}
+		// Numbers are compared by value so 2.0 matches 2
+		got, errGot := strconv.ParseFloat(value, 64)
+		flagValue, errWant := strconv.ParseFloat(want, 64)
+		if errGot != nil || errWant != nil || got != flagValue {
+			t.Errorf("defaults.yaml sets %s to %q, but the flag default is %q", name, value, want)
+		}
+	}
+}
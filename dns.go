@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsAnswer is the set of addresses a hostname resolved to and how long they are valid
+type dnsAnswer struct {
+	addrs      []string
+	ttl        time.Duration
+	resolvedAt time.Time
+}
+
+// expires returns when the answer's TTL runs out
+func (a *dnsAnswer) expires() time.Time {
+	return a.resolvedAt.Add(a.ttl)
+}
+
+// systemNameservers returns the nameservers from /etc/resolv.conf
+func systemNameservers() []string {
+	f, err := os.Open("/etc/resolv.conf")
+	if err != nil {
+		return []string{"127.0.0.1:53"}
+	}
+	defer f.Close()
+
+	var servers []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			servers = append(servers, net.JoinHostPort(fields[1], "53"))
+		}
+	}
+	if len(servers) == 0 {
+		return []string{"127.0.0.1:53"}
+	}
+	return servers
+}
+
+// resolveWithTTL looks up the A and AAAA records for host along with the lowest TTL.
+// The standard resolver does not expose TTLs, so the queries are sent directly.
+func resolveWithTTL(host string, timeout time.Duration) (*dnsAnswer, error) {
+	name, err := dnsmessage.NewName(strings.TrimSuffix(host, ".") + ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, server := range systemNameservers() {
+		answer := &dnsAnswer{resolvedAt: time.Now()}
+		minTTL := uint32(0)
+		ok := true
+		for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+			addrs, ttl, err := queryDNS(server, name, qtype, timeout)
+			if err != nil {
+				lastErr = err
+				ok = false
+				break
+			}
+			answer.addrs = append(answer.addrs, addrs...)
+			if len(addrs) > 0 && (minTTL == 0 || ttl < minTTL) {
+				minTTL = ttl
+			}
+		}
+		if !ok {
+			continue
+		}
+		if len(answer.addrs) == 0 {
+			return nil, fmt.Errorf("no A or AAAA records for %s", host)
+		}
+
+		slices.Sort(answer.addrs)
+		answer.ttl = time.Duration(minTTL) * time.Second
+		return answer, nil
+	}
+	return nil, lastErr
+}
+
+// queryDNS sends a single query to server and returns the addresses in the answer
+// section along with their lowest TTL
+func queryDNS(server string, name dnsmessage.Name, qtype dnsmessage.Type, timeout time.Duration) ([]string, uint32, error) {
+	id := uint16(rand.Intn(1 << 16))
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true})
+	builder.EnableCompression()
+	builder.StartQuestions()
+	builder.Question(dnsmessage.Question{Name: name, Type: qtype, Class: dnsmessage.ClassINET})
+	query, err := builder.Finish()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	conn, err := net.DialTimeout("udp", server, timeout)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := conn.Write(query); err != nil {
+		return nil, 0, err
+	}
+
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var msg dnsmessage.Message
+	if err := msg.Unpack(buf[:n]); err != nil {
+		return nil, 0, err
+	}
+	if msg.ID != id {
+		return nil, 0, errors.New("mismatched DNS response ID")
+	}
+	if msg.RCode != dnsmessage.RCodeSuccess {
+		return nil, 0, fmt.Errorf("DNS query for %s failed: %v", name, msg.RCode)
+	}
+
+	var addrs []string
+	var minTTL uint32
+	for _, rr := range msg.Answers {
+		var addr netip.Addr
+		switch body := rr.Body.(type) {
+		case *dnsmessage.AResource:
+			addr = netip.AddrFrom4(body.A)
+		case *dnsmessage.AAAAResource:
+			addr = netip.AddrFrom16(body.AAAA)
+		default:
+			continue
+		}
+		addrs = append(addrs, addr.String())
+		if minTTL == 0 || rr.Header.TTL < minTTL {
+			minTTL = rr.Header.TTL
+		}
+	}
+	return addrs, minTTL, nil
+}
+
+// dnsTracker remembers the last DNS answer for a hostname to detect changes
+type dnsTracker struct {
+	host string
+	last *dnsAnswer
+}
+
+// observe records answer and reports whether the address set changed since the
+// last answer, and whether the change happened after the previous TTL expired
+func (t *dnsTracker) observe(answer *dnsAnswer) (changed, afterTTL bool) {
+	prev := t.last
+	t.last = answer
+	if prev == nil || slices.Equal(prev.addrs, answer.addrs) {
+		return false, false
+	}
+	return true, !answer.resolvedAt.Before(prev.expires())
+}
+
+// checkDNS resolves host, retrying like checkWebsiteDown
+// The addresses it resolved to, one per line, are returned as the body
+func checkDNS(host string, timeout time.Duration, opts checkOptions) (*checkResult, error) {
+	return retryCheck("DNS", opts, func() (*checkResult, error) {
+		start := time.Now()
+		answer, err := resolveWithTTL(host, timeout)
+		if err != nil {
+			return nil, err
+		}
+		return &checkResult{Body: []byte(strings.Join(answer.addrs, "\n")), Latency: time.Since(start)}, nil
+	})
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dnsCache resolves a hostname in the background so checks can dial cached
+// addresses directly instead of resolving on every request
+type dnsCache struct {
+	tracker *dnsTracker
+
+	mu    sync.RWMutex
+	addrs []string
+}
+
+// newDNSCache resolves host once and then refreshes it every interval
+// Changes to the address set raise DNS change events like -monitor-dns-changes
+func newDNSCache(host, url string, interval, timeout time.Duration, elfPath string, verbose bool) *dnsCache {
+	c := &dnsCache{tracker: &dnsTracker{host: host}}
+	c.refresh(url, timeout, elfPath, verbose)
+	go func() {
+		for range time.Tick(interval) {
+			c.refresh(url, timeout, elfPath, verbose)
+		}
+	}()
+	return c
+}
+
+// refresh resolves the hostname and replaces the cached addresses
+// A failed lookup keeps the previous addresses
+func (c *dnsCache) refresh(url string, timeout time.Duration, elfPath string, verbose bool) {
+	answer := observeDNS(c.tracker, url, timeout, elfPath, verbose)
+	if answer == nil {
+		return
+	}
+	c.mu.Lock()
+	c.addrs = answer.addrs
+	c.mu.Unlock()
+}
+
+// cached returns the cached addresses for host, if any
+func (c *dnsCache) cached(host string) []string {
+	if host != c.tracker.host {
+		return nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.addrs
+}
+
+// lookupIPs returns the addresses host resolves to, for recording in events
+// The cached addresses are used when there are any. A failed lookup returns nil
+// since the check itself will report it
+func lookupIPs(ctx context.Context, host string, cache *dnsCache, timeout time.Duration) []string {
+	if host == "" {
+		return nil
+	}
+	if net.ParseIP(host) != nil {
+		return []string{host}
+	}
+	if cache != nil {
+		if addrs := cache.cached(host); len(addrs) > 0 {
+			return addrs
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil
+	}
+	return addrs
+}
+
+// formatResolvedIPs describes addrs for appending to a log message
+func formatResolvedIPs(addrs []string) string {
+	if len(addrs) == 0 {
+		return ""
+	}
+	return " (resolved to " + strings.Join(addrs, ", ") + ")"
+}
+
+// dialContext wraps dial so connections to the cached hostname go straight to
+// its cached addresses, trying each in turn. The request keeps its Host header
+// and TLS server name because only the dialed address changes.
+func (c *dnsCache) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return dial(ctx, network, addr)
+		}
+		addrs := c.cached(host)
+		if len(addrs) == 0 {
+			return dial(ctx, network, addr)
+		}
+
+		var errs []error
+		for _, ip := range addrs {
+			conn, err := dial(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			errs = append(errs, err)
+		}
+		return nil, errors.Join(errs...)
+	}
+}
+
+// withDNSCache makes client dial the addresses in cache instead of resolving
+func withDNSCache(client *http.Client, cache *dnsCache) {
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		if client.Transport != nil {
+			log.Printf("Warning: cannot use the DNS cache with a custom transport")
+			return
+		}
+		transport = http.DefaultTransport.(*http.Transport)
+	}
+	transport = transport.Clone()
+
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	transport.DialContext = cache.dialContext(dial)
+	client.Transport = transport
+}
@@ -0,0 +1,173 @@
+//go:build linux
+
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"golang.org/x/sys/unix"
+)
+
+// ebpfProbe times a response in the kernel with a socket filter attached to
+// the connection of the request. The filter stores the kernel time at which the
+// first packet carrying data reached the socket, so the measurement is free of
+// Go runtime scheduling, which delays when the transport sees the response
+type ebpfProbe struct {
+	firstData *ebpf.Map
+	program   *ebpf.Program
+
+	// raw is the socket the program is attached to, if any
+	raw syscall.RawConn
+
+	// sent is the kernel time the request was written at, and wrote the Go time
+	sent  int64
+	wrote time.Time
+}
+
+// ebpfLatencySupported reports whether socket filters can be loaded, by loading one
+func ebpfLatencySupported() error {
+	probe, err := newEBPFProbe()
+	if err != nil {
+		return err
+	}
+	probe.close()
+	return nil
+}
+
+// newEBPFProbe loads the socket filter and the map it writes to
+func newEBPFProbe() (*ebpfProbe, error) {
+	firstData, err := ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.Array, KeySize: 4, ValueSize: 8, MaxEntries: 1})
+	if err != nil {
+		return nil, fmt.Errorf("creating eBPF map: %w", err)
+	}
+	program, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+		Type:         ebpf.SocketFilter,
+		License:      "GPL",
+		Instructions: firstDataFilter(firstData.FD()),
+	})
+	if err != nil {
+		firstData.Close()
+		return nil, fmt.Errorf("loading eBPF program: %w", err)
+	}
+	return &ebpfProbe{firstData: firstData, program: program}, nil
+}
+
+// firstDataFilter returns a socket filter that stores bpf_ktime_get_ns() in the
+// map at mapFD when it sees the first packet with a TCP payload, keeping every packet
+// A TCP socket filter sees the packet from the TCP header on
+func firstDataFilter(mapFD int) asm.Instructions {
+	return asm.Instructions{
+		// Packet loads take the context from R6
+		asm.Mov.Reg(asm.R6, asm.R1),
+
+		// R7 is the TCP header length, from the data offset in byte 12
+		asm.LoadAbs(12, asm.Byte),
+		asm.RSh.Imm(asm.R0, 4),
+		asm.LSh.Imm(asm.R0, 2),
+		asm.Mov.Reg(asm.R7, asm.R0),
+
+		// Pure ACKs have no payload
+		asm.LoadMem(asm.R2, asm.R6, 0, asm.Word), // __sk_buff.len
+		asm.JLE.Reg(asm.R2, asm.R7, "keep"),
+
+		asm.StoreImm(asm.RFP, -4, 0, asm.Word),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -4),
+		asm.LoadMapPtr(asm.R1, mapFD),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "keep"),
+
+		// Only the first packet after the request counts
+		asm.LoadMem(asm.R1, asm.R0, 0, asm.DWord),
+		asm.JNE.Imm(asm.R1, 0, "keep"),
+		asm.Mov.Reg(asm.R8, asm.R0),
+		asm.FnKtimeGetNs.Call(),
+		asm.StoreMem(asm.R8, 0, asm.R0, asm.DWord),
+
+		asm.Mov.Imm(asm.R0, -1).WithSymbol("keep"),
+		asm.Return(),
+	}
+}
+
+// attach moves the filter to conn, the connection the request is sent on
+func (p *ebpfProbe) attach(conn net.Conn) error {
+	p.detach()
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	sysConn, ok := conn.(syscall.Conn)
+	if !ok {
+		return errors.New("connection does not expose a socket")
+	}
+	raw, err := sysConn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_ATTACH_BPF, p.program.FD())
+	}); err != nil {
+		return err
+	}
+	if sockErr != nil {
+		return fmt.Errorf("attaching eBPF program: %w", sockErr)
+	}
+	p.raw = raw
+	return nil
+}
+
+// requestWritten clears the stored time and records when the request was written
+func (p *ebpfProbe) requestWritten() {
+	p.firstData.Put(uint32(0), uint64(0))
+	p.sent = monotonicNanos()
+	p.wrote = time.Now()
+}
+
+// measure returns the time from the request being written until its response
+// reached the kernel, and until it reached Go, which is now
+func (p *ebpfProbe) measure() (kernel, app time.Duration, err error) {
+	app = time.Since(p.wrote)
+	if p.raw == nil || p.wrote.IsZero() {
+		return 0, app, errors.New("the request was not sent on a connection the eBPF program is attached to")
+	}
+	var first uint64
+	if err := p.firstData.Lookup(uint32(0), &first); err != nil {
+		return 0, app, err
+	}
+	if first == 0 || int64(first) < p.sent {
+		return 0, app, errors.New("the eBPF program saw no response data")
+	}
+	return time.Duration(int64(first) - p.sent), app, nil
+}
+
+// detach removes the filter from the connection, which the transport may reuse
+func (p *ebpfProbe) detach() {
+	if p.raw == nil {
+		return
+	}
+	p.raw.Control(func(fd uintptr) {
+		unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_DETACH_BPF, 0)
+	})
+	p.raw = nil
+}
+
+// close detaches the filter and releases the program and its map
+func (p *ebpfProbe) close() {
+	p.detach()
+	p.program.Close()
+	p.firstData.Close()
+}
+
+// monotonicNanos returns CLOCK_MONOTONIC, the clock bpf_ktime_get_ns reads
+func monotonicNanos() int64 {
+	var ts unix.Timespec
+	unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts)
+	return ts.Nano()
+}
@@ -0,0 +1,31 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// errEBPFUnsupported is returned on platforms without eBPF socket filters
+var errEBPFUnsupported = errors.New("eBPF latency measurement requires Linux")
+
+// ebpfProbe is not available on this platform
+type ebpfProbe struct{}
+
+// ebpfLatencySupported reports whether socket filters can be loaded
+func ebpfLatencySupported() error {
+	return errEBPFUnsupported
+}
+
+func newEBPFProbe() (*ebpfProbe, error) {
+	return nil, errEBPFUnsupported
+}
+
+func (p *ebpfProbe) attach(conn net.Conn) error { return errEBPFUnsupported }
+func (p *ebpfProbe) requestWritten()            {}
+func (p *ebpfProbe) measure() (kernel, app time.Duration, err error) {
+	return 0, 0, errEBPFUnsupported
+}
+func (p *ebpfProbe) close() {}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exit codes with a special meaning when returned by the ELF binary
+const (
+	elfExitError    = 1
+	elfExitSuppress = 2  // suppress further executions for this outage
+	elfExitTempFail = 75 // EX_TEMPFAIL from sysexits.h
+)
+
+// elfRetryDelay is the pause between retries of a failed ELF execution
+const elfRetryDelay = 5 * time.Second
+
+// elfTempFailRetries is how many times exit code 75 is retried when -elf-retries is lower
+const elfTempFailRetries = 3
+
+// elfValidateArg is passed to the ELF binary by -validate-elf
+const elfValidateArg = "--validate"
+
+// runELF executes the ELF binary for event, retrying non-zero exits up to retries times
+// Exit code 75 is retried at least elfTempFailRetries times; exit codes 1 and 2
+// are never retried. Returns event with the final exit code and stderr recorded.
+func runELF(elfPath string, event Event, retries int) Event {
+	// -elf is optional when healing actions handle failures instead
+	if elfPath == "" {
+		return event
+	}
+	for attempt := 0; ; attempt++ {
+		exitCode, stderr := executeELF(elfPath, event)
+		event.ELFExitCode = exitCode
+		event.ELFStderr = stderr
+
+		limit := retries
+		switch exitCode {
+		case 0:
+			return event
+		case elfExitSuppress:
+			return event
+		case elfExitError:
+			// The binary ran and failed, so running it again would fail the same way
+			log.Printf("ERROR: ELF binary failed (exit code %d), not retrying", exitCode)
+			return event
+		case elfExitTempFail:
+			log.Printf("ELF binary reported a temporary failure (exit code %d)", exitCode)
+			limit = max(retries, elfTempFailRetries)
+		default:
+			log.Printf("ELF binary exited with code %d", exitCode)
+		}
+
+		if attempt >= limit {
+			if limit > 0 {
+				log.Printf("Giving up on ELF binary after %d retries", limit)
+			}
+			return event
+		}
+
+		log.Printf("Retrying ELF binary in %s (retry %d/%d)", elfRetryDelay, attempt+1, limit)
+		time.Sleep(elfRetryDelay)
+	}
+}
+
+// elfRunner runs the ELF binary either in the check cycle or, when async, in the background
+// Background runs are bounded so a long outage with a slow binary cannot pile them up
+type elfRunner struct {
+	retries int
+	async   bool
+	slots   chan struct{}
+}
+
+// newELFRunner creates a runner allowing maxConcurrent background runs at once
+func newELFRunner(retries int, async bool, maxConcurrent int) *elfRunner {
+	return &elfRunner{retries: retries, async: async, slots: make(chan struct{}, max(maxConcurrent, 1))}
+}
+
+// run executes the ELF binary at elfPath for event and then calls done, if set, with the result
+// Returns false without running the binary if every background slot is in use
+func (r *elfRunner) run(elfPath string, event Event, done func(Event)) bool {
+	if !r.async {
+		event = runELF(elfPath, event, r.retries)
+		if done != nil {
+			done(event)
+		}
+		return true
+	}
+
+	select {
+	case r.slots <- struct{}{}:
+	default:
+		log.Printf("WARNING: %d ELF binaries are still running, skipping the ELF binary for this %s event", cap(r.slots), event.Type)
+		return false
+	}
+	go func() {
+		defer func() { <-r.slots }()
+		start := time.Now()
+		event := runELF(elfPath, event, r.retries)
+		log.Printf("Background ELF binary for %s event exited with code %d after %s", event.Type, event.ELFExitCode, time.Since(start).Round(time.Millisecond))
+		if done != nil {
+			done(event)
+		}
+	}()
+	return true
+}
+
+// elfGuard tracks ELF executions during the current outage
+// It is safe for concurrent use because overlapping cycles report ELF results asynchronously
+type elfGuard struct {
+	maxExecutions int
+
+	mu         sync.Mutex
+	executions int
+	suspended  bool
+
+	// outage is incremented on recovery so late results from an earlier outage are ignored
+	outage int
+}
+
+// begin reserves an ELF execution for the current outage
+// Returns false if ELF execution is suspended
+func (g *elfGuard) begin() (int, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.suspended {
+		return 0, false
+	}
+	g.executions++
+	return g.outage, true
+}
+
+// finish records the exit code of an execution started with begin
+// Returns a reason if ELF execution is now suspended for the rest of the outage
+func (g *elfGuard) finish(outage, exitCode int) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if outage != g.outage || g.suspended {
+		return ""
+	}
+
+	switch {
+	case exitCode == elfExitSuppress:
+		g.suspended = true
+		return fmt.Sprintf("ELF binary exited with code %d", exitCode)
+	case g.maxExecutions > 0 && g.executions >= g.maxExecutions:
+		g.suspended = true
+		return fmt.Sprintf("reached %d ELF executions", g.executions)
+	}
+	return ""
+}
+
+// cancel releases an execution reserved with begin that did not run
+func (g *elfGuard) cancel() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.executions--
+}
+
+// recover ends the current outage, returning true if ELF execution had been suspended
+func (g *elfGuard) recover() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	wasSuspended := g.suspended
+	g.executions = 0
+	g.suspended = false
+	g.outage++
+	return wasSuspended
+}
+
+// validateELF runs the ELF binary with --validate and WEBSITECHECK_EVENT=validate
+// and returns an error unless it exits with code 0 within timeout.
+// The combined output is returned for logging either way.
+func validateELF(elfPath string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, elfPath, elfValidateArg)
+	cmd.Env = append(os.Environ(), "WEBSITECHECK_EVENT=validate")
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		err = fmt.Errorf("exited with code %d", exitErr.ExitCode())
+	}
+	return strings.TrimSpace(output.String()), err
+}
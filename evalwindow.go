@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// EvalMode controls when failed checks count as the site being down
+type EvalMode string
+
+// Supported evaluation modes
+const (
+	EvalConsecutive EvalMode = "consecutive"
+	EvalWindow      EvalMode = "window"
+)
+
+// parseEvalMode validates an -eval-mode value
+func parseEvalMode(s string) (EvalMode, error) {
+	switch mode := EvalMode(s); mode {
+	case EvalConsecutive, EvalWindow:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown evaluation mode %q (expected consecutive or window)", s)
+	}
+}
+
+// evalWindow decides whether the site is down from the last size checks
+// The site is down while at least failures of them failed, so a site that
+// alternates between passing and failing is still caught
+type evalWindow struct {
+	size     int
+	failures int
+
+	// outcomes is a ring of the last checks, true for a failure
+	outcomes []bool
+	next     int
+	lastErr  error
+}
+
+// newEvalWindow creates a window over the last size checks
+func newEvalWindow(failures, size int) *evalWindow {
+	return &evalWindow{size: size, failures: failures, outcomes: make([]bool, 0, size)}
+}
+
+// evaluate records the outcome of a check and returns the error to treat it as
+// Below the threshold a failure is only logged and nil is returned. At or above
+// it the site is down even if this check passed, with the latest failure as the cause
+func (w *evalWindow) evaluate(url string, checkErr error) error {
+	if len(w.outcomes) < w.size {
+		w.outcomes = append(w.outcomes, checkErr != nil)
+	} else {
+		w.outcomes[w.next] = checkErr != nil
+		w.next = (w.next + 1) % w.size
+	}
+	if checkErr != nil {
+		w.lastErr = checkErr
+	}
+
+	failed := 0
+	for _, outcome := range w.outcomes {
+		if outcome {
+			failed++
+		}
+	}
+	if failed < w.failures {
+		if checkErr != nil {
+			log.Printf("Check of %s failed (%v), %d of the last %d checks failed, below -eval-failures %d", url, checkErr, failed, len(w.outcomes), w.failures)
+		}
+		return nil
+	}
+	if checkErr != nil {
+		return checkErr
+	}
+	return fmt.Errorf("%d of the last %d checks failed, most recently: %w", failed, len(w.outcomes), w.lastErr)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Event types passed to the ELF binary
+const (
+	EventDown         = "down"
+	EventPartial      = "partial"
+	EventDegraded     = "degraded"
+	EventDNSChange    = "dns-change"
+	EventRecovery     = "recovery"
+	EventCertExpiring = "cert-expiring"
+	EventCanary       = "canary-mismatch"
+	EventFailureRate  = "failure-rate"
+	EventSizeAnomaly  = "size-anomaly"
+	EventServerErrors = "server-errors"
+)
+
+// Severity levels attached to events
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+)
+
+// Event describes why the ELF binary is being executed
+type Event struct {
+	Type     string    `json:"type"`
+	Severity string    `json:"severity"`
+	URL      string    `json:"url"`
+	Time     time.Time `json:"timestamp"`
+	Message  string    `json:"message,omitempty"`
+
+	// LocalTime is Time in the -timezone location, unless that is UTC
+	LocalTime string `json:"local_time,omitempty"`
+
+	// FinalURL is where the URL last resolved to after redirects
+	FinalURL string `json:"final_url,omitempty"`
+
+	// Error is the failure behind a down or degraded event, for notifiers that inspect it
+	// with errors.Is or errors.As; Message carries its text
+	Error error `json:"-"`
+
+	// ResolvedIPs are the addresses the hostname resolved to before the check
+	ResolvedIPs []string `json:"resolved_ips,omitempty"`
+
+	// Phases are the spans of the check behind the event, such as dns_resolve and tls_handshake
+	Phases []phaseSpan `json:"phases,omitempty"`
+
+	// FailureReason classifies the failure for routing, e.g. Timeout or HTTPError5xx
+	FailureReason FailureReason `json:"failure_reason,omitempty"`
+
+	// ConsecutiveFailures is the number of failed checks in the current outage
+	ConsecutiveFailures int `json:"consecutive_failures,omitempty"`
+
+	// Diagnostic holds extra troubleshooting output such as a traceroute
+	Diagnostic string `json:"diagnostic,omitempty"`
+
+	// ELFExitCode and ELFStderr record the result of running the ELF binary for this event
+	ELFExitCode int    `json:"elf_exit_code,omitempty"`
+	ELFStderr   string `json:"elf_stderr,omitempty"`
+}
+
+// newEvent creates an event for url stamped with the current time
+func newEvent(eventType, severity, url, message string) Event {
+	now := time.Now().UTC()
+	return Event{
+		Type:      eventType,
+		Severity:  severity,
+		URL:       url,
+		Time:      now,
+		Message:   message,
+		LocalTime: localTime(now),
+	}
+}
+
+// environ returns the environment for the ELF binary with the event fields added
+// The full event is also available as JSON in WEBSITECHECK_EVENT_JSON
+func (e Event) environ() []string {
+	env := append(os.Environ(),
+		"WEBSITECHECK_EVENT="+e.Type,
+		"WEBSITECHECK_SEVERITY="+e.Severity,
+		"WEBSITECHECK_URL="+e.URL,
+		"WEBSITECHECK_TIMESTAMP="+e.Time.Format(time.RFC3339),
+		"WEBSITECHECK_MESSAGE="+e.Message,
+		"WEBSITECHECK_FINAL_URL="+e.FinalURL,
+		"WEBSITECHECK_FAILURE_REASON="+string(e.FailureReason),
+		"WEBSITECHECK_DIAGNOSTIC="+e.Diagnostic,
+		"WEBSITECHECK_RESOLVED_IPS="+strings.Join(e.ResolvedIPs, ","),
+	)
+
+	if data, err := json.Marshal(e); err == nil {
+		env = append(env, fmt.Sprintf("WEBSITECHECK_EVENT_JSON=%s", data))
+	}
+	return env
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// eventBuffer keeps the events a notifier could not publish, up to size of
+// them, and retries them in order until the service accepts them
+// Retries back off exponentially from minDelay to maxDelay after each failure
+type eventBuffer struct {
+	name     string
+	size     int
+	minDelay time.Duration
+	maxDelay time.Duration
+
+	// publish sends events in order and returns how many were accepted
+	publish func(events []Event) (int, error)
+
+	mu      sync.Mutex
+	pending []Event
+	wake    chan struct{}
+}
+
+// newEventBuffer creates a buffer and starts its retry loop
+// name identifies the service in log messages, such as "Redis localhost:6379"
+func newEventBuffer(name string, size int, minDelay, maxDelay time.Duration, publish func([]Event) (int, error)) *eventBuffer {
+	b := &eventBuffer{
+		name:     name,
+		size:     size,
+		minDelay: minDelay,
+		maxDelay: maxDelay,
+		publish:  publish,
+		wake:     make(chan struct{}, 1),
+	}
+	go b.retryLoop()
+	return b
+}
+
+// add buffers event behind the pending ones and publishes them all
+// The oldest buffered event is dropped when the buffer is full
+func (b *eventBuffer) add(event Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.pending) >= b.size {
+		log.Printf("WARNING: %s buffer full, dropping buffered %s event from %s", b.name, b.pending[0].Type, b.pending[0].Time.Format(time.RFC3339))
+		b.pending = b.pending[1:]
+	}
+	b.pending = append(b.pending, event)
+	if err := b.flush(); err != nil {
+		select {
+		case b.wake <- struct{}{}:
+		default:
+		}
+		return fmt.Errorf("%v (%d events buffered)", err, len(b.pending))
+	}
+	return nil
+}
+
+// flush publishes the pending events, keeping those that were not accepted
+// The caller must hold mu
+func (b *eventBuffer) flush() error {
+	if len(b.pending) == 0 {
+		return nil
+	}
+	sent, err := b.publish(b.pending)
+	b.pending = b.pending[sent:]
+	return err
+}
+
+// retryLoop retries buffered events after a failed publish until they are accepted
+func (b *eventBuffer) retryLoop() {
+	for range b.wake {
+		for delay := b.minDelay; ; delay = min(delay*2, b.maxDelay) {
+			time.Sleep(delay)
+			b.mu.Lock()
+			count := len(b.pending)
+			err := b.flush()
+			b.mu.Unlock()
+			if err == nil {
+				if count > 0 {
+					log.Printf("%s is reachable again, published %d buffered events", b.name, count)
+				}
+				break
+			}
+		}
+	}
+}
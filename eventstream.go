@@ -0,0 +1,119 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// eventStreamBuffer is how many events a GET /events client may fall behind by
+// before it is disconnected
+const eventStreamBuffer = 100
+
+// eventWriteTimeout limits writing one event to a GET /events client
+const eventWriteTimeout = 10 * time.Second
+
+// eventStream fans events out to the WebSocket clients of GET /events
+// It is a Notifier, so clients see every down and recovery event
+type eventStream struct {
+	upgrader websocket.Upgrader
+	nextID   atomic.Int64
+
+	// clients maps a client ID to its eventClient
+	clients sync.Map
+}
+
+// eventClient is one connected GET /events client
+type eventClient struct {
+	events chan Event
+
+	// dropped is closed when the client fell too far behind
+	dropped    chan struct{}
+	dropOnce   sync.Once
+	remoteAddr string
+}
+
+// newEventStream creates a stream with no clients
+// Browsers only connect from the API's own origin or one of corsOrigins, so
+// another site open in the browser cannot read the events
+func newEventStream(corsOrigins []string) *eventStream {
+	return &eventStream{upgrader: websocket.Upgrader{CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || originAllowed(corsOrigins, origin) {
+			return true
+		}
+		parsed, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(parsed.Host, r.Host)
+	}}}
+}
+
+// Name implements Notifier
+func (s *eventStream) Name() string {
+	return "GET /events WebSocket clients"
+}
+
+// Notify implements Notifier
+// It never blocks: a client whose buffer is full is disconnected instead
+func (s *eventStream) Notify(event Event) error {
+	s.clients.Range(func(_, value any) bool {
+		client := value.(*eventClient)
+		select {
+		case client.events <- event:
+		default:
+			client.dropOnce.Do(func() {
+				log.Printf("WARNING: Event stream client %s fell %d events behind, disconnecting", client.remoteAddr, eventStreamBuffer)
+				close(client.dropped)
+			})
+		}
+		return true
+	})
+	return nil
+}
+
+// handleEvents upgrades the request to a WebSocket and sends each event as a JSON text frame
+func (s *eventStream) handleEvents(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already written an error response
+		return
+	}
+	defer conn.Close()
+
+	id := s.nextID.Add(1)
+	client := &eventClient{events: make(chan Event, eventStreamBuffer), dropped: make(chan struct{}), remoteAddr: r.RemoteAddr}
+	s.clients.Store(id, client)
+	defer s.clients.Delete(id)
+
+	// Reading is needed to notice when the client goes away; messages from it are ignored
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case event := <-client.events:
+			conn.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-client.dropped:
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "too slow"), time.Now().Add(time.Second))
+			return
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,38 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// validateExecutable checks that path is a Windows executable
+// Windows has no execute permission bit, so the file must have an .exe
+// extension or start with the MZ header of a PE binary
+func validateExecutable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is not executable", path)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".exe") {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(f, header); err != nil || string(header) != "MZ" {
+		return fmt.Errorf("%s is not an .exe file or PE binary", path)
+	}
+	return nil
+}
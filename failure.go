@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// FailureReason classifies why a check failed so notifications can be routed by cause
+type FailureReason string
+
+// Failure reasons reported in events
+const (
+	FailureConnectionRefused      FailureReason = "ConnectionRefused"
+	FailureDNSResolutionFailed    FailureReason = "DNSResolutionFailed"
+	FailureTimeout                FailureReason = "Timeout"
+	FailureTLSHandshakeFailed     FailureReason = "TLSHandshakeFailed"
+	FailureCertificateExpired     FailureReason = "CertificateExpired"
+	FailureCertificatePinMismatch FailureReason = "CertificatePinMismatch"
+	FailureTLSCipherNotAllowed    FailureReason = "TLSCipherNotAllowed"
+	FailureTLSVersionTooOld       FailureReason = "TLSVersionTooOld"
+	FailureHTTPError4xx           FailureReason = "HTTPError4xx"
+	FailureHTTPError5xx           FailureReason = "HTTPError5xx"
+	FailureBodyAssertionFailed    FailureReason = "BodyAssertionFailed"
+	FailureLatencyExceeded        FailureReason = "LatencyExceeded"
+	FailureContentChanged         FailureReason = "ContentChanged"
+	FailureHeaderChanged          FailureReason = "HeaderChanged"
+	FailureMixedContent           FailureReason = "MixedContent"
+	FailureHSTSCheckFailed        FailureReason = "HSTSCheckFailed"
+	FailureCSPCheckFailed         FailureReason = "CSPCheckFailed"
+	FailureCanaryMismatch         FailureReason = "CanaryMismatch"
+	FailureUnknown                FailureReason = "Unknown"
+)
+
+// reasonError attaches a failure reason to an error without changing its message
+type reasonError struct {
+	reason FailureReason
+	err    error
+}
+
+func (e *reasonError) Error() string { return e.err.Error() }
+func (e *reasonError) Unwrap() error { return e.err }
+
+// withReason marks err as failing for reason
+// Used where the reason is known but cannot be told from the error itself,
+// such as a body that does not match its schema
+func withReason(reason FailureReason, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &reasonError{reason: reason, err: err}
+}
+
+// classifyError returns the failure reason for a failed check
+// resp is the response that failed the check, if one was received
+func classifyError(err error, resp *http.Response) FailureReason {
+	if resp != nil {
+		switch {
+		case resp.StatusCode >= 500 && resp.StatusCode <= 599:
+			return FailureHTTPError5xx
+		case resp.StatusCode >= 400 && resp.StatusCode <= 499:
+			return FailureHTTPError4xx
+		}
+	}
+	if err == nil {
+		return FailureUnknown
+	}
+
+	var marked *reasonError
+	if errors.As(err, &marked) {
+		return marked.reason
+	}
+
+	// Certificate errors are checked before timeouts and connection errors
+	// because the TLS handshake wraps them in net.OpError
+	var invalid x509.CertificateInvalidError
+	if errors.As(err, &invalid) && invalid.Reason == x509.Expired {
+		return FailureCertificateExpired
+	}
+	var tooOld *tlsVersionError
+	if errors.As(err, &tooOld) {
+		return FailureTLSVersionTooOld
+	}
+	var verification *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var recordHeader tls.RecordHeaderError
+	var alert tls.AlertError
+	if errors.As(err, &verification) || errors.As(err, &invalid) || errors.As(err, &unknownAuthority) ||
+		errors.As(err, &hostname) || errors.As(err, &recordHeader) || errors.As(err, &alert) {
+		return FailureTLSHandshakeFailed
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && !dnsErr.IsTimeout {
+		return FailureDNSResolutionFailed
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return FailureTimeout
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return FailureConnectionRefused
+	}
+	return FailureUnknown
+}
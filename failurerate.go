@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// failureRateUnits are the periods a -failure-rate-alert can be given per
+var failureRateUnits = map[string]time.Duration{
+	"s":    time.Second,
+	"sec":  time.Second,
+	"min":  time.Minute,
+	"h":    time.Hour,
+	"hour": time.Hour,
+}
+
+// parseFailureRate parses a -failure-rate-alert value such as 0.5/min
+// The result is the number of failures per minute
+func parseFailureRate(s string) (float64, error) {
+	count, unit, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return 0, fmt.Errorf("invalid failure rate %q (expected a rate such as 0.5/min)", s)
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(count), 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid failure count %q in %q", count, s)
+	}
+	period, ok := failureRateUnits[strings.TrimSpace(unit)]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q in %q (expected s, min or h)", unit, s)
+	}
+	return n * float64(time.Minute) / float64(period), nil
+}
+
+// checkOutcome is one check recorded by a failureRateTracker
+type checkOutcome struct {
+	at     time.Time
+	failed bool
+}
+
+// failureRateTracker computes the failure rate over the last checks
+// Every check is counted, including those made while backing off
+type failureRateTracker struct {
+	checks []checkOutcome
+	size   int
+	next   int
+}
+
+// newFailureRateTracker tracks the last size checks
+func newFailureRateTracker(size int) *failureRateTracker {
+	return &failureRateTracker{checks: make([]checkOutcome, 0, size), size: size}
+}
+
+// record adds the outcome of a check, replacing the oldest once full
+func (t *failureRateTracker) record(at time.Time, failed bool) {
+	outcome := checkOutcome{at: at, failed: failed}
+	if len(t.checks) < t.size {
+		t.checks = append(t.checks, outcome)
+		return
+	}
+	t.checks[t.next] = outcome
+	t.next = (t.next + 1) % t.size
+}
+
+// rate returns the failures per minute over the recorded checks
+// It reports false until the window is full, so a failure in the first
+// few checks after startup is not taken as a high rate
+func (t *failureRateTracker) rate() (float64, int, bool) {
+	if len(t.checks) < t.size || t.size < 2 {
+		return 0, 0, false
+	}
+	oldest := t.checks[t.next].at
+	newest := t.checks[(t.next+t.size-1)%t.size].at
+	span := newest.Sub(oldest)
+	if span <= 0 {
+		return 0, 0, false
+	}
+
+	failures := 0
+	for _, check := range t.checks {
+		if check.failed {
+			failures++
+		}
+	}
+	return float64(failures) / span.Minutes(), failures, true
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// graphQLRequestBody encodes query as a GraphQL-over-HTTP JSON request
+func graphQLRequestBody(query string) ([]byte, error) {
+	return json.Marshal(map[string]string{"query": query})
+}
+
+// checkGraphQLResponse validates a GraphQL response body
+// Any top-level "errors" fail the check, as does expectField not resolving
+// to a non-null value inside "data"
+func checkGraphQLResponse(body []byte, expectField string) error {
+	var response struct {
+		Data   any               `json:"data"`
+		Errors []json.RawMessage `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("invalid GraphQL response: %v", err)
+	}
+
+	if len(response.Errors) > 0 {
+		var first struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(response.Errors[0], &first)
+		return fmt.Errorf("GraphQL returned %d errors, first: %s", len(response.Errors), first.Message)
+	}
+
+	if expectField != "" {
+		value, err := evalJSONPath(response.Data, expectField)
+		if err != nil {
+			return fmt.Errorf("expected field %s: %v", expectField, err)
+		}
+		if value == nil {
+			return fmt.Errorf("expected field %s is null", expectField)
+		}
+	}
+	return nil
+}
+
+// evalJSONPath resolves a simple JSONPath such as "$.user.posts[0].id"
+// against a decoded JSON document. Only child and index selectors are supported.
+func evalJSONPath(doc any, path string) (any, error) {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$")
+	path = strings.ReplaceAll(path, "[", ".[")
+
+	current := doc
+	for _, part := range strings.Split(path, ".") {
+		if part == "" {
+			continue
+		}
+
+		if strings.HasPrefix(part, "[") {
+			index, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(part, "["), "]"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %s", part)
+			}
+			list, ok := current.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s applied to a non-array", part)
+			}
+			if index < 0 || index >= len(list) {
+				return nil, fmt.Errorf("index %d out of range", index)
+			}
+			current = list[index]
+			continue
+		}
+
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%q applied to a non-object", part)
+		}
+		value, ok := object[part]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		current = value
+	}
+	return current, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// checkGRPCHealth calls the standard gRPC health service at addr, retrying like checkWebsiteDown
+// The server as a whole must report SERVING
+func checkGRPCHealth(addr string, timeout time.Duration, opts checkOptions) (*checkResult, error) {
+	return retryCheck("gRPC", opts, func() (*checkResult, error) {
+		conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		start := time.Now()
+		resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			return nil, fmt.Errorf("gRPC health check failed: %w", err)
+		}
+		if resp.Status != healthpb.HealthCheckResponse_SERVING {
+			return nil, fmt.Errorf("gRPC health status is %s", resp.Status)
+		}
+		return &checkResult{Latency: time.Since(start)}, nil
+	})
+}
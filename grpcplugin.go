@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// pluginCheckMethod is the full gRPC method name of CheckService.Check in checkplugin.proto
+const pluginCheckMethod = "/websitecheck.plugin.v1.CheckService/Check"
+
+// pluginCheckRequest mirrors the CheckRequest message in checkplugin.proto
+type pluginCheckRequest struct {
+	url     string
+	options map[string]string
+}
+
+// pluginCheckResult mirrors the CheckResult message in checkplugin.proto
+type pluginCheckResult struct {
+	up        bool
+	err       string
+	latencyMS int64
+}
+
+// pluginCodec encodes the plugin messages in protobuf wire format
+// The messages are small enough to encode by hand, which avoids generated code
+type pluginCodec struct{}
+
+func (pluginCodec) Name() string { return "proto" }
+
+func (pluginCodec) Marshal(v any) ([]byte, error) {
+	req, ok := v.(*pluginCheckRequest)
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal %T", v)
+	}
+
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, req.url)
+
+	// Map entries are embedded messages with the key in field 1 and the value in field 2
+	keys := make([]string, 0, len(req.options))
+	for key := range req.options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var entry []byte
+		entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, key)
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendString(entry, req.options[key])
+
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+	return b, nil
+}
+
+func (pluginCodec) Unmarshal(data []byte, v any) error {
+	result, ok := v.(*pluginCheckResult)
+	if !ok {
+		return fmt.Errorf("cannot unmarshal into %T", v)
+	}
+
+	*result = pluginCheckResult{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			value, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			result.up = value != 0
+			data = data[n:]
+		case num == 2 && typ == protowire.BytesType:
+			value, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			result.err = value
+			data = data[n:]
+		case num == 3 && typ == protowire.VarintType:
+			value, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			result.latencyMS = int64(value)
+			data = data[n:]
+		default:
+			// Skip fields added by newer versions of the protocol
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	return nil
+}
+
+// grpcCheckPlugin delegates checks to an external CheckService
+// The client connection is kept open and reconnects by itself after failures
+type grpcCheckPlugin struct {
+	addr    string
+	conn    *grpc.ClientConn
+	options map[string]string
+	timeout time.Duration
+}
+
+// newGRPCCheckPlugin creates a persistent connection to the plugin at addr
+// The connection is established lazily on the first check
+func newGRPCCheckPlugin(addr string, options map[string]string, timeout time.Duration) (*grpcCheckPlugin, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+
+	if options == nil {
+		options = make(map[string]string)
+	}
+	options["timeout"] = strconv.FormatFloat(timeout.Seconds(), 'f', -1, 64)
+	return &grpcCheckPlugin{addr: addr, conn: conn, options: options, timeout: timeout}, nil
+}
+
+// check asks the plugin whether url is up
+// Failing to reach the plugin counts as the URL being down
+func (p *grpcCheckPlugin) check(url string) (*checkResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	req := &pluginCheckRequest{url: url, options: p.options}
+	var result pluginCheckResult
+	start := time.Now()
+	err := p.conn.Invoke(ctx, pluginCheckMethod, req, &result, grpc.ForceCodec(pluginCodec{}), grpc.WaitForReady(true))
+	if err != nil {
+		return nil, fmt.Errorf("check plugin %s failed: %v", p.addr, err)
+	}
+
+	latency := time.Duration(result.latencyMS) * time.Millisecond
+	if latency == 0 {
+		latency = time.Since(start)
+	}
+	if !result.up {
+		if result.err == "" {
+			return nil, errors.New("check plugin reported down")
+		}
+		return nil, errors.New(result.err)
+	}
+	return &checkResult{Latency: latency}, nil
+}
+
+// parsePluginOptions parses a comma-separated list of key=value options
+func parsePluginOptions(list string) (map[string]string, error) {
+	options := make(map[string]string)
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid option %q (expected key=value)", field)
+		}
+		options[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return options, nil
+}
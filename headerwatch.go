@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// headerWatch remembers the values of the -watch-headers between checks
+type headerWatch struct {
+	names []string
+	last  map[string]string
+}
+
+// newHeaderWatch watches the comma-separated header names in list
+func newHeaderWatch(list string) *headerWatch {
+	w := &headerWatch{}
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			w.names = append(w.names, http.CanonicalHeaderKey(name))
+		}
+	}
+	return w
+}
+
+// observe records the watched headers and describes each that changed since the
+// previous check. The first check only records the values
+func (w *headerWatch) observe(header http.Header) []string {
+	current := make(map[string]string, len(w.names))
+	for _, name := range w.names {
+		current[name] = strings.Join(header.Values(name), ", ")
+	}
+	previous := w.last
+	w.last = current
+	if previous == nil {
+		return nil
+	}
+
+	var changes []string
+	for _, name := range w.names {
+		if previous[name] != current[name] {
+			changes = append(changes, fmt.Sprintf("%s changed from %s to %s", name, headerValue(previous[name]), headerValue(current[name])))
+		}
+	}
+	return changes
+}
+
+// headerValue quotes a header value for logging, naming a missing header
+func headerValue(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return fmt.Sprintf("%q", value)
+}
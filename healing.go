@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultHealingTimeout limits each precondition and action unless the action sets its own
+const defaultHealingTimeout = 60 * time.Second
+
+// failureReasons are the reasons a healing action can be triggered by
+var failureReasons = []FailureReason{
+	FailureConnectionRefused, FailureDNSResolutionFailed, FailureTimeout, FailureTLSHandshakeFailed,
+	FailureCertificateExpired, FailureCertificatePinMismatch, FailureTLSCipherNotAllowed, FailureTLSVersionTooOld, FailureHTTPError4xx, FailureHTTPError5xx,
+	FailureBodyAssertionFailed, FailureLatencyExceeded, FailureContentChanged, FailureHeaderChanged, FailureMixedContent, FailureHSTSCheckFailed, FailureCSPCheckFailed, FailureCanaryMismatch, FailureUnknown,
+}
+
+// healingAction is one remediation step from the -healing-actions file
+type healingAction struct {
+	Name         string          `yaml:"name"`
+	Trigger      []FailureReason `yaml:"trigger"`
+	Precondition string          `yaml:"precondition"`
+	Action       string          `yaml:"action"`
+	Cooldown     int             `yaml:"cooldown"`
+	Timeout      int             `yaml:"timeout"`
+}
+
+// triggeredBy reports whether the action handles reason
+func (a healingAction) triggeredBy(reason FailureReason) bool {
+	for _, trigger := range a.Trigger {
+		if trigger == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// timeout returns how long the precondition and action may each run
+func (a healingAction) timeout() time.Duration {
+	if a.Timeout > 0 {
+		return time.Duration(a.Timeout) * time.Second
+	}
+	return defaultHealingTimeout
+}
+
+// healingRegistry runs the healing actions matching each failure
+// Actions run in file order and each is skipped while in its cooldown
+type healingRegistry struct {
+	actions []healingAction
+
+	mu      sync.Mutex
+	lastRun map[string]time.Time
+}
+
+// loadHealingRegistry reads and validates a -healing-actions file
+func loadHealingRegistry(path string) (*healingRegistry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Actions []healingAction `yaml:"actions"`
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid healing actions file %s: %v", path, err)
+	}
+	if len(file.Actions) == 0 {
+		return nil, fmt.Errorf("healing actions file %s defines no actions", path)
+	}
+
+	known := make(map[FailureReason]bool, len(failureReasons))
+	for _, reason := range failureReasons {
+		known[reason] = true
+	}
+	names := make(map[string]bool, len(file.Actions))
+	var errs []error
+	for i, action := range file.Actions {
+		switch {
+		case action.Name == "":
+			errs = append(errs, fmt.Errorf("action %d has no name", i+1))
+		case names[action.Name]:
+			errs = append(errs, fmt.Errorf("duplicate action name %q", action.Name))
+		}
+		names[action.Name] = true
+		if len(action.Trigger) == 0 {
+			errs = append(errs, fmt.Errorf("action %q has no trigger", action.Name))
+		}
+		for _, trigger := range action.Trigger {
+			if !known[trigger] {
+				errs = append(errs, fmt.Errorf("action %q: unknown failure reason %q", action.Name, trigger))
+			}
+		}
+		if strings.TrimSpace(action.Action) == "" {
+			errs = append(errs, fmt.Errorf("action %q has no action", action.Name))
+		}
+		if action.Cooldown < 0 || action.Timeout < 0 {
+			errs = append(errs, fmt.Errorf("action %q: cooldown and timeout must not be negative", action.Name))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid healing actions file %s: %v", path, err)
+	}
+	return &healingRegistry{actions: file.Actions, lastRun: make(map[string]time.Time)}, nil
+}
+
+// run executes every action triggered by the event's failure reason
+// Each step is logged so the remediation history can be audited
+func (r *healingRegistry) run(event Event) {
+	if until, ok := alertSuppressions.active(event.URL); ok {
+		log.Printf("Healing actions for %s suppressed until %s", event.URL, until.Format(time.RFC3339))
+		return
+	}
+
+	for _, action := range r.actions {
+		if !action.triggeredBy(event.FailureReason) {
+			continue
+		}
+
+		r.mu.Lock()
+		last, ran := r.lastRun[action.Name]
+		cooldown := time.Duration(action.Cooldown) * time.Second
+		if ran && time.Since(last) < cooldown {
+			r.mu.Unlock()
+			log.Printf("Healing action %s skipped: in cooldown until %s", action.Name, last.Add(cooldown).Format(time.RFC3339))
+			continue
+		}
+		r.mu.Unlock()
+
+		if action.Precondition != "" {
+			if output, err := runHealingCommand(action.Precondition, event, action.timeout()); err != nil {
+				log.Printf("Healing action %s skipped: precondition %q failed: %v%s", action.Name, action.Precondition, err, output)
+				continue
+			}
+		}
+
+		r.mu.Lock()
+		r.lastRun[action.Name] = time.Now()
+		r.mu.Unlock()
+
+		log.Printf("Running healing action %s for %s (%s): %s", action.Name, event.URL, event.FailureReason, action.Action)
+		if output, err := runHealingCommand(action.Action, event, action.timeout()); err != nil {
+			log.Printf("ERROR: Healing action %s failed: %v%s", action.Name, err, output)
+		} else {
+			log.Printf("Healing action %s succeeded%s", action.Name, output)
+		}
+	}
+}
+
+// runHealingCommand runs command with sh -c and the event in its environment
+// The returned string holds the command's combined output, if any, for logging
+func runHealingCommand(command string, event Event, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Env = event.environ()
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+
+	text := strings.TrimSpace(string(output))
+	if text != "" {
+		text = ": " + text
+	}
+	return text, err
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// heartbeat logs that the monitor is alive at a fixed interval, whatever the
+// check results, and touches a file whose age shows whether the process stalled
+// The file is only touched when a check has completed since the last beat, so
+// it goes stale when the check loop is stuck even though the ticker still runs
+type heartbeat struct {
+	file string
+
+	checks     atomic.Int64
+	downEvents atomic.Int64
+
+	// beatChecks is the number of checks completed at the last beat
+	beatChecks int64
+}
+
+// newHeartbeat starts a heartbeat every interval, writing the time to file if set
+func newHeartbeat(interval time.Duration, file string) *heartbeat {
+	h := &heartbeat{file: file}
+	h.writeFile()
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			h.beat()
+		}
+	}()
+	return h
+}
+
+// recordCheck counts a completed check and whether it raised a down event
+func (h *heartbeat) recordCheck(down bool) {
+	h.checks.Add(1)
+	if down {
+		h.downEvents.Add(1)
+	}
+}
+
+// beat logs the heartbeat and updates the heartbeat file if a check completed since the last beat
+func (h *heartbeat) beat() {
+	checks := h.checks.Load()
+	log.Printf("websitecheck alive, %d checks completed, %d down events", checks, h.downEvents.Load())
+	if checks == h.beatChecks {
+		return
+	}
+	h.beatChecks = checks
+	h.writeFile()
+}
+
+// writeFile records the current time in the heartbeat file
+func (h *heartbeat) writeFile() {
+	if h.file == "" {
+		return
+	}
+	if err := os.WriteFile(h.file, []byte(time.Now().UTC().Format(time.RFC3339)+"\n"), 0644); err != nil {
+		log.Printf("Failed to write heartbeat file: %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+// uptimeWindow is the period over which uptime is reported
+const uptimeWindow = 30 * 24 * time.Hour
+
+// bloomFalsePositiveRate is the target false positive rate of the history bloom filters
+// A false positive marks an hour as down, so uptime can only be under-reported
+const bloomFalsePositiveRate = 0.001
+
+// checkHistory records check outcomes and answers uptime queries
+type checkHistory interface {
+	record(url string, at time.Time, up bool)
+	uptime(url string, now time.Time) (float64, bool)
+}
+
+// bloomFilter is a fixed-size set membership filter with no false negatives
+type bloomFilter struct {
+	bits   []uint64
+	m      uint64
+	hashes uint64
+}
+
+// bloomParameters returns the bit count and hash count for n items at false positive rate p
+func bloomParameters(n int, p float64) (m uint64, k uint64) {
+	if n < 1 {
+		n = 1
+	}
+	bits := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	hashes := math.Round(bits / float64(n) * math.Ln2)
+	return uint64(math.Max(bits, 64)), uint64(math.Max(hashes, 1))
+}
+
+// newBloomFilter creates an empty filter with m bits and k hashes
+func newBloomFilter(m, k uint64) *bloomFilter {
+	return &bloomFilter{bits: make([]uint64, (m+63)/64), m: m, hashes: k}
+}
+
+// locations returns the bit positions for key using double hashing
+func (f *bloomFilter) locations(key string) []uint64 {
+	h := fnv.New128a()
+	h.Write([]byte(key))
+	sum := h.Sum(nil)
+
+	var h1, h2 uint64
+	for i := 0; i < 8; i++ {
+		h1 = h1<<8 | uint64(sum[i])
+		h2 = h2<<8 | uint64(sum[8+i])
+	}
+
+	locations := make([]uint64, f.hashes)
+	for i := range locations {
+		locations[i] = (h1 + uint64(i)*h2) % f.m
+	}
+	return locations
+}
+
+// add inserts key into the filter
+func (f *bloomFilter) add(key string) {
+	for _, bit := range f.locations(key) {
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// has reports whether key may have been added
+func (f *bloomFilter) has(key string) bool {
+	for _, bit := range f.locations(key) {
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomHistory stores only whether each URL was down during each hour
+// Two filters cover the current and previous uptime window so the oldest
+// hours can be dropped by discarding a whole filter
+type bloomHistory struct {
+	m, k uint64
+
+	mu         sync.Mutex
+	current    *bloomFilter
+	previous   *bloomFilter
+	epochStart time.Time
+	firstSeen  map[string]time.Time
+}
+
+// newBloomHistory sizes the filters for urls URLs checked every interval
+// Each URL adds at most one item per hour however often it is checked
+func newBloomHistory(urls int, interval time.Duration) *bloomHistory {
+	hours := int(uptimeWindow / time.Hour)
+	if interval > 0 {
+		if checks := int(uptimeWindow / interval); checks < hours {
+			hours = checks
+		}
+	}
+
+	m, k := bloomParameters(urls*hours, bloomFalsePositiveRate)
+	return &bloomHistory{
+		m:          m,
+		k:          k,
+		current:    newBloomFilter(m, k),
+		previous:   newBloomFilter(m, k),
+		epochStart: time.Now(),
+		firstSeen:  make(map[string]time.Time),
+	}
+}
+
+// hourKey identifies url during the hour containing at
+func hourKey(url string, at time.Time) string {
+	return url + "@" + at.UTC().Truncate(time.Hour).Format(time.RFC3339)
+}
+
+// record marks the hour as down when the check failed
+func (h *bloomHistory) record(url string, at time.Time, up bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if at.Sub(h.epochStart) >= uptimeWindow {
+		h.previous = h.current
+		h.current = newBloomFilter(h.m, h.k)
+		h.epochStart = at
+	}
+	if _, ok := h.firstSeen[url]; !ok {
+		h.firstSeen[url] = at
+	}
+	if !up {
+		h.current.add(hourKey(url, at))
+	}
+}
+
+// uptime returns the percentage of hours in the window during which url was never down
+func (h *bloomHistory) uptime(url string, now time.Time) (float64, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	first, ok := h.firstSeen[url]
+	if !ok {
+		return 0, false
+	}
+	start := now.Add(-uptimeWindow)
+	if first.After(start) {
+		start = first
+	}
+
+	total, down := 0, 0
+	for hour := start.UTC().Truncate(time.Hour); !hour.After(now); hour = hour.Add(time.Hour) {
+		total++
+		key := hourKey(url, hour)
+		if h.current.has(key) || h.previous.has(key) {
+			down++
+		}
+	}
+	return float64(total-down) * 100 / float64(total), true
+}
+
+// historyRecord is a single check outcome kept by exactHistory
+type historyRecord struct {
+	at int64
+	up bool
+}
+
+// historyRecordSize is the in-memory size of a historyRecord
+const historyRecordSize = 16
+
+// exactHistory keeps every check outcome in a fixed-size ring buffer per URL
+// so memory use never exceeds the configured budget
+type exactHistory struct {
+	capacity int
+
+	mu      sync.Mutex
+	records map[string]*historyRing
+}
+
+// historyRing is a ring buffer of the most recent checks for one URL
+type historyRing struct {
+	records []historyRecord
+	next    int
+	full    bool
+}
+
+// newExactHistory splits budget bytes evenly between urls URLs
+func newExactHistory(urls int, budget int64) *exactHistory {
+	if urls < 1 {
+		urls = 1
+	}
+	capacity := int(budget / historyRecordSize / int64(urls))
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &exactHistory{capacity: capacity, records: make(map[string]*historyRing)}
+}
+
+// record appends a check outcome, overwriting the oldest once the ring is full
+func (h *exactHistory) record(url string, at time.Time, up bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ring, ok := h.records[url]
+	if !ok {
+		ring = &historyRing{records: make([]historyRecord, h.capacity)}
+		h.records[url] = ring
+	}
+	ring.records[ring.next] = historyRecord{at: at.Unix(), up: up}
+	ring.next = (ring.next + 1) % len(ring.records)
+	if ring.next == 0 {
+		ring.full = true
+	}
+}
+
+// uptime returns the percentage of checks in the window that succeeded
+// Only checks still held in the ring are counted
+func (h *exactHistory) uptime(url string, now time.Time) (float64, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ring, ok := h.records[url]
+	if !ok {
+		return 0, false
+	}
+	records := ring.records[:ring.next]
+	if ring.full {
+		records = ring.records
+	}
+
+	cutoff := now.Add(-uptimeWindow).Unix()
+	total, up := 0, 0
+	for _, r := range records {
+		if r.at < cutoff {
+			continue
+		}
+		total++
+		if r.up {
+			up++
+		}
+	}
+	if total == 0 {
+		return 0, false
+	}
+	return float64(up) * 100 / float64(total), true
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// hstsPolicy holds the requirements for the Strict-Transport-Security header
+type hstsPolicy struct {
+	minMaxAge                int64
+	requireIncludeSubDomains bool
+	requirePreload           bool
+}
+
+// checkHSTS validates the Strict-Transport-Security header against policy
+// Returns an error describing the first requirement that is not met
+func checkHSTS(header http.Header, policy hstsPolicy) error {
+	value := header.Get("Strict-Transport-Security")
+	if value == "" {
+		return errors.New("Strict-Transport-Security header is missing")
+	}
+
+	maxAge := int64(-1)
+	includeSubDomains := false
+	preload := false
+	for _, directive := range strings.Split(value, ";") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "max-age":
+			age, err := strconv.ParseInt(strings.Trim(strings.TrimSpace(arg), `"`), 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid max-age %q", arg)
+			}
+			maxAge = age
+		case "includesubdomains":
+			includeSubDomains = true
+		case "preload":
+			preload = true
+		}
+	}
+
+	if maxAge < 0 {
+		return fmt.Errorf("max-age directive is missing from %q", value)
+	}
+	if maxAge < policy.minMaxAge {
+		return fmt.Errorf("max-age %d is below the minimum of %d", maxAge, policy.minMaxAge)
+	}
+	if policy.requireIncludeSubDomains && !includeSubDomains {
+		return errors.New("includeSubDomains directive is missing")
+	}
+	if policy.requirePreload && !preload {
+		return errors.New("preload directive is missing")
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// checkSchedule describes when a URL is checked, for calendar export
+type checkSchedule struct {
+	url      string
+	interval time.Duration
+	start    time.Time
+	timeout  time.Duration
+}
+
+// icalTimeFormat is the UTC date-time format used by iCalendar
+const icalTimeFormat = "20060102T150405Z"
+
+// writeCalendar writes an iCalendar document with one recurring event per schedule
+func writeCalendar(w io.Writer, schedules []checkSchedule) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//websitecheck//check schedule//EN",
+		"CALSCALE:GREGORIAN",
+	}
+
+	now := time.Now().UTC().Format(icalTimeFormat)
+	for _, schedule := range schedules {
+		uid := fmt.Sprintf("%x@websitecheck", sha1.Sum([]byte(schedule.url)))
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+uid,
+			"DTSTAMP:"+now,
+			"DTSTART:"+schedule.start.UTC().Format(icalTimeFormat),
+			fmt.Sprintf("DURATION:PT%dS", int(schedule.timeout.Seconds())),
+			"RRULE:"+icalRecurrence(schedule.interval),
+			"SUMMARY:"+icalEscape("Check "+schedule.url),
+			"DESCRIPTION:"+icalEscape(fmt.Sprintf("websitecheck checks %s every %s", schedule.url, schedule.interval)),
+			"END:VEVENT",
+		)
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, icalFold(line)+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// icalRecurrence returns an RRULE value repeating every interval
+// using the coarsest frequency that divides it exactly
+func icalRecurrence(interval time.Duration) string {
+	seconds := int(interval.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	switch {
+	case seconds%86400 == 0:
+		return fmt.Sprintf("FREQ=DAILY;INTERVAL=%d", seconds/86400)
+	case seconds%3600 == 0:
+		return fmt.Sprintf("FREQ=HOURLY;INTERVAL=%d", seconds/3600)
+	case seconds%60 == 0:
+		return fmt.Sprintf("FREQ=MINUTELY;INTERVAL=%d", seconds/60)
+	default:
+		return fmt.Sprintf("FREQ=SECONDLY;INTERVAL=%d", seconds)
+	}
+}
+
+// icalEscape escapes a TEXT value as required by RFC 5545
+func icalEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// icalFold splits lines longer than 75 octets into continuation lines
+func icalFold(line string) string {
+	const limit = 75
+	if len(line) <= limit {
+		return line
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
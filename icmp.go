@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// icmpSequence numbers echo requests so late replies to earlier pings are ignored
+var icmpSequence atomic.Uint32
+
+// checkICMP pings host, retrying like checkWebsiteDown
+func checkICMP(host string, timeout time.Duration, opts checkOptions) (*checkResult, error) {
+	return retryCheck("ICMP", opts, func() (*checkResult, error) {
+		return ping(host, timeout)
+	})
+}
+
+// ping sends one echo request to host and waits for its reply
+// Unprivileged ICMP sockets are used where the kernel allows them, otherwise raw
+// sockets, which need root or CAP_NET_RAW
+func ping(host string, timeout time.Duration) (*checkResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	ip := ips[0]
+	for _, candidate := range ips {
+		if candidate.To4() != nil {
+			ip = candidate
+			break
+		}
+	}
+
+	network, rawNetwork, protocol := "udp4", "ip4:icmp", 1
+	var request, reply icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if ip.To4() == nil {
+		network, rawNetwork, protocol = "udp6", "ip6:ipv6-icmp", 58
+		request, reply = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+	var dst net.Addr = &net.UDPAddr{IP: ip}
+	conn, err := icmp.ListenPacket(network, "")
+	if err != nil {
+		dst = &net.IPAddr{IP: ip}
+		conn, err = icmp.ListenPacket(rawNetwork, "")
+	}
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return nil, fmt.Errorf("cannot open an ICMP socket (allow unprivileged ping with net.ipv4.ping_group_range or grant CAP_NET_RAW): %v", err)
+		}
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Unprivileged sockets replace the ID with their own, so replies are matched by sequence
+	seq := int(icmpSequence.Add(1) & 0xffff)
+	message := icmp.Message{Type: request, Body: &icmp.Echo{ID: os.Getpid() & 0xffff, Seq: seq, Data: []byte("websitecheck")}}
+	packet, err := message.Marshal(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	conn.SetDeadline(start.Add(timeout))
+	if _, err := conn.WriteTo(packet, dst); err != nil {
+		return nil, fmt.Errorf("sending echo request to %s: %v", ip, err)
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return nil, fmt.Errorf("no echo reply from %s: %w", ip, err)
+		}
+		received, err := icmp.ParseMessage(protocol, buf[:n])
+		if err != nil || received.Type != reply {
+			continue
+		}
+		if echo, ok := received.Body.(*icmp.Echo); ok && echo.Seq == seq {
+			return &checkResult{Latency: time.Since(start)}, nil
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// JitterMode controls how the wait between checks varies
+type JitterMode string
+
+// Supported jitter modes
+const (
+	JitterNone   JitterMode = "none"
+	JitterRandom JitterMode = "random"
+)
+
+// parseJitterMode validates a -jitter-mode value
+func parseJitterMode(s string) (JitterMode, error) {
+	switch mode := JitterMode(s); mode {
+	case JitterNone, JitterRandom:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown jitter mode %q (expected none or random)", s)
+	}
+}
+
+// applyJitter returns the wait before the next check for interval
+// Random mode picks a new wait uniformly between 0.5 and 1.5 times the interval
+// on every call, so checks cannot be predicted and monitors drift out of step
+func applyJitter(interval time.Duration, mode JitterMode) time.Duration {
+	if mode != JitterRandom || interval <= 0 {
+		return interval
+	}
+	return interval/2 + time.Duration(rand.Int63n(int64(interval)+1))
+}
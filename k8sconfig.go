@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// k8sConfigSource reads flag values from a Kubernetes ConfigMap and Secret
+// Each data key is the name of a flag, e.g. "interval" or "retries"
+type k8sConfigSource struct {
+	client    kubernetes.Interface
+	configMap string
+	secret    string
+}
+
+// newK8sConfigSource connects to the Kubernetes API using the in-cluster
+// service account, falling back to ~/.kube/config outside a cluster
+func newK8sConfigSource(configMap, secret string) (*k8sConfigSource, error) {
+	for _, ref := range []string{configMap, secret} {
+		if ref != "" {
+			if _, _, err := splitNamespacedName(ref); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	restConfig, err := rest.InClusterConfig()
+	if err != nil {
+		kubeconfig := os.Getenv("KUBECONFIG")
+		if kubeconfig == "" {
+			home, _ := os.UserHomeDir()
+			kubeconfig = filepath.Join(home, ".kube", "config")
+		}
+		restConfig, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+		if err != nil {
+			return nil, fmt.Errorf("no in-cluster config and cannot load %s: %v", kubeconfig, err)
+		}
+	}
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &k8sConfigSource{client: client, configMap: configMap, secret: secret}, nil
+}
+
+// splitNamespacedName splits a "namespace/name" reference
+func splitNamespacedName(ref string) (string, string, error) {
+	namespace, name, ok := strings.Cut(ref, "/")
+	if !ok || namespace == "" || name == "" {
+		return "", "", fmt.Errorf("invalid reference %q, expected namespace/name", ref)
+	}
+	return namespace, name, nil
+}
+
+// load fetches the ConfigMap and Secret and merges their data
+// Secret values take precedence over ConfigMap values
+func (s *k8sConfigSource) load(timeout time.Duration) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	settings := make(map[string]string)
+	if s.configMap != "" {
+		namespace, name, _ := splitNamespacedName(s.configMap)
+		cm, err := s.client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("reading ConfigMap %s: %v", s.configMap, err)
+		}
+		for key, value := range cm.Data {
+			settings[key] = value
+		}
+	}
+
+	if s.secret != "" {
+		namespace, name, _ := splitNamespacedName(s.secret)
+		secret, err := s.client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("reading Secret %s: %v", s.secret, err)
+		}
+		for key, value := range secret.Data {
+			settings[key] = string(value)
+		}
+	}
+	return settings, nil
+}
+
+// explicitFlags returns the names of the flags set so far
+// Called right after flag.Parse it returns the flags given on the command line
+func explicitFlags() map[string]bool {
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+		// An alias and its flag share a value, so giving either protects both
+		if name, ok := flagAliases[f.Name]; ok {
+			explicit[name] = true
+		}
+		for alias, name := range flagAliases {
+			if name == f.Name {
+				explicit[alias] = true
+			}
+		}
+	})
+	return explicit
+}
+
+// flagAliases maps the alias names some flags can also be given by to the flag
+var flagAliases = map[string]string{
+	"server-log":          "tail-log",
+	"log-window-seconds":  "tail-window",
+	"log-error-threshold": "error-rate-threshold",
+}
+
+// applySettings sets each flag named in settings unless it was given on the
+// command line, which always wins. Returns the names of the flags applied and
+// an error listing any settings that could not be applied.
+func applySettings(settings map[string]string, explicit map[string]bool) ([]string, error) {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var applied []string
+	var errs []error
+	for _, key := range keys {
+		if explicit[key] {
+			continue
+		}
+		if flag.Lookup(key) == nil {
+			errs = append(errs, fmt.Errorf("unknown setting %q", key))
+			continue
+		}
+		if err := flag.Set(key, strings.TrimSpace(settings[key])); err != nil {
+			errs = append(errs, fmt.Errorf("setting %q: %v", key, err))
+			continue
+		}
+		applied = append(applied, key)
+	}
+	return applied, errors.Join(errs...)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// comparePercentiles are the percentiles reported by the compare command
+var comparePercentiles = []float64{50, 95, 99}
+
+// latencyWindow collects the latencies of successful checks of one URL
+type latencyWindow struct {
+	latencies []time.Duration
+	failures  int
+}
+
+// add records the outcome of one check
+func (w *latencyWindow) add(result *checkResult, err error) {
+	if err != nil {
+		w.failures++
+		return
+	}
+	w.latencies = append(w.latencies, result.Latency)
+}
+
+// percentile returns the latency below which p percent of the successful checks completed
+func (w *latencyWindow) percentile(p float64) time.Duration {
+	sorted := append([]time.Duration(nil), w.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return benchmarkReport{latencies: sorted}.percentile(p)
+}
+
+// runCompare checks primary and secondary at the same time every interval until
+// ctx is cancelled. After every window checks it logs the secondary's latency at each
+// percentile as a ratio of the primary's, and warns if the median is more than
+// threshold percent slower
+func runCompare(ctx context.Context, primary, secondary string, client *http.Client, opts checkOptions, interval time.Duration, window int, threshold float64) {
+	var primaryWindow, secondaryWindow latencyWindow
+	for checks := 1; ; checks++ {
+		var wg sync.WaitGroup
+		var primaryResult, secondaryResult *checkResult
+		var primaryErr, secondaryErr error
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			primaryResult, primaryErr = checkWebsiteDown(ctx, primary, client, opts)
+		}()
+		go func() {
+			defer wg.Done()
+			secondaryResult, secondaryErr = checkWebsiteDown(ctx, secondary, client, opts)
+		}()
+		wg.Wait()
+		if ctx.Err() != nil {
+			return
+		}
+		primaryWindow.add(primaryResult, primaryErr)
+		secondaryWindow.add(secondaryResult, secondaryErr)
+
+		if opts.verbose {
+			if primaryErr == nil && secondaryErr == nil {
+				log.Printf("Primary %s, secondary %s", primaryResult.Latency.Round(time.Millisecond), secondaryResult.Latency.Round(time.Millisecond))
+			}
+			if primaryErr != nil {
+				log.Printf("Primary %s check failed: %v", primary, primaryErr)
+			}
+			if secondaryErr != nil {
+				log.Printf("Secondary %s check failed: %v", secondary, secondaryErr)
+			}
+		}
+
+		if checks%window == 0 {
+			reportComparison(&primaryWindow, &secondaryWindow, window, threshold)
+			primaryWindow, secondaryWindow = latencyWindow{}, latencyWindow{}
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reportComparison logs the latency ratios of one window of checks
+func reportComparison(primary, secondary *latencyWindow, window int, threshold float64) {
+	if len(primary.latencies) == 0 || len(secondary.latencies) == 0 {
+		log.Printf("WARNING: No successful checks of both URLs in the last %d checks (%d primary and %d secondary failures)", window, primary.failures, secondary.failures)
+		return
+	}
+
+	var median float64
+	for _, p := range comparePercentiles {
+		primaryLatency, secondaryLatency := primary.percentile(p), secondary.percentile(p)
+		if primaryLatency <= 0 {
+			continue
+		}
+		ratio := float64(secondaryLatency) / float64(primaryLatency)
+		if p == 50 {
+			median = ratio
+		}
+		log.Printf("p%g over %d checks: primary %s, secondary %s, ratio %.2f (%+.1f%%)", p, window,
+			primaryLatency.Round(time.Millisecond), secondaryLatency.Round(time.Millisecond), ratio, (ratio-1)*100)
+	}
+	if primary.failures > 0 || secondary.failures > 0 {
+		log.Printf("Failed checks over %d checks: primary %d, secondary %d", window, primary.failures, secondary.failures)
+	}
+	if median > 1+threshold/100 {
+		log.Printf("WARNING: Secondary is %.1f%% slower than primary at the median, more than the %.1f%% -compare-threshold", (median-1)*100, threshold)
+	}
+}
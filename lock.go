@@ -0,0 +1,44 @@
+package main
+
+import "context"
+
+// cycleLock is a channel-based mutex that serialises check cycles
+// A disabled lock never blocks, which lets cycles overlap
+type cycleLock struct {
+	sem chan struct{}
+}
+
+// newCycleLock creates a cycle lock, or a no-op lock if enabled is false
+func newCycleLock(enabled bool) *cycleLock {
+	if !enabled {
+		return &cycleLock{}
+	}
+	return &cycleLock{sem: make(chan struct{}, 1)}
+}
+
+// Lock waits until no other cycle is running
+func (l *cycleLock) Lock() {
+	if l.sem != nil {
+		l.sem <- struct{}{}
+	}
+}
+
+// lockContext is Lock that gives up with ctx's error once ctx is done
+func (l *cycleLock) lockContext(ctx context.Context) error {
+	if l.sem == nil {
+		return nil
+	}
+	select {
+	case l.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Unlock releases the lock
+func (l *cycleLock) Unlock() {
+	if l.sem != nil {
+		<-l.sem
+	}
+}
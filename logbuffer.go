@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// logClientBuffer is how many lines a GET /logs client may fall behind by before it is disconnected
+const logClientBuffer = 100
+
+// defaultLogTailLines is how many kept lines GET /logs sends without ?lines
+const defaultLogTailLines = 50
+
+// logBuffer keeps the most recent log lines for GET /logs and passes new ones to its clients
+// It is written to through the log package, so it must never block
+type logBuffer struct {
+	mu      sync.Mutex
+	lines   []string
+	size    int
+	next    int
+	clients map[chan string]struct{}
+}
+
+// newLogBuffer creates a buffer keeping the last size lines
+func newLogBuffer(size int) *logBuffer {
+	return &logBuffer{lines: make([]string, 0, size), size: size, clients: make(map[chan string]struct{})}
+}
+
+// Write implements io.Writer for one or more complete log lines
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if len(b.lines) < b.size {
+			b.lines = append(b.lines, line)
+		} else {
+			b.lines[b.next] = line
+			b.next = (b.next + 1) % b.size
+		}
+		for client := range b.clients {
+			select {
+			case client <- line:
+			default:
+				delete(b.clients, client)
+				close(client)
+			}
+		}
+	}
+	return len(p), nil
+}
+
+// subscribe returns the last n kept lines and a channel receiving every later line
+func (b *logBuffer) subscribe(n int) ([]string, chan string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ordered := append(append([]string{}, b.lines[b.next:]...), b.lines[:b.next]...)
+	if n < len(ordered) {
+		ordered = ordered[len(ordered)-n:]
+	}
+	client := make(chan string, logClientBuffer)
+	b.clients[client] = struct{}{}
+	return ordered, client
+}
+
+// unsubscribe removes a client unless it was already dropped
+func (b *logBuffer) unsubscribe(client chan string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.clients[client]; ok {
+		delete(b.clients, client)
+		close(client)
+	}
+}
+
+// handleLogs sends the last ?lines log lines as chunked plain text, then each new line
+// until the client disconnects. token must be given as a bearer token
+func (b *logBuffer) handleLogs(token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireBearerToken(w, r, token, "GET /logs") {
+			return
+		}
+		n := defaultLogTailLines
+		if param := r.URL.Query().Get("lines"); param != "" {
+			parsed, err := strconv.Atoi(param)
+			if err != nil || parsed < 0 {
+				http.Error(w, "lines must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			n = parsed
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		recent, client := b.subscribe(n)
+		defer b.unsubscribe(client)
+		for _, line := range recent {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		for {
+			select {
+			case line, ok := <-client:
+				if !ok {
+					fmt.Fprintf(w, "-- disconnected: fell %d lines behind --\n", logClientBuffer)
+					return
+				}
+				if _, err := fmt.Fprintln(w, line); err != nil {
+					return
+				}
+				flusher.Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
@@ -1,13 +1,32 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
 )
 
 func main() {
@@ -21,134 +40,2151 @@ func main() {
 	maxBackoffFlag := flag.Int("max-backoff", 3600, "Maximum backoff time in seconds")
 	initialBackoffFlag := flag.Int("initial-backoff", 60, "Initial backoff time in seconds")
 	backoffFactorFlag := flag.Float64("backoff-factor", 2.0, "Backoff multiplication factor")
+	evalModeFlag := flag.String("eval-mode", "consecutive", "When failures count as down: consecutive for every failed check, window for at least -eval-failures of the last -eval-window checks")
+	evalFailuresFlag := flag.Int("eval-failures", 3, "Failed checks within -eval-window that count as down in window mode")
+	evalWindowFlag := flag.Int("eval-window", 5, "Number of recent checks evaluated in window mode")
+	jitterModeFlag := flag.String("jitter-mode", "none", "Wait between checks: none for exactly -interval, random for a new random wait between 0.5 and 1.5 times -interval each time")
+	backoffModeFlag := flag.String("backoff-mode", "exponential", "Backoff progression: exponential, linear or constant")
+	backoffIncrementFlag := flag.Int("backoff-increment", 60, "Seconds added to the backoff on each failure in linear mode")
+	backoffResetAfterFlag := flag.Int("backoff-reset-after", 0, "Only reset the backoff after the site has been continuously up for this many seconds (0 = immediately)")
+	allowOverlapFlag := flag.Bool("allow-overlap", false, "Let a new check cycle start while the previous cycle's ELF binary is still running")
+	elfAsyncFlag := flag.Bool("elf-async", false, "Run the ELF binary in the background so a slow binary does not delay the next check")
+	maxConcurrentELFFlag := flag.Int("max-concurrent-elf", 3, "Maximum number of ELF binaries running in the background with -elf-async or -allow-overlap")
+	elfRetriesFlag := flag.Int("elf-retries", 0, "Number of times to retry the ELF binary when it exits non-zero; exit code 1 is never retried and 75 at least 3 times")
+	maxELFExecutionsFlag := flag.Int("max-elf-executions", 0, "Maximum ELF executions per outage before suspending until the site recovers (0 = unlimited)")
+	apiAddrFlag := flag.String("api-addr", "", "Address to serve the HTTP API on, e.g. :8080 (disabled if empty)")
+	pprofFlag := flag.Bool("pprof", false, "Serve the net/http/pprof profiling endpoints on -pprof-addr")
+	lockFileFlag := flag.String("lock-file", "", "PID lock file that stops a second instance from starting; removed on a clean exit")
+	pprofAddrFlag := flag.String("pprof-addr", "", "Address for the -pprof endpoints, kept apart from -api-addr, e.g. 127.0.0.1:6060")
+	dashboardAddrFlag := flag.String("dashboard-addr", "", "Address to serve a live web dashboard of the check results on, e.g. :8081 (disabled if empty)")
+	apiTokenFlag := flag.String("api-token", "", "Bearer token required by GET /logs, GET /config, the serve API and every API endpoint that changes state")
+	triggerTimeoutFlag := flag.Int("trigger-timeout", 30, "Seconds POST /checks/{url}/trigger waits for the check result")
+	logBufferLinesFlag := flag.Int("log-buffer-lines", 1000, "Number of recent log lines kept for GET /logs")
+	apiCORSOriginsFlag := flag.String("api-cors-origins", "", "Comma-separated origins allowed to call the API from a browser, or * for any")
+	matrixPeersFlag := flag.String("matrix-peers", "", "Comma-separated API addresses of peer instances whose /status results are combined into a reachability matrix")
+	failOnHTTPDowngradeFlag := flag.Bool("fail-on-http-downgrade", false, "Treat a redirect from HTTPS to HTTP as the site being down")
+	blocklistFileFlag := flag.String("blocklist-file", "", "File of IPs/CIDRs (one per line) the target must not resolve to, reloaded on SIGHUP")
+	failOnBlocklistFlag := flag.Bool("fail-on-blocklist", false, "Treat resolving to a blocklisted IP as the site being down")
+	checkHSTSFlag := flag.Bool("check-hsts", false, "Verify the Strict-Transport-Security header on HTTPS responses")
+	minHSTSMaxAgeFlag := flag.Int64("min-hsts-max-age", 31536000, "Minimum acceptable HSTS max-age in seconds")
+	failOnMissingHSTSFlag := flag.Bool("fail-on-missing-hsts", false, "Treat a missing or weak HSTS header as the site being down")
+	requireHSTSIncludeSubdomainsFlag := flag.Bool("require-hsts-include-subdomains", false, "Require the includeSubDomains HSTS directive")
+	requireHSTSPreloadFlag := flag.Bool("require-hsts-preload", false, "Require the preload HSTS directive")
+	checkMixedContentFlag := flag.Bool("check-mixed-content", false, "Warn about http:// src, href and url() references in HTTPS pages")
+	failOnMixedContentFlag := flag.Bool("fail-on-mixed-content", false, "Treat mixed content found by -check-mixed-content as the site being down")
+	checkCSPFlag := flag.Bool("check-csp", false, "Verify the Content-Security-Policy header is present and non-empty")
+	minCSPDirectivesFlag := flag.Int("min-csp-directives", 0, "Minimum number of directives the Content-Security-Policy must contain")
+	expectCSPFlag := flag.String("expect-csp", "", "Exact Content-Security-Policy value the response must have")
+	failOnCSPFlag := flag.Bool("fail-on-csp", false, "Treat a missing or invalid Content-Security-Policy header as the site being down")
+	securityScoreFlag := flag.Bool("security-score", false, "Log a 0-100 score for the security headers after each successful check")
+	minSecurityScoreFlag := flag.Int("min-security-score", 0, "Raise a degraded event when the security header score drops below this")
+	expectPushFlag := flag.String("expect-push", "", "Comma-separated paths the server is expected to send HTTP/2 push promises for")
+	mirrorURLsFlag := flag.String("mirror-urls", "", "Comma-separated mirror URLs, tried when the primary is down, weighted with :weight after an explicit port, e.g. mirror.example.com:443:3")
+	tracerouteOnFailureFlag := flag.Bool("traceroute-on-failure", false, "Run a traceroute to the target when the site is down and include it in the event")
+	monitorDNSChangesFlag := flag.Bool("monitor-dns-changes", false, "Resolve the hostname before each check and warn when its addresses change")
+	dnsChangeELFFlag := flag.String("dns-change-elf", "", "Path to binary to execute when the resolved addresses change")
+	minTLSVersionFlag := flag.String("min-tls-version", "", "Minimum TLS version to accept: 1.0, 1.1, 1.2 or 1.3 (default Go's minimum)")
+	methodFlag := flag.String("method", http.MethodGet, "HTTP method of the check request, e.g. POST for health checks that need a -request-body")
+	requestBodyFlag := flag.String("request-body", "", "Body sent with the check request")
+	requestBodyFileFlag := flag.String("request-body-file", "", "File whose contents, which may be binary, are sent as the check request body")
+	requestBodyContentTypeFlag := flag.String("request-body-content-type", "", "Content-Type of the request body (default application/octet-stream)")
+	decompressFlag := flag.Bool("decompress", false, "Request gzip or brotli responses and decompress the body before checking its content")
+	requireCipherFlag := flag.String("require-cipher", "", "Comma-separated Go TLS cipher suite names, one of which must be negotiated (e.g. TLS_AES_256_GCM_SHA384,TLS_CHACHA20_POLY1305_SHA256)")
+	certPinFlag := flag.String("cert-pin", "", "Base64-encoded SHA-256 digest of the expected leaf certificate's SubjectPublicKeyInfo")
+	compareURLFlag := flag.String("compare-url", "", "Alternate host (e.g. https://canary.example.com) to fetch the same path from and compare against")
+	canaryURLFlag := flag.String("canary-url", "", "Canary deployment URL whose status, latency and body must match the primary")
+	canaryMaxSlowdownFlag := flag.Float64("canary-max-slowdown", 20, "Percent by which the canary may respond slower than the primary")
+	canaryBodyRegexFlag := flag.String("canary-body-regex", "", "Regular expression the canary body must match instead of equalling the primary body")
+	canaryELFFlag := flag.String("canary-elf", "", "ELF binary to run on a canary mismatch, e.g. to roll back (default -elf)")
+	compareBodyFlag := flag.Bool("compare-body", false, "Also compare response bodies when using -compare-url")
+	k8sConfigMapFlag := flag.String("k8s-config-configmap", "", "Kubernetes ConfigMap (namespace/name) whose keys set flag values, re-read on SIGHUP")
+	k8sSecretFlag := flag.String("k8s-secret", "", "Kubernetes Secret (namespace/name) whose keys set sensitive flag values, re-read on SIGHUP")
+	vaultAddrFlag := flag.String("vault-addr", "", "Vault server address to fetch HTTP credentials from")
+	vaultSecretPathFlag := flag.String("vault-secret-path", "", "Vault secret path holding username/password or token fields, e.g. secret/data/website")
+	vaultTokenFileFlag := flag.String("vault-token-file", "", "File containing the Vault token if VAULT_TOKEN is not set (default ~/.vault-token)")
+	vaultRenewBeforeFlag := flag.Int("vault-renew-before", 60, "Refresh Vault credentials this many seconds before they expire")
+	awsSecretARNFlag := flag.String("aws-secret-arn", "", "AWS Secrets Manager secret ARN holding username, password and/or bearer_token JSON fields")
+	ebpfLatencyFlag := flag.Bool("ebpf-latency", false, "Also time responses in the kernel with an eBPF socket filter and log how much the Go runtime adds (Linux 5.x+, needs CAP_BPF)")
+	bustCacheFlag := flag.Bool("bust-cache", false, "Add a unique query parameter and no-cache headers to bypass CDN caches")
+	watchHeadersFlag := flag.String("watch-headers", "", "Comma-separated response headers (e.g. X-Served-By) whose changes between checks are logged")
+	failOnHeaderChangeFlag := flag.Bool("fail-on-header-change", false, "Treat a change in a -watch-headers header as a failure")
+	minCacheHitRateFlag := flag.Float64("min-cache-hit-rate", 0, "Warn when the percentage of the last -cache-hit-window checks served from cache drops below this (0 disables)")
+	cacheHitWindowFlag := flag.Int("cache-hit-window", 20, "Number of recent checks over which the cache hit rate is computed")
+	verifyCacheHitFlag := flag.Bool("verify-cache-hit", false, "Warn when the response is not served from cache (X-Cache: HIT)")
+	modeFlag := flag.String("mode", "http", "Check mode: http, graphql, tcp, compound, icmp, grpc, websocket or dns (tcp://, icmp://, grpc://, ws://, wss:// and dns:// URLs select their mode)")
+	gqlQueryFlag := flag.String("gql-query", "", "GraphQL query to send in graphql mode")
+	gqlQueryFileFlag := flag.String("gql-query-file", "", "File containing the GraphQL query to send in graphql mode")
+	gqlExpectFieldFlag := flag.String("gql-expect-field", "", "JSONPath (e.g. $.health.status) that must resolve to a non-null value in the GraphQL data")
+	compoundChecksFlag := flag.String("compound-checks", "", "Sub-checks that must all pass in compound mode, e.g. 'tcp:443,tls,http:/health,body:\"status\":\"ok\"'")
+	tcpSendFlag := flag.String("tcp-send", "", "Data to send after connecting in tcp mode (\\r and \\n escapes are expanded)")
+	tcpExpectFlag := flag.String("tcp-expect", "", "String that must be received within -timeout in tcp mode, e.g. 220 for SMTP")
+	tailLogFlag := flag.String("tail-log", "", "Watch this access log for 5xx rates, in place of requests without -url or alongside the checks of -url")
+	errorRateThresholdFlag := flag.Float64("error-rate-threshold", 5, "Percentage of 5xx responses in -tail-log that counts as down, or with -url runs the ELF binary")
+	failureRateAlertFlag := flag.String("failure-rate-alert", "", "Run the ELF binary when failures exceed this rate over the last -failure-rate-window checks, e.g. 0.5/min")
+	failureRateWindowFlag := flag.Int("failure-rate-window", 20, "Number of checks over which the -failure-rate-alert rate is computed")
+	sizeAlertStddevFlag := flag.Float64("size-alert-stddev", 0, "Run the ELF binary when the response size is this many standard deviations from its mean (0 disables)")
+	sizeWarmupChecksFlag := flag.Int("size-warmup-checks", 10, "Number of checks used to learn the response size before -size-alert-stddev applies")
+	sizeRebaselineChecksFlag := flag.Int("size-rebaseline-checks", 10, "Take the response size as the new baseline after this many size anomalies in a row (0 never re-baselines)")
+	tailWindowFlag := flag.Int("tail-window", 300, "Window in seconds over which the -tail-log error rate is computed")
+	flag.StringVar(tailLogFlag, "server-log", "", "Alias of -tail-log")
+	flag.IntVar(tailWindowFlag, "log-window-seconds", 300, "Alias of -tail-window")
+	flag.Float64Var(errorRateThresholdFlag, "log-error-threshold", 5, "Alias of -error-rate-threshold")
+	logFormatFlag := flag.String("log-format", "combined", "Access log format for -tail-log: common or combined")
+	logPatternFlag := flag.String("log-pattern", "", "Custom grok-style pattern for -tail-log lines, e.g. '%{IP:client} .* %{NUMBER:status}'")
+	jsonSchemaFlag := flag.String("json-schema", "", "JSON Schema file the response body must validate against")
+	openAPISpecFlag := flag.String("openapi-spec", "", "OpenAPI 3 spec (JSON or YAML) whose x-monitor operations are also checked, reloaded when the file changes")
+	openAPIAllPathsFlag := flag.Bool("openapi-all-paths", false, "Check the GET operation of every path in -openapi-spec, not just those marked x-monitor")
+	pathParamsFlag := flag.String("path-params", "", "JSON file of path parameter values substituted into -openapi-spec paths")
+	openAPIWorkersFlag := flag.Int("openapi-workers", 4, "Number of OpenAPI endpoints checked concurrently")
+	discoverFromRobotsFlag := flag.Bool("discover-from-robots", false, "Also check pages listed in the sitemaps named by the URL's robots.txt, found at startup")
+	maxDiscoveredURLsFlag := flag.Int("max-discovered-urls", 10, "Maximum number of sitemap pages checked with -discover-from-robots")
+	retryOnTimeoutFlag := flag.Bool("retry-on-timeout", true, "Retry requests that time out")
+	retryOnConnectionRefusedFlag := flag.Bool("retry-on-connection-refused", false, "Retry requests whose connection is refused")
+	retryOn5xxFlag := flag.Bool("retry-on-5xx", true, "Retry requests that return a 5xx status")
+	retryOn4xxFlag := flag.Bool("retry-on-4xx", false, "Retry requests that return a 4xx status")
+	grpcCheckPluginFlag := flag.String("grpc-check-plugin", "", "Address of a gRPC CheckService (see checkplugin.proto) whose result replaces the built-in check")
+	grpcCheckOptionsFlag := flag.String("grpc-check-options", "", "Comma-separated key=value options passed to the check plugin")
+	dnsRefreshFlag := flag.Int("dns-refresh", 0, "Resolve the hostname in the background every N seconds and connect to the cached addresses (0 disables)")
+	exactHistoryFlag := flag.Bool("exact-history", false, "Keep every check outcome for uptime reporting instead of an hourly bloom filter")
+	historyMemoryFlag := flag.Int("history-memory", 16, "Memory budget in MB for -exact-history; the oldest checks are dropped beyond it")
+	onceFlag := flag.Bool("once", false, "Run a single check and exit with status 1 if the website is down, e.g. from cron")
+	pushgatewayAddrFlag := flag.String("pushgateway-addr", "", "Prometheus Pushgateway address to push metrics to after each check")
+	selfCheckIntervalFlag := flag.Int("self-check-interval", 300, "Seconds between logging the goroutine count and heap usage of websitecheck itself (0 disables)")
+	maxGoroutinesFlag := flag.Int("max-goroutines", 1000, "Warn when websitecheck runs more goroutines than this at a self check (0 disables)")
+	maxHeapMBFlag := flag.Int("max-heap-mb", 256, "Warn when websitecheck has more than this many MB of heap allocated at a self check (0 disables)")
+	checkUpdatesFlag := flag.Bool("check-updates", false, "Check GitHub once a day for a newer websitecheck release and log a notice")
+	heartbeatIntervalFlag := flag.Int("heartbeat-interval", 0, "Log a heartbeat with the number of checks and down events every N seconds (0 disables)")
+	heartbeatFileFlag := flag.String("heartbeat-file", "", "File updated with the current time on each heartbeat that follows a completed check, so a stalled check loop can be detected from its age")
+	watchdogTimeoutFlag := flag.Int("watchdog-timeout", 0, "Exit if a check cycle runs longer than this many seconds (0 means twice -interval, -1 disables)")
+	adaptiveIntervalFlag := flag.Bool("adaptive-interval", false, "Double the interval when responses approach the timeout and halve it when they are fast")
+	minIntervalFlag := flag.Int("min-interval", 10, "Shortest interval in seconds used by -adaptive-interval")
+	cloudWatchNamespaceFlag := flag.String("cloudwatch-namespace", "", "Publish check results to CloudWatch Metrics in this namespace")
+	githubActionsFlag := flag.Bool("github-actions", false, "Write failures as GitHub Actions error annotations when running in GitHub Actions")
+	sentryDSNFlag := flag.String("sentry-dsn", "", "Sentry DSN to report check failures and ELF errors to")
+	iftttKeyFlag := flag.String("ifttt-key", "", "IFTTT Webhooks key to trigger -ifttt-event with when the website goes down")
+	iftttEventFlag := flag.String("ifttt-event", "", "IFTTT Webhooks event name, triggered with value1=URL, value2=failure reason, value3=consecutive failures")
+	telegramTokenFlag := flag.String("telegram-token", "", "Telegram bot token to send down and recovery messages with")
+	telegramChatIDFlag := flag.String("telegram-chat-id", "", "Telegram chat the -telegram-token bot sends messages to")
+	discordWebhookFlag := flag.String("discord-webhook", "", "Discord webhook URL to post down and recovery embeds to")
+	teamsWebhookFlag := flag.String("teams-webhook", "", "Microsoft Teams incoming webhook URL to post down and recovery cards to")
+	statusPageURLFlag := flag.String("status-page-url", "", "Status page linked from the View Status button of -teams-webhook cards")
+	mqttBrokerFlag := flag.String("mqtt-broker", "", "MQTT broker to publish status changes to, e.g. tcp://broker:1883")
+	mqttTopicFlag := flag.String("mqtt-topic", "websitecheck/%host%/status", "MQTT topic for status changes; %host%, %port% and %path% are taken from -url")
+	mqttClientIDFlag := flag.String("mqtt-client-id", "", "MQTT client ID (default websitecheck-<hostname>-<pid>)")
+	mqttQoSFlag := flag.Int("mqtt-qos", 0, "MQTT QoS level for status messages: 0, 1 or 2")
+	redisAddrFlag := flag.String("redis-addr", "", "Redis server (host:port or redis:// URL) to publish down and recovery events to")
+	redisChannelFlag := flag.String("redis-channel", "websitecheck", "Redis Pub/Sub channel for -redis-addr events")
+	redisBufferSizeFlag := flag.Int("redis-buffer-size", 100, "Maximum number of events kept in memory while Redis is unreachable")
+	kafkaBrokersFlag := flag.String("kafka-brokers", "", "Comma-separated Kafka brokers to publish down and recovery events to")
+	kafkaTopicFlag := flag.String("kafka-topic", "websitecheck-events", "Kafka topic for -kafka-brokers events, keyed by URL")
+	kafkaPartitionCountFlag := flag.Int("kafka-partition-count", 0, "Create -kafka-topic with this many partitions (replication factor 1) if it does not exist (0 leaves it to the brokers)")
+	tagsFlag := flag.String("tags", "", "Comma-separated tags describing this check, used in -nats-subject")
+	natsURLFlag := flag.String("nats-url", "", "NATS server to publish down and recovery events to, e.g. nats://localhost:4222")
+	natsSubjectFlag := flag.String("nats-subject", "websitecheck.{tag}.{status}", "NATS subject for events; {tag}, {status} (up or down) and {host} are filled in")
+	natsJetStreamFlag := flag.Bool("nats-jetstream", false, "Publish events through JetStream to a stream with a durable consumer so each is delivered once")
+	alertmanagerAddrFlag := flag.String("alertmanager-addr", "", "Alertmanager base URL, e.g. http://localhost:9093, in which to silence the URL's alerts while the ELF binary handles an outage")
+	alertmanagerSilenceDurationFlag := flag.Int("alertmanager-silence-duration", 3600, "Seconds the Alertmanager silence lasts unless the site recovers first")
+	alertmanagerLabelFlag := flag.String("alertmanager-label", "instance", "Alert label the Alertmanager silence matches against the URL")
+	alertmanagerStateFlag := flag.String("alertmanager-state", "", "File recording the ID of the open Alertmanager silence (default websitecheck-alertmanager-<URL hash>.state)")
+	snmpTrapAddrFlag := flag.String("snmp-trap-addr", "", "Send SNMP v2c traps to this host[:port] when the website goes down and recovers")
+	snmpTrapOIDFlag := flag.String("snmp-trap-oid", "1.3.6.1.4.1.8072.9999.1", "OID of the traps sent with -snmp-trap-addr")
+	snmpCommunityFlag := flag.String("snmp-community", "public", "SNMP community for -snmp-trap-addr")
+	certCriticalDaysFlag := flag.Int("cert-critical-days", 0, "Raise a cert-expiring event when the TLS certificate expires within this many days (0 disables)")
+	certRenewELFFlag := flag.String("cert-renew-elf", "", "Run this ELF binary instead of -elf when the certificate is within -cert-critical-days of expiry, at most once a day")
+	certRenewWaitFlag := flag.Int("cert-renew-wait", 60, "Seconds to wait after -cert-renew-elf before re-checking the certificate")
+	certRenewStateFlag := flag.String("cert-renew-state", "", "File recording when -cert-renew-elf last ran (default websitecheck-cert-renew-<URL hash>.state)")
+	benchmarkRequestsFlag := flag.Int("benchmark-requests", 100, "Number of requests sent by the benchmark command")
+	benchmarkConcurrencyFlag := flag.Int("benchmark-concurrency", 1, "Number of requests the benchmark command keeps in flight")
+	secondaryURLFlag := flag.String("secondary-url", "", "URL the compare command checks alongside -url")
+	compareWindowFlag := flag.Int("compare-window", 20, "Number of checks the compare command accumulates for each latency report")
+	compareThresholdFlag := flag.Float64("compare-threshold", 20, "Percent slower than -url at the median that makes the compare command warn about -secondary-url")
+	completionFlag := flag.String("completion", "", "Print a shell completion script for bash, zsh or fish and exit")
+	healingActionsFlag := flag.String("healing-actions", "", "YAML file of healing actions run for matching failure reasons, each with a precondition and cooldown")
+	tenantsFileFlag := flag.String("tenants-file", "", "YAML file of api_keys per tenant; serve then requires X-Tenant-ID and X-API-Key headers and shows each tenant only its own checks")
+	configFlag := flag.String("config", "", "YAML or JSON file of flag names to values, overriding the built-in defaults; flags on the command line override it")
+	timezoneFlag := flag.String("timezone", "UTC", "Time zone for log and status page timestamps, e.g. Europe/Berlin; stored times stay in UTC")
+	printDefaultsFlag := flag.Bool("print-defaults", false, "Print the built-in defaults in -config format and exit")
+	configDirFlag := flag.String("config-dir", "", "Directory of *.yaml or *.json files, each defining one check by flag name, all monitored at once and reloaded on SIGHUP")
+	watchConfigDirFlag := flag.Bool("watch-config-dir", false, "Reload -config-dir whenever a file in it changes, not only on SIGHUP")
+	testModeFlag := flag.Bool("test-mode", false, "Monitor a built-in mock server instead of -url; toggle it with POST "+mockControlPath+"up or down")
+	testStatusCodeFlag := flag.Int("test-status-code", 200, "Status code returned by the -test-mode server")
+	testBodyFlag := flag.String("test-body", "OK", "Response body returned by the -test-mode server")
+	testLatencyFlag := flag.Int("test-latency-ms", 0, "Delay in milliseconds before the -test-mode server responds")
+	validateELFFlag := flag.Bool("validate-elf", false, "Run the ELF binary with --validate at startup and warn unless it exits with code 0")
+	validateConfigFlag := flag.Bool("validate-config", false, "Validate the configuration and exit with status 0 if it is valid or 1 if not")
+	bindAddressesFlag := flag.String("bind-addresses", "", "Comma-separated local IPs to check from in parallel; down only if unreachable from all")
+	
+	// Subcommands set the same flags as the equivalent options
+	command := parseCommandLine(os.Args)
+	switch command {
+	case commandValidate:
+		*validateConfigFlag = true
+	case commandOnce:
+		*onceFlag = true
+	}
+	
+	if *completionFlag != "" {
+		if err := writeCompletion(os.Stdout, *completionFlag, filepath.Base(os.Args[0])); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+	
+	if *printDefaultsFlag {
+		printDefaults()
+		return
+	}
+	
+	// Layer the built-in defaults and the config file under the command line
+	// Flags set by them or by Kubernetes must not count as given on the command line when reloading
+	commandLine := explicitFlags()
+	applyConfigLayers(*configFlag, commandLine)
+	
+	// Fill in flags not given on the command line from Kubernetes
+	var k8sSettings chan map[string]string
+	if *k8sConfigMapFlag != "" || *k8sSecretFlag != "" {
+		k8sSettings = loadK8sSettings(*k8sConfigMapFlag, *k8sSecretFlag, time.Duration(*timeoutFlag)*time.Second, commandLine)
+	}
+	
+	// Stamp logs in the configured time zone, keeping recent lines for GET /logs
+	var logOutput io.Writer = os.Stderr
+	var logs *logBuffer
+	if *apiAddrFlag != "" {
+		if *logBufferLinesFlag < 1 {
+			log.Fatal("Error: -log-buffer-lines must be positive")
+		}
+		logs = newLogBuffer(*logBufferLinesFlag)
+		logOutput = io.MultiWriter(os.Stderr, logs)
+	}
+	if err := setTimezone(*timezoneFlag, logOutput); err != nil {
+		log.Fatalf("Error: Invalid -timezone: %v", err)
+	}
+	
+	// Report every configuration problem at once and exit, like nginx -t
+	if *validateConfigFlag {
+		errs := validateConfig()
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		if len(errs) > 0 {
+			os.Exit(1)
+		}
+		fmt.Println("Configuration is valid")
+		return
+	}
+	
+	// Profile the process, including a supervisor of child monitors
+	if *pprofFlag {
+		if *pprofAddrFlag == "" {
+			log.Fatal("Error: -pprof requires -pprof-addr")
+		}
+		if *pprofAddrFlag == *apiAddrFlag {
+			log.Fatal("Error: -pprof-addr must differ from -api-addr")
+		}
+		startPprof(*pprofAddrFlag)
+	} else if *pprofAddrFlag != "" {
+		log.Fatal("Error: -pprof-addr requires -pprof")
+	}
+	
+	// Refuse to start while another instance holds the lock file
+	var lock *pidLock
+	if *lockFileFlag != "" {
+		var err error
+		if lock, err = acquirePIDLock(*lockFileFlag); err != nil {
+			log.Printf("Error: %v", err)
+			var held *lockHeldError
+			if errors.As(err, &held) {
+				os.Exit(exitLockHeld)
+			}
+			os.Exit(1)
+		}
+	}
+	defer lock.release()
 	
-	flag.Parse()
+	// Run a child monitor per check file or per check added through the API;
+	// flags given here are shared by every check
+	if command == commandServe {
+		runServe(*apiAddrFlag, *configDirFlag, *tenantsFileFlag, *apiTokenFlag, sharedArgs(commandLine))
+		return
+	}
+	if *configDirFlag != "" {
+		runCheckDir(*configDirFlag, *watchConfigDirFlag, sharedArgs(commandLine))
+		return
+	}
 	
 	// Validate required flags
+	// Without a URL the access log is watched in place of active checks, and
+	// identifies the site
+	passiveLog := *urlFlag == "" && *tailLogFlag != ""
+	if passiveLog {
+		if path, err := filepath.Abs(*tailLogFlag); err == nil {
+			*urlFlag = "file://" + path
+		}
+	}
+	// Test mode monitors a local mock server so no real site is contacted
+	if *testModeFlag {
+		if *testStatusCodeFlag < 100 || *testStatusCodeFlag > 599 {
+			log.Fatalf("Error: -test-status-code must be between 100 and 599, got %d", *testStatusCodeFlag)
+		}
+		mock := newMockServer(*testStatusCodeFlag, *testBodyFlag, time.Duration(*testLatencyFlag)*time.Millisecond)
+		defer mock.Close()
+		*urlFlag = mock.URL
+		log.Printf("Test mode: monitoring mock server %s (toggle with POST %s%sup or down)", mock.URL, mock.URL, mockControlPath)
+	}
 	if *urlFlag == "" {
 		log.Fatal("Error: URL is required. Use -url flag.")
 	}
 	
-	if *elfPathFlag == "" {
-		log.Fatal("Error: ELF binary path is required. Use -elf flag.")
+	if *bustCacheFlag && *verifyCacheHitFlag {
+		log.Fatal("Error: -bust-cache and -verify-cache-hit are mutually exclusive")
+	}
+	if *bustCacheFlag && *minCacheHitRateFlag > 0 {
+		log.Fatal("Error: -bust-cache and -min-cache-hit-rate are mutually exclusive")
 	}
 	
-	// Validate that the ELF file exists and is executable
-	elfInfo, err := os.Stat(*elfPathFlag)
+	mode, err := parseMode(*modeFlag)
 	if err != nil {
-		log.Fatalf("Error: Cannot access ELF binary %s: %v", *elfPathFlag, err)
+		log.Fatalf("Error: %v", err)
 	}
 	
-	// Check if file is executable
-	if elfInfo.Mode().Perm()&0111 == 0 {
-		log.Fatalf("Error: ELF binary %s is not executable", *elfPathFlag)
+	backoffMode, err := parseBackoffMode(*backoffModeFlag)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
 	}
 	
-	log.Printf("Starting website monitor for %s", *urlFlag)
-	log.Printf("Will execute %s when website is down", *elfPathFlag)
-	log.Printf("Checking every %d seconds", *intervalFlag)
-	log.Printf("Using backoff: initial=%ds, factor=%.1f, max=%ds", *initialBackoffFlag, *backoffFactorFlag, *maxBackoffFlag)
+	jitterMode, err := parseJitterMode(*jitterModeFlag)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
 	
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: time.Duration(*timeoutFlag) * time.Second,
+	evalMode, err := parseEvalMode(*evalModeFlag)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	var evaluation *evalWindow
+	if evalMode == EvalWindow {
+		if *evalFailuresFlag < 1 || *evalFailuresFlag > *evalWindowFlag {
+			log.Fatalf("Error: -eval-failures must be between 1 and -eval-window (%d)", *evalWindowFlag)
+		}
+		evaluation = newEvalWindow(*evalFailuresFlag, *evalWindowFlag)
+		log.Printf("Counting the site as down while %d of the last %d checks failed", *evalFailuresFlag, *evalWindowFlag)
 	}
 	
-	// Initialize backoff state
-	consecutiveFailures := 0
-	currentBackoff := *initialBackoffFlag
+	// Use the canonical form of the URL everywhere it appears
+	canonicalURL, err := normalizeURL(*urlFlag)
+	if err != nil {
+		log.Fatalf("Error: Invalid URL %s: %v", *urlFlag, err)
+	}
+	if canonicalURL != *urlFlag {
+		log.Printf("Using canonical URL %s for %s", canonicalURL, *urlFlag)
+		*urlFlag = canonicalURL
+	}
 	
-	// Main monitoring loop
-	for {
-		siteDown := checkWebsiteDown(*urlFlag, client, *retriesFlag, *verboseFlag)
+	targetURL, err := url.Parse(*urlFlag)
+	if err != nil {
+		log.Fatalf("Error: Invalid URL %s: %v", *urlFlag, err)
+	}
+	
+	// The URL scheme selects the check mode, so tcp://, icmp://, grpc://, ws://, wss://
+	// and dns:// URLs need no -mode. checkTarget is what that mode connects to
+	checkTarget := *urlFlag
+	if !passiveLog && *grpcCheckPluginFlag == "" {
+		resolved, target, err := resolveMode(mode, *urlFlag)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if resolved != mode {
+			log.Printf("Using %s mode for %s", resolved, *urlFlag)
+		}
+		mode, checkTarget = resolved, target
+	}
+	
+	// TCP mode checks a tcp://host:port URL with an optional send/expect handshake
+	if mode != ModeTCP && (*tcpSendFlag != "" || *tcpExpectFlag != "") {
+		log.Fatal("Error: -tcp-send and -tcp-expect require -mode tcp")
+	}
+	
+	// Compound mode combines several sub-checks into one up/down verdict
+	var compoundSteps []compoundStep
+	if mode == ModeCompound {
+		compoundSteps, err = parseCompoundSteps(*compoundChecksFlag)
+		if err != nil {
+			log.Fatalf("Error: Invalid -compound-checks: %v", err)
+		}
+	} else if *compoundChecksFlag != "" {
+		log.Fatal("Error: -compound-checks requires -mode compound")
+	}
+	
+	// The benchmark and compare commands never run the ELF binary, and healing actions can replace it
+	if *elfPathFlag == "" && command != commandBenchmark && command != commandCompare && *healingActionsFlag == "" {
+		log.Fatal("Error: ELF binary path is required. Use -elf flag.")
+	}
+	
+	// Validate that the ELF file exists and is executable on this platform
+	if *elfPathFlag != "" {
+		if err := validateExecutable(*elfPathFlag); err != nil {
+			log.Fatalf("Error: Cannot use ELF binary %s: %v", *elfPathFlag, err)
+		}
+	}
+	
+	// Make sure the ELF binary actually runs before it is needed in an outage
+	// A failure is only a warning since the binary may need a real outage's context
+	if *validateELFFlag {
+		output, err := validateELF(*elfPathFlag, time.Duration(*timeoutFlag)*time.Second)
+		if err != nil {
+			log.Printf("WARNING: ELF binary %s failed validation: %v", *elfPathFlag, err)
+		} else {
+			log.Printf("ELF binary %s passed validation", *elfPathFlag)
+		}
+		if *verboseFlag && output != "" {
+			log.Printf("ELF validation output: %s", output)
+		}
+	}
+	
+	log.Printf("Starting website monitor for %s", *urlFlag)
+	if *elfPathFlag != "" {
+		log.Printf("Will execute %s when website is down", *elfPathFlag)
+	}
+	if jitterMode == JitterRandom {
+		log.Printf("Checking every %d seconds on average, at random", *intervalFlag)
+	} else {
+		log.Printf("Checking every %d seconds", *intervalFlag)
+	}
+	switch backoffMode {
+	case BackoffLinear:
+		log.Printf("Using linear backoff: initial=%ds, increment=%ds, max=%ds", *initialBackoffFlag, *backoffIncrementFlag, *maxBackoffFlag)
+	case BackoffConstant:
+		log.Printf("Using constant backoff: %ds", *initialBackoffFlag)
+	default:
+		log.Printf("Using backoff: initial=%ds, factor=%.1f, max=%ds", *initialBackoffFlag, *backoffFactorFlag, *maxBackoffFlag)
+	}
+	
+	// Load the IP blocklist and reload it whenever we receive SIGHUP
+	var ipBlocklist *blocklist
+	if *blocklistFileFlag != "" {
+		ipBlocklist, err = loadBlocklist(*blocklistFileFlag)
+		if err != nil {
+			log.Fatalf("Error: Cannot load blocklist %s: %v", *blocklistFileFlag, err)
+		}
+		log.Printf("Loaded %d blocklist entries from %s", ipBlocklist.size(), *blocklistFileFlag)
 		
-		if siteDown {
-			log.Printf("Website %s is DOWN! Executing ELF binary...", *urlFlag)
-			executeELF(*elfPathFlag)
-			
-			// Increment failure counter and calculate new backoff
-			consecutiveFailures++
-			if consecutiveFailures > 1 {
-				// Apply backoff factor
-				newBackoff := int(float64(currentBackoff) * *backoffFactorFlag)
-				
-				// Cap at maximum backoff
-				if newBackoff > *maxBackoffFlag {
-					currentBackoff = *maxBackoffFlag
-				} else {
-					currentBackoff = newBackoff
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for range hup {
+				if err := ipBlocklist.reload(); err != nil {
+					log.Printf("Failed to reload blocklist: %v", err)
+					continue
 				}
-				
-				log.Printf("Consecutive failures: %d. Next check in %d seconds", consecutiveFailures, currentBackoff)
-				time.Sleep(time.Duration(currentBackoff) * time.Second)
-				continue
+				log.Printf("Reloaded %d blocklist entries from %s", ipBlocklist.size(), *blocklistFileFlag)
 			}
+		}()
+	}
+	
+	// Create HTTP client with timeout
+	timeout := time.Duration(*timeoutFlag) * time.Second
+	redirectPolicy := checkRedirect(*failOnHTTPDowngradeFlag, *verboseFlag)
+	client := newHTTPClient(timeout, nil, redirectPolicy)
+	
+	// Create one client per source address on multihomed hosts
+	var sources []sourceClient
+	if *bindAddressesFlag != "" {
+		bindIPs, err := parseBindAddresses(*bindAddressesFlag)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		for _, ip := range bindIPs {
+			sources = append(sources, sourceClient{
+				addr:   ip.String(),
+				client: newHTTPClient(timeout, ip, redirectPolicy),
+			})
+		}
+		log.Printf("Checking from %d source addresses: %s", len(sources), *bindAddressesFlag)
+	}
+	
+	hstsEnabled := *checkHSTSFlag || *failOnMissingHSTSFlag
+	hsts := hstsPolicy{
+		minMaxAge:                *minHSTSMaxAgeFlag,
+		requireIncludeSubDomains: *requireHSTSIncludeSubdomainsFlag,
+		requirePreload:           *requireHSTSPreloadFlag,
+	}
+	
+	mixedContentEnabled := *checkMixedContentFlag || *failOnMixedContentFlag
+	
+	cspEnabled := *checkCSPFlag || *minCSPDirectivesFlag > 0 || *expectCSPFlag != "" || *failOnCSPFlag
+	csp := cspPolicy{
+		minDirectives: *minCSPDirectivesFlag,
+		expected:      *expectCSPFlag,
+	}
+	
+	expectedPushes := parsePushPaths(*expectPushFlag)
+	if len(expectedPushes) > 0 && targetURL.Scheme != "https" {
+		log.Printf("Warning: -expect-push requires an https URL, server push will not be checked")
+		expectedPushes = nil
+	}
+	
+	// Fetch credentials for the monitored host from Vault
+	var creds *credentialStore
+	if *vaultAddrFlag != "" || *vaultSecretPathFlag != "" {
+		if *vaultAddrFlag == "" || *vaultSecretPathFlag == "" {
+			log.Fatal("Error: -vault-addr and -vault-secret-path must be used together")
+		}
+		source, err := newVaultSource(*vaultAddrFlag, *vaultSecretPathFlag, *vaultTokenFileFlag, timeout)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		creds, err = newCredentialStore(source, time.Duration(*vaultRenewBeforeFlag)*time.Second)
+		if err != nil {
+			log.Fatalf("Error: Cannot fetch credentials from Vault: %v", err)
+		}
+		log.Printf("Using credentials from %s", source.name())
+	}
+	
+	// Or fetch them from AWS Secrets Manager, refreshing on the rotation schedule
+	if *awsSecretARNFlag != "" {
+		if creds != nil {
+			log.Fatal("Error: -aws-secret-arn cannot be combined with Vault credentials")
+		}
+		source, err := newAWSSecretSource(*awsSecretARNFlag, timeout)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		creds, err = newCredentialStore(source, 0)
+		if err != nil {
+			log.Fatalf("Error: Cannot fetch credentials from AWS Secrets Manager: %v", err)
+		}
+		log.Printf("Using credentials from %s", source.name())
+	}
+	// Refuse servers that only offer older TLS versions
+	// The check is installed even without the flag so that a server older than
+	// Go's default minimum fails with a *tlsVersionError too
+	var minTLSVersion uint16
+	if *minTLSVersionFlag != "" {
+		minTLSVersion, err = parseTLSVersion(*minTLSVersionFlag)
+		if err != nil {
+			log.Fatalf("Error: Invalid -min-tls-version: %v", err)
+		}
+		log.Printf("Requiring TLS %s or newer", *minTLSVersionFlag)
+	}
+	withMinTLSVersion(client, minTLSVersion)
+	for _, source := range sources {
+		withMinTLSVersion(source.client, minTLSVersion)
+	}
+	// Resolve the hostname in the background and dial the cached addresses
+	var resolveCache *dnsCache
+	if *dnsRefreshFlag > 0 {
+		if net.ParseIP(targetURL.Hostname()) != nil {
+			log.Printf("Warning: %s is an IP address, -dns-refresh has no effect", targetURL.Hostname())
 		} else {
-			if *verboseFlag {
-				log.Printf("Website %s is UP", *urlFlag)
+			resolveCache = newDNSCache(targetURL.Hostname(), *urlFlag, time.Duration(*dnsRefreshFlag)*time.Second, timeout, *dnsChangeELFFlag, *verboseFlag)
+			withDNSCache(client, resolveCache)
+			for _, source := range sources {
+				withDNSCache(source.client, resolveCache)
 			}
-			// Reset backoff when site comes back up
-			consecutiveFailures = 0
-			currentBackoff = *initialBackoffFlag
+			log.Printf("Refreshing DNS for %s every %ds", targetURL.Hostname(), *dnsRefreshFlag)
 		}
-		
-		// Wait for the normal check interval
-		time.Sleep(time.Duration(*intervalFlag) * time.Second)
 	}
-}
-
-// checkWebsiteDown checks if a website is down by making HTTP requests
-// Returns true if the website is considered down
-func checkWebsiteDown(url string, client *http.Client, retries int, verbose bool) bool {
-	for i := 0; i < retries; i++ {
-		resp, err := client.Get(url)
-		
+	
+	if creds != nil {
+		withCredentials(client, targetURL.Host, creds)
+		for _, source := range sources {
+			withCredentials(source.client, targetURL.Host, creds)
+		}
+	}
+	
+	var mirrors *mirrorPool
+	if *mirrorURLsFlag != "" {
+		mirrors, err = parseMirrors(*mirrorURLsFlag, targetURL.Scheme)
 		if err != nil {
-			if verbose {
-				log.Printf("Request failed (attempt %d/%d): %v", i+1, retries, err)
-			}
-			// If not our last attempt, try again
-			if i < retries-1 {
-				time.Sleep(2 * time.Second) // Small delay between retries
-				continue
+			log.Fatalf("Error: Invalid -mirror-urls: %v", err)
+		}
+		mirrors.mirrors = slices.DeleteFunc(mirrors.mirrors, func(m *mirror) bool {
+			if m.url == *urlFlag {
+				log.Printf("Warning: ignoring mirror %s, it is the monitored URL", m.url)
+				return true
 			}
-			return true // Website is down after all retries failed
+			return false
+		})
+		log.Printf("Using %d mirrors when %s is down", len(mirrors.mirrors), *urlFlag)
+	}
+	
+	var dnsChanges *dnsTracker
+	if *monitorDNSChangesFlag && *dnsRefreshFlag <= 0 {
+		if net.ParseIP(targetURL.Hostname()) != nil {
+			log.Printf("Warning: %s is an IP address, DNS changes will not be monitored", targetURL.Hostname())
+		} else {
+			dnsChanges = &dnsTracker{host: targetURL.Hostname()}
 		}
-		
-		defer resp.Body.Close()
-		
-		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
-			if verbose {
-				log.Printf("Bad status code (attempt %d/%d): %d", i+1, retries, resp.StatusCode)
+	}
+	
+	compareURL := ""
+	if *compareURLFlag != "" {
+		compareURL, err = compareURLFor(targetURL, *compareURLFlag)
+		if err != nil {
+			log.Fatalf("Error: Invalid -compare-url: %v", err)
+		}
+		log.Printf("Comparing responses against %s", compareURL)
+	}
+	
+	// Track the failure rate to catch sites that fail intermittently
+	var failureRate *failureRateTracker
+	var failureRateLimit float64
+	if *failureRateAlertFlag != "" {
+		failureRateLimit, err = parseFailureRate(*failureRateAlertFlag)
+		if err != nil {
+			log.Fatalf("Error: Invalid -failure-rate-alert: %v", err)
+		}
+		if *failureRateWindowFlag < 2 {
+			log.Fatal("Error: -failure-rate-window must be at least 2")
+		}
+		failureRate = newFailureRateTracker(*failureRateWindowFlag)
+		log.Printf("Alerting when failures exceed %.2f/min over the last %d checks", failureRateLimit, *failureRateWindowFlag)
+	}
+	
+	// Watch headers that reveal which CDN or server answered
+	var watchedHeaders *headerWatch
+	if *watchHeadersFlag != "" {
+		watchedHeaders = newHeaderWatch(*watchHeadersFlag)
+		log.Printf("Watching response headers for changes: %s", strings.Join(watchedHeaders.names, ", "))
+	} else if *failOnHeaderChangeFlag {
+		log.Fatal("Error: -fail-on-header-change requires -watch-headers")
+	}
+	
+	// Learn the usual response size to catch truncated or empty pages
+	var sizes *sizeTracker
+	if *sizeAlertStddevFlag > 0 {
+		sizes = newSizeTracker(*sizeWarmupChecksFlag, *sizeAlertStddevFlag, *sizeRebaselineChecksFlag)
+		log.Printf("Alerting when the response size is more than %g standard deviations from its mean after %d checks", *sizeAlertStddevFlag, *sizeWarmupChecksFlag)
+	}
+	
+	// Gate the canary deployment on matching the primary
+	var canary *canaryCheck
+	if *canaryURLFlag != "" {
+		canary = &canaryCheck{url: *canaryURLFlag, maxSlowdown: *canaryMaxSlowdownFlag}
+		if *canaryBodyRegexFlag != "" {
+			canary.bodyPattern, err = regexp.Compile(*canaryBodyRegexFlag)
+			if err != nil {
+				log.Fatalf("Error: Invalid -canary-body-regex: %v", err)
 			}
-			// If not our last attempt, try again
-			if i < retries-1 {
-				time.Sleep(2 * time.Second) // Small delay between retries
-				continue
+		}
+		log.Printf("Comparing canary %s against %s", *canaryURLFlag, *urlFlag)
+	}
+	canaryELF := *canaryELFFlag
+	if canaryELF == "" {
+		canaryELF = *elfPathFlag
+	}
+	
+	// Time responses in the kernel where eBPF socket filters can be loaded
+	measureEBPF := *ebpfLatencyFlag
+	if measureEBPF {
+		if err := ebpfLatencySupported(); err != nil {
+			log.Printf("WARNING: eBPF latency measurement is unavailable (%v), falling back to application-level timing", err)
+			measureEBPF = false
+		}
+	}
+	
+	checkOpts := checkOptions{
+		retries:   *retriesFlag,
+		verbose:   *verboseFlag,
+		certPin:   *certPinFlag,
+		readBody:  *compareBodyFlag || (canary != nil && canary.bodyPattern == nil) || sizes != nil || mixedContentEnabled,
+		ebpfLatency: measureEBPF,
+		bustCache: *bustCacheFlag,
+		retry: retryPolicy{
+			onTimeout:           *retryOnTimeoutFlag,
+			onConnectionRefused: *retryOnConnectionRefusedFlag,
+			on5xx:               *retryOn5xxFlag,
+			on4xx:               *retryOn4xxFlag,
+		},
+	}
+	checkOpts.minTLSVersion = minTLSVersion
+	checkOpts.decompress = *decompressFlag
+	if *requireCipherFlag != "" {
+		checkOpts.allowedCiphers, err = parseCipherSuites(*requireCipherFlag)
+		if err != nil {
+			log.Fatalf("Error: Invalid -require-cipher: %v", err)
+		}
+	}
+	
+	// Send the configured request, e.g. a POST with a JSON payload
+	checkOpts.method = strings.ToUpper(*methodFlag)
+	if *requestBodyFlag != "" && *requestBodyFileFlag != "" {
+		log.Fatal("Error: -request-body and -request-body-file are mutually exclusive")
+	}
+	if *requestBodyFlag != "" {
+		checkOpts.body = []byte(*requestBodyFlag)
+	} else if *requestBodyFileFlag != "" {
+		checkOpts.body, err = os.ReadFile(*requestBodyFileFlag)
+		if err != nil {
+			log.Fatalf("Error: Cannot read request body file: %v", err)
+		}
+	}
+	if checkOpts.body != nil {
+		if mode == ModeGraphQL {
+			log.Fatal("Error: graphql mode builds its own request body, -request-body cannot be used")
+		}
+		checkOpts.contentType = *requestBodyContentTypeFlag
+		if checkOpts.contentType == "" {
+			checkOpts.contentType = "application/octet-stream"
+		}
+		if checkOpts.method == http.MethodGet || checkOpts.method == http.MethodHead {
+			log.Printf("Warning: sending a request body with %s, servers usually ignore it; use -method POST", checkOpts.method)
+		}
+		log.Printf("Sending %s requests with a %d-byte %s body", checkOpts.method, len(checkOpts.body), checkOpts.contentType)
+	} else if checkOpts.method != http.MethodGet {
+		log.Printf("Sending %s requests", checkOpts.method)
+	}
+	
+	// GraphQL mode POSTs the query as JSON and validates the response body
+	if mode == ModeGraphQL {
+		query := *gqlQueryFlag
+		if *gqlQueryFileFlag != "" {
+			data, err := os.ReadFile(*gqlQueryFileFlag)
+			if err != nil {
+				log.Fatalf("Error: Cannot read GraphQL query file: %v", err)
 			}
-			return true // Website is down after all retries returned bad status codes
+			query = string(data)
+		}
+		if strings.TrimSpace(query) == "" {
+			log.Fatal("Error: graphql mode requires -gql-query or -gql-query-file")
 		}
 		
-		// If we get here, the website is up
-		return false
+		checkOpts.method = http.MethodPost
+		checkOpts.contentType = "application/json"
+		checkOpts.body, err = graphQLRequestBody(query)
+		if err != nil {
+			log.Fatalf("Error: Cannot encode GraphQL query: %v", err)
+		}
+		checkOpts.readBody = true
 	}
 	
-	return true // Should not reach here, but if we do, assume the site is down
-}
-
-// executeELF runs the specified ELF binary
-func executeELF(elfPath string) {
-	cmd := exec.Command(elfPath)
+	// Delegate checks to an external plugin over gRPC
+	var checkPlugin *grpcCheckPlugin
+	if *grpcCheckPluginFlag != "" {
+		options, err := parsePluginOptions(*grpcCheckOptionsFlag)
+		if err != nil {
+			log.Fatalf("Error: Invalid -grpc-check-options: %v", err)
+		}
+		checkPlugin, err = newGRPCCheckPlugin(*grpcCheckPluginFlag, options, timeout)
+		if err != nil {
+			log.Fatalf("Error: Cannot connect to check plugin: %v", err)
+		}
+		log.Printf("Using check plugin at %s", *grpcCheckPluginFlag)
+	}
 	
-	// Capture output
-	output, err := cmd.CombinedOutput()
+	// Read the access log instead of making requests in passive mode, or
+	// watch it alongside the active checks of -url
+	var tailer, serverLog *logTailer
+	if *tailLogFlag != "" {
+		if *tailWindowFlag <= 0 {
+			log.Fatal("Error: -tail-window must be positive")
+		}
+		parser, err := newLogLineParser(*logFormatFlag, *logPatternFlag)
+		if err != nil {
+			log.Fatalf("Error: Invalid log format: %v", err)
+		}
+		logTail, err := newLogTailer(*tailLogFlag, parser, time.Duration(*tailWindowFlag)*time.Second, *errorRateThresholdFlag)
+		if err != nil {
+			log.Fatalf("Error: Cannot tail log: %v", err)
+		}
+		if passiveLog {
+			tailer = logTail
+			log.Printf("Passively monitoring %s for 5xx rates above %.1f%% over %ds", *tailLogFlag, *errorRateThresholdFlag, *tailWindowFlag)
+		} else {
+			serverLog = logTail
+			log.Printf("Watching %s for 5xx rates above %.1f%% over %ds", *tailLogFlag, *errorRateThresholdFlag, *tailWindowFlag)
+		}
+	}
 	
-	if err != nil {
-		log.Printf("Failed to execute ELF binary: %v", err)
+	probe := tcpProbe{
+		send:    []byte(parseTCPEscapes(*tcpSendFlag)),
+		expect:  []byte(parseTCPEscapes(*tcpExpectFlag)),
+		timeout: timeout,
+	}
+	
+	// Cancel in-flight checks on SIGINT or SIGTERM so the monitor stops promptly
+	// A second signal kills the process as usual
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-quit
+		signal.Stop(quit)
+		log.Printf("Received %s, stopping", sig)
+		cancel()
+	}()
+	
+	// runCheck checks the target once in the configured mode
+	// It is shared by the scheduled loop and POST /checks/{url}/trigger.
+	// failedSources lists the -bind-addresses the target was unreachable from
+	runCheck := func(ctx context.Context) (failedSources []string, result *checkResult, err error) {
+		if checkPlugin != nil {
+			result, err = checkPlugin.check(*urlFlag)
+		} else if tailer != nil {
+			result, err = &checkResult{}, tailer.check(*verboseFlag)
+		} else if mode == ModeCompound {
+			result, err = checkCompound(ctx, targetURL, compoundSteps, client, checkOpts, timeout)
+		} else if mode == ModeTCP {
+			result, err = checkTCP(checkTarget, probe, checkOpts)
+		} else if mode == ModeICMP {
+			result, err = checkICMP(checkTarget, timeout, checkOpts)
+		} else if mode == ModeGRPC {
+			result, err = checkGRPCHealth(checkTarget, timeout, checkOpts)
+		} else if mode == ModeWebSocket {
+			result, err = checkWebSocket(client, checkTarget, checkOpts)
+		} else if mode == ModeDNS {
+			result, err = checkDNS(checkTarget, timeout, checkOpts)
+		} else if len(sources) > 0 {
+			failedSources, result, err = checkFromSources(ctx, *urlFlag, sources, checkOpts)
+		} else {
+			result, err = checkWebsiteDown(ctx, *urlFlag, client, checkOpts)
+		}
+		return failedSources, result, err
+	}
+	
+	// Load the JSON Schema used to validate response bodies
+	var responseSchema *jsonschema.Schema
+	if *jsonSchemaFlag != "" {
+		responseSchema, err = loadJSONSchema(*jsonSchemaFlag)
+		if err != nil {
+			log.Fatalf("Error: Cannot load JSON schema: %v", err)
+		}
+		checkOpts.readBody = true
+		log.Printf("Validating responses against JSON schema %s", *jsonSchemaFlag)
+	}
+	
+	// Generate endpoint checks from the OpenAPI spec
+	var openAPI *openAPIMonitor
+	if *openAPISpecFlag != "" {
+		if *openAPIWorkersFlag < 1 {
+			log.Fatal("Error: -openapi-workers must be at least 1")
+		}
+		openAPI, err = newOpenAPIMonitor(*openAPISpecFlag, targetURL, *pathParamsFlag, *openAPIAllPathsFlag)
+		if err != nil {
+			log.Fatalf("Error: Cannot load OpenAPI spec: %v", err)
+		}
+		log.Printf("Checking %d endpoints from OpenAPI spec %s", len(openAPI.current()), *openAPISpecFlag)
+	} else if *pathParamsFlag != "" {
+		log.Fatal("Error: -path-params requires -openapi-spec")
+	}
+	
+	// Find pages to check in the sitemaps listed by robots.txt
+	// The site may be down at startup, so failing to discover them is not fatal
+	var discovered []endpointCheck
+	if *discoverFromRobotsFlag {
+		if mode != ModeHTTP {
+			log.Fatalf("Error: -discover-from-robots requires http mode, not %s", mode)
+		}
+		discovered, err = discoverFromRobots(client, targetURL, *maxDiscoveredURLsFlag)
+		if err != nil {
+			log.Printf("WARNING: Cannot discover pages from robots.txt: %v", err)
+		} else {
+			log.Printf("Checking %d pages discovered from the sitemaps of %s", len(discovered), targetURL.Host)
+		}
+	}
+	
+	// Measure latency under load instead of monitoring
+	if command == commandBenchmark {
+		if mode != ModeHTTP && mode != ModeGraphQL {
+			log.Fatalf("Error: benchmark supports http and graphql modes, not %s", mode)
+		}
+		if *benchmarkRequestsFlag < 1 || *benchmarkConcurrencyFlag < 1 {
+			log.Fatal("Error: -benchmark-requests and -benchmark-concurrency must be at least 1")
+		}
+		log.Printf("Benchmarking %s with %d requests, %d at a time", *urlFlag, *benchmarkRequestsFlag, *benchmarkConcurrencyFlag)
+		runBenchmark(ctx, *urlFlag, client, checkOpts, *benchmarkRequestsFlag, *benchmarkConcurrencyFlag).write(os.Stdout)
 		return
 	}
 	
-	// Log the output
-	fmt.Println("ELF binary output:")
-	fmt.Println(string(output))
+	// Compare the latency of two URLs instead of monitoring
+	if command == commandCompare {
+		if mode != ModeHTTP && mode != ModeGraphQL {
+			log.Fatalf("Error: compare supports http and graphql modes, not %s", mode)
+		}
+		if *secondaryURLFlag == "" {
+			log.Fatal("Error: compare requires -secondary-url")
+		}
+		if *compareWindowFlag < 1 {
+			log.Fatal("Error: -compare-window must be at least 1")
+		}
+		secondary, err := normalizeURL(*secondaryURLFlag)
+		if err != nil {
+			log.Fatalf("Error: Invalid -secondary-url: %v", err)
+		}
+		log.Printf("Comparing %s against %s every %d seconds, reporting every %d checks", secondary, *urlFlag, *intervalFlag, *compareWindowFlag)
+		runCompare(ctx, *urlFlag, secondary, client, checkOpts, time.Duration(*intervalFlag)*time.Second, *compareWindowFlag, *compareThresholdFlag)
+		return
+	}
+	
+	var healing *healingRegistry
+	if *healingActionsFlag != "" {
+		healing, err = loadHealingRegistry(*healingActionsFlag)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		log.Printf("Loaded %d healing actions from %s", len(healing.actions), *healingActionsFlag)
+	}
+	
+	// Initialize backoff state
+	consecutiveFailures := 0
+	currentBackoff := *initialBackoffFlag
+	backoffResetAfter := time.Duration(*backoffResetAfterFlag) * time.Second
+	var continuousUpSince time.Time
+	elfExecutions := &elfGuard{maxExecutions: *maxELFExecutionsFlag}
+	
+	// Run the ELF binary in the background with -elf-async or -allow-overlap so the
+	// next cycle can start on time even if it is slow
+	elfs := newELFRunner(*elfRetriesFlag, (*elfAsyncFlag || *allowOverlapFlag) && !*onceFlag, *maxConcurrentELFFlag)
+	partialFailure := false
+	lastRedirectCount := -1
+	lastFinalURL := ""
+	lowSecurityScore := false
+	certExpiring := false
+	canaryMismatch := false
+	failureRateExceeded := false
+	serverLogErrors := false
+	sizeAnomaly := false
+	cacheHits := newCacheHitRate(*cacheHitWindowFlag)
+	lowCacheHitRate := false
+	var certRenew *certRenewer
+	if *certRenewELFFlag != "" {
+		if *certCriticalDaysFlag <= 0 {
+			log.Fatal("Error: -cert-renew-elf requires -cert-critical-days")
+		}
+		statePath := *certRenewStateFlag
+		if statePath == "" {
+			statePath = defaultStatePath("cert-renew", *urlFlag)
+		}
+		certRenew = newCertRenewer(*certRenewELFFlag, statePath, time.Duration(*certRenewWaitFlag)*time.Second)
+		log.Printf("Will execute %s when the certificate expires within %d days", *certRenewELFFlag, *certCriticalDaysFlag)
+	}
+	
+	// Silence the URL's Prometheus alerts while the ELF binary handles an outage
+	var alertSilencer *alertmanagerSilencer
+	if *alertmanagerAddrFlag != "" {
+		if *elfPathFlag == "" {
+			log.Fatal("Error: -alertmanager-addr requires -elf")
+		}
+		statePath := *alertmanagerStateFlag
+		if statePath == "" {
+			statePath = defaultStatePath("alertmanager", *urlFlag)
+		}
+		alertSilencer = newAlertmanagerSilencer(*alertmanagerAddrFlag, *alertmanagerLabelFlag, time.Duration(*alertmanagerSilenceDurationFlag)*time.Second, statePath, timeout)
+		log.Printf("Silencing alerts with %s=%s in Alertmanager %s during outages", *alertmanagerLabelFlag, *urlFlag, *alertmanagerAddrFlag)
+	}
+	
+	// Serve the API with the current status and the check schedule
+	// Uptime history is hourly and approximate unless -exact-history is set
+	var history checkHistory
+	if *exactHistoryFlag {
+		if *historyMemoryFlag <= 0 {
+			log.Fatal("Error: -history-memory must be positive")
+		}
+		history = newExactHistory(1, int64(*historyMemoryFlag)<<20)
+	} else {
+		history = newBloomHistory(1, time.Duration(*intervalFlag)*time.Second)
+	}
+	status := newStatusTracker(history)
+	config := &configSnapshot{}
+	config.update()
+	// Only one check runs at a time, in the loop or triggered through the API,
+	// unless -allow-overlap is set. Goroutines that do not check the URL, such as
+	// the blocklist reloader, never take this lock
+	cycleLock := newCycleLock(!*allowOverlapFlag)
+	
+	var events *eventStream
+	if *apiAddrFlag != "" {
+		status.results = newResultStream()
+		var corsOrigins []string
+		for _, origin := range strings.Split(*apiCORSOriginsFlag, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				corsOrigins = append(corsOrigins, origin)
+			}
+		}
+		events = newEventStream(corsOrigins)
+		api := &apiServer{
+			schedules: []checkSchedule{{
+				url:      *urlFlag,
+				interval: time.Duration(*intervalFlag) * time.Second,
+				start:    time.Now(),
+				timeout:  timeout,
+			}},
+			status:      status,
+			config:      config,
+			events:      events,
+			results:     status.results,
+			logs:        logs,
+			apiToken:    *apiTokenFlag,
+			corsOrigins: corsOrigins,
+		}
+		
+		// A -tail-log check reads the log as it grows, so it cannot be run out of cycle
+		if tailer == nil {
+			api.trigger = func(ctx context.Context) (*checkResult, error) {
+				if err := cycleLock.lockContext(ctx); err != nil {
+					return nil, err
+				}
+				defer cycleLock.Unlock()
+				_, result, err := runCheck(ctx)
+				return result, err
+			}
+			api.triggerTimeout = time.Duration(*triggerTimeoutFlag) * time.Second
+		}
+		
+		// Poll the peers so the status includes the reachability matrix
+		if peers := parsePeers(*matrixPeersFlag); len(peers) > 0 {
+			api.peers = newPeerPoller(peers, timeout)
+			go api.peers.run(time.Duration(*intervalFlag) * time.Second)
+			log.Printf("Polling %d matrix peers", len(peers))
+		}
+		api.start(*apiAddrFlag)
+	} else if *matrixPeersFlag != "" {
+		log.Fatal("Error: -matrix-peers requires -api-addr")
+	}
+	
+	// The dashboard follows the same result stream as GET /events/sse
+	if *dashboardAddrFlag != "" {
+		if status.results == nil {
+			status.results = newResultStream()
+		}
+		startDashboard(*dashboardAddrFlag, status)
+	}
+	
+	// The adaptive interval starts at -interval and never exceeds -max-backoff
+	adaptiveInterval := *intervalFlag
+	if *adaptiveIntervalFlag && (*minIntervalFlag < 1 || *minIntervalFlag > *intervalFlag) {
+		log.Fatal("Error: -min-interval must be between 1 and -interval")
+	}
+	
+	// Notifiers are told when the website goes down and when it recovers
+	var notifiers []Notifier
+	if *snmpTrapAddrFlag != "" {
+		snmp, err := newSNMPNotifier(*snmpTrapAddrFlag, *snmpCommunityFlag, *snmpTrapOIDFlag, timeout)
+		if err != nil {
+			log.Fatalf("Error: Invalid -snmp-trap-addr: %v", err)
+		}
+		notifiers = append(notifiers, snmp)
+	}
+	if *githubActionsFlag {
+		if inGitHubActions() {
+			notifiers = append(notifiers, &githubActionsNotifier{out: os.Stdout})
+		} else {
+			log.Printf("Warning: GITHUB_ACTIONS is not set, -github-actions has no effect")
+		}
+	}
+	var sentryReporter *sentryNotifier
+	if *sentryDSNFlag != "" {
+		sentryReporter, err = newSentryNotifier(*sentryDSNFlag)
+		if err != nil {
+			log.Fatalf("Error: Invalid -sentry-dsn: %v", err)
+		}
+		notifiers = append(notifiers, sentryReporter)
+	}
+	if (*iftttKeyFlag == "") != (*iftttEventFlag == "") {
+		log.Fatal("Error: -ifttt-key and -ifttt-event must be given together")
+	} else if *iftttKeyFlag != "" {
+		notifiers = append(notifiers, newIFTTTNotifier(*iftttKeyFlag, *iftttEventFlag, timeout))
+	}
+	if (*telegramTokenFlag == "") != (*telegramChatIDFlag == "") {
+		log.Fatal("Error: -telegram-token and -telegram-chat-id must be given together")
+	} else if *telegramTokenFlag != "" {
+		notifiers = append(notifiers, newTelegramNotifier(*telegramTokenFlag, *telegramChatIDFlag, timeout))
+	}
+	if *discordWebhookFlag != "" {
+		discord, err := newDiscordNotifier(*discordWebhookFlag, timeout)
+		if err != nil {
+			log.Fatalf("Error: Invalid -discord-webhook: %v", err)
+		}
+		notifiers = append(notifiers, discord)
+	}
+	if *teamsWebhookFlag != "" {
+		teams, err := newTeamsNotifier(*teamsWebhookFlag, *statusPageURLFlag, timeout)
+		if err != nil {
+			log.Fatalf("Error: Invalid -teams-webhook or -status-page-url: %v", err)
+		}
+		notifiers = append(notifiers, teams)
+	} else if *statusPageURLFlag != "" {
+		log.Fatal("Error: -status-page-url requires -teams-webhook")
+	}
+	if *mqttBrokerFlag != "" {
+		mqttPublisher, err := newMQTTNotifier(*mqttBrokerFlag, *mqttTopicFlag, *mqttClientIDFlag, *mqttQoSFlag, *urlFlag, timeout)
+		if err != nil {
+			log.Fatalf("Error: Invalid MQTT settings: %v", err)
+		}
+		notifiers = append(notifiers, mqttPublisher)
+	}
+	if *redisAddrFlag != "" {
+		redisPublisher, err := newRedisNotifier(*redisAddrFlag, *redisChannelFlag, *redisBufferSizeFlag, timeout)
+		if err != nil {
+			log.Fatalf("Error: Invalid Redis settings: %v", err)
+		}
+		notifiers = append(notifiers, redisPublisher)
+	}
+	if *kafkaBrokersFlag != "" {
+		kafkaPublisher, err := newKafkaNotifier(*kafkaBrokersFlag, *kafkaTopicFlag, *kafkaPartitionCountFlag, timeout)
+		if err != nil {
+			log.Fatalf("Error: Invalid Kafka settings: %v", err)
+		}
+		notifiers = append(notifiers, kafkaPublisher)
+	}
+	if *natsURLFlag != "" {
+		var tags []string
+		for _, tag := range strings.Split(*tagsFlag, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		natsPublisher, err := newNATSNotifier(*natsURLFlag, *natsSubjectFlag, *natsJetStreamFlag, tags, *urlFlag, timeout)
+		if err != nil {
+			log.Fatalf("Error: Cannot set up NATS: %v", err)
+		}
+		notifiers = append(notifiers, natsPublisher)
+	}
+	if events != nil {
+		notifiers = append(notifiers, events)
+	}
+	for _, n := range notifiers {
+		log.Printf("Sending notifications via %s", n.Name())
+	}
+	var notifications *sync.WaitGroup
+	
+	// Publish metrics to CloudWatch with the standard AWS credential chain
+	var cloudWatch *cloudWatchPublisher
+	if *cloudWatchNamespaceFlag != "" {
+		cloudWatch, err = newCloudWatchPublisher(*cloudWatchNamespaceFlag, timeout)
+		if err != nil {
+			log.Fatalf("Error: Cannot set up CloudWatch: %v", err)
+		}
+		log.Printf("Publishing metrics to CloudWatch namespace %s", *cloudWatchNamespaceFlag)
+	}
+	
+	// Exit rather than hang silently if a cycle stalls
+	var cycleWatchdog *watchdog
+	if *watchdogTimeoutFlag >= 0 {
+		watchdogTimeout := time.Duration(*watchdogTimeoutFlag) * time.Second
+		if watchdogTimeout == 0 {
+			watchdogTimeout = 2 * time.Duration(*intervalFlag) * time.Second
+		}
+		if watchdogTimeout > 0 {
+			cycleWatchdog = newWatchdog(watchdogTimeout)
+		}
+	}
+	
+	// Watch for goroutine and memory leaks in the monitor itself
+	if *selfCheckIntervalFlag > 0 && !*onceFlag {
+		selfCheck{maxGoroutines: *maxGoroutinesFlag, maxHeapMB: *maxHeapMBFlag}.start(time.Duration(*selfCheckIntervalFlag) * time.Second)
+	}
+	
+	// Look for a newer release in the background; failures never stop monitoring
+	if *checkUpdatesFlag && !*onceFlag {
+		startUpdateCheck(*verboseFlag)
+	}
+	
+	// Show that the monitor is alive even when nothing changes
+	var alive *heartbeat
+	if *heartbeatIntervalFlag > 0 {
+		alive = newHeartbeat(time.Duration(*heartbeatIntervalFlag)*time.Second, *heartbeatFileFlag)
+		log.Printf("Logging a heartbeat every %ds", *heartbeatIntervalFlag)
+	} else if *heartbeatFileFlag != "" {
+		log.Fatal("Error: -heartbeat-file requires -heartbeat-interval")
+	}
+	
+	// Main monitoring loop
+	for {
+		cycleLock.Lock()
+		if cycleWatchdog != nil {
+			cycleWatchdog.begin()
+		}
+		
+		// Apply settings re-read from Kubernetes between cycles
+		select {
+		case settings := <-k8sSettings:
+			applyK8sSettings(settings, commandLine)
+			config.update()
+		default:
+		}
+		nextCheck := applyJitter(time.Duration(*intervalFlag)*time.Second, jitterMode)
+		
+		// Renew credentials that are about to expire
+		if creds != nil {
+			creds.refresh()
+		}
+		
+		// Track the DNS answer for the hostname before checking, keeping the
+		// addresses for the events of this check
+		var resolvedIPs []string
+		if dnsChanges != nil {
+			if answer := observeDNS(dnsChanges, *urlFlag, timeout, *dnsChangeELFFlag, *verboseFlag); answer != nil {
+				resolvedIPs = answer.addrs
+			}
+		} else if tailer == nil {
+			resolvedIPs = lookupIPs(ctx, targetURL.Hostname(), resolveCache, timeout)
+		}
+		
+		failedSources, result, checkErr := runCheck(ctx)
+		
+		// Stop without reporting a check cut short by SIGINT or SIGTERM
+		if ctx.Err() != nil {
+			return
+		}
+		
+		// Raise a warning once when only some source addresses fail
+		if checkErr == nil && len(failedSources) > 0 {
+			if !partialFailure {
+				message := fmt.Sprintf("unreachable from %s", strings.Join(failedSources, ", "))
+				log.Printf("Website %s is PARTIALLY DOWN (%s). Executing ELF binary...", *urlFlag, message)
+				elfs.run(*elfPathFlag, newEvent(EventPartial, SeverityWarning, *urlFlag, message), nil)
+			}
+			partialFailure = true
+		} else {
+			partialFailure = false
+		}
+		
+		// Log the redirect chain when verbose, otherwise only when the number of redirects changes
+		if checkErr == nil && len(result.Redirects) > 0 {
+			redirects := len(result.Redirects) - 1
+			chain := formatRedirectChain(result.Redirects)
+			if *verboseFlag && redirects > 0 {
+				log.Printf("Redirect chain for %s: %s", *urlFlag, chain)
+			} else if lastRedirectCount >= 0 && redirects != lastRedirectCount {
+				log.Printf("Redirect count for %s changed from %d to %d: %s", *urlFlag, lastRedirectCount, redirects, chain)
+			} else if lastRedirectCount < 0 && redirects > 0 {
+				log.Printf("%s is served via %d redirects: %s", *urlFlag, redirects, chain)
+			}
+			lastRedirectCount = redirects
+			lastFinalURL = result.finalURL()
+		}
+		
+		// Check the resolved addresses against the blocklist
+		if ipBlocklist != nil && checkBlocklist(ctx, targetURL.Hostname(), timeout, ipBlocklist) && *failOnBlocklistFlag && checkErr == nil {
+			checkErr = fmt.Errorf("%s resolves to a blocklisted address", targetURL.Hostname())
+		}
+		
+		// Validate the GraphQL response body
+		if checkErr == nil && mode == ModeGraphQL {
+			if err := checkGraphQLResponse(result.Body, *gqlExpectFieldFlag); err != nil {
+				checkErr = withReason(FailureBodyAssertionFailed, err)
+			} else if *verboseFlag {
+				log.Printf("GraphQL response from %s is valid", *urlFlag)
+			}
+		}
+		
+		// Validate the response body against the JSON schema
+		if checkErr == nil && responseSchema != nil {
+			if err := validateJSONBody(result.Body, responseSchema); err != nil {
+				checkErr = withReason(FailureBodyAssertionFailed, err)
+			} else if *verboseFlag {
+				log.Printf("Response from %s matches the JSON schema", *urlFlag)
+			}
+		}
+		
+		// Check the endpoints generated from the OpenAPI spec
+		if checkErr == nil && openAPI != nil {
+			if _, err := openAPI.refresh(); err != nil {
+				log.Printf("WARNING: Cannot reload OpenAPI spec, keeping previous checks: %v", err)
+			}
+			
+			endpoints := openAPI.current()
+			var failing []string
+			for _, r := range runEndpointChecks(ctx, endpoints, client, checkOpts, *openAPIWorkersFlag) {
+				if r.err != nil {
+					failing = append(failing, fmt.Sprintf("%s (%v)", r.check.name(), r.err))
+				} else if *verboseFlag {
+					log.Printf("OpenAPI endpoint %s is up", r.check.name())
+				}
+			}
+			if len(failing) > 0 {
+				checkErr = fmt.Errorf("%d of %d OpenAPI endpoints failing: %s", len(failing), len(endpoints), strings.Join(failing, "; "))
+			}
+		}
+		
+		// Check the pages discovered from the sitemaps
+		if checkErr == nil && len(discovered) > 0 {
+			var failing []string
+			for _, r := range runEndpointChecks(ctx, discovered, client, checkOpts, discoveryWorkers) {
+				if r.err != nil {
+					failing = append(failing, fmt.Sprintf("%s (%v)", r.check.url, r.err))
+				} else if *verboseFlag {
+					log.Printf("Sitemap page %s is up", r.check.url)
+				}
+			}
+			if len(failing) > 0 {
+				checkErr = fmt.Errorf("%d of %d sitemap pages failing: %s", len(failing), len(discovered), strings.Join(failing, "; "))
+			}
+		}
+		
+		// Validate the HSTS header on responses served over HTTPS
+		if checkErr == nil && hstsEnabled && result.TLS != nil {
+			if err := checkHSTS(result.Header, hsts); err != nil {
+				log.Printf("WARNING: HSTS check failed for %s: %v", *urlFlag, err)
+				if *failOnMissingHSTSFlag {
+					checkErr = withReason(FailureHSTSCheckFailed, fmt.Errorf("HSTS check failed: %w", err))
+				}
+			} else if *verboseFlag {
+				log.Printf("HSTS header is valid: %s", result.Header.Get("Strict-Transport-Security"))
+			}
+		}
+		
+		// Look for plain HTTP resources on HTTPS pages
+		if checkErr == nil && mixedContentEnabled && result.TLS != nil {
+			refs := findMixedContent(result.Body)
+			for _, ref := range refs {
+				log.Printf("WARNING: Mixed content on %s: %s", *urlFlag, ref)
+			}
+			if len(refs) > 0 && *failOnMixedContentFlag {
+				checkErr = withReason(FailureMixedContent, fmt.Errorf("%d mixed content references, first %s", len(refs), refs[0]))
+			} else if len(refs) == 0 && *verboseFlag {
+				log.Printf("No mixed content on %s", *urlFlag)
+			}
+		}
+		
+		// Log changes to the watched headers, failing the check if asked to
+		if checkErr == nil && watchedHeaders != nil && result.Header != nil {
+			if changes := watchedHeaders.observe(result.Header); len(changes) > 0 {
+				log.Printf("WARNING: Response headers of %s changed: %s", *urlFlag, strings.Join(changes, "; "))
+				if *failOnHeaderChangeFlag {
+					checkErr = withReason(FailureHeaderChanged, fmt.Errorf("response headers changed: %s", strings.Join(changes, "; ")))
+				}
+			}
+		}
+		
+		// Renew the certificate, or raise a cert-expiring event once, when it is close to expiry
+		if checkErr == nil && *certCriticalDaysFlag > 0 {
+			if expiry, ok := certExpiry(result.TLS); ok {
+				if time.Until(expiry) < time.Duration(*certCriticalDaysFlag)*24*time.Hour {
+					message := fmt.Sprintf("certificate expires %s (%d days left)", expiry.UTC().Format(time.RFC3339), certDaysLeft(expiry, time.Now()))
+					log.Printf("WARNING: %s %s", *urlFlag, message)
+					if certRenew != nil {
+						certRenew.renew(ctx, *urlFlag, expiry, client, checkOpts)
+					} else if !certExpiring {
+						elfs.run(*elfPathFlag, newEvent(EventCertExpiring, SeverityWarning, *urlFlag, message), nil)
+					}
+					certExpiring = true
+				} else {
+					certExpiring = false
+				}
+			}
+		}
+		
+		// Validate the Content-Security-Policy header
+		if checkErr == nil && cspEnabled {
+			if err := checkCSP(result.Header, csp); err != nil {
+				log.Printf("WARNING: CSP check failed for %s: %v", *urlFlag, err)
+				if *failOnCSPFlag {
+					checkErr = withReason(FailureCSPCheckFailed, fmt.Errorf("CSP check failed: %w", err))
+				}
+			} else if *verboseFlag {
+				log.Printf("CSP header is valid: %s", result.Header.Get("Content-Security-Policy"))
+			}
+		}
+		
+		// Score the security headers and raise a degraded event once when the score drops too low
+		if checkErr == nil && (*securityScoreFlag || *minSecurityScoreFlag > 0) {
+			score, findings := securityScore(result.Header)
+			log.Printf("Security header score for %s: %d/100", *urlFlag, score)
+			if *verboseFlag && len(findings) > 0 {
+				log.Printf("Security header findings: %s", strings.Join(findings, "; "))
+			}
+			
+			if score < *minSecurityScoreFlag {
+				if !lowSecurityScore {
+					message := fmt.Sprintf("security header score %d is below %d: %s", score, *minSecurityScoreFlag, strings.Join(findings, "; "))
+					log.Printf("Website %s is DEGRADED (%s). Executing ELF binary...", *urlFlag, message)
+					elfs.run(*elfPathFlag, newEvent(EventDegraded, SeverityWarning, *urlFlag, message), nil)
+				}
+				lowSecurityScore = true
+			} else {
+				lowSecurityScore = false
+			}
+		}
+		
+		// Warn when the response did not come from the CDN cache
+		if checkErr == nil && *verifyCacheHitFlag {
+			if value, hit := cacheStatus(result.Header); !hit {
+				if value == "" {
+					value = "no cache status header"
+				}
+				log.Printf("WARNING: Cache miss for %s (%s)", *urlFlag, value)
+			} else if *verboseFlag {
+				log.Printf("Cache hit for %s (%s)", *urlFlag, value)
+			}
+		}
+		
+		// Track how often the response is served from cache to catch invalidation problems
+		if checkErr == nil && result != nil && result.Header != nil {
+			cacheHits.record(result.Header)
+			rate, ok := cacheHits.rate()
+			if *verboseFlag {
+				if ok {
+					log.Printf("Cache for %s: %s (hit rate %.1f%% over %d checks)", *urlFlag, describeCache(result.Header), rate, cacheHits.checks())
+				} else {
+					log.Printf("Cache for %s: %s", *urlFlag, describeCache(result.Header))
+				}
+			}
+			if ok && *minCacheHitRateFlag > 0 {
+				if rate < *minCacheHitRateFlag {
+					if !lowCacheHitRate {
+						log.Printf("WARNING: Cache hit rate for %s is %.1f%% over %d checks, below %.1f%%", *urlFlag, rate, cacheHits.checks(), *minCacheHitRateFlag)
+					}
+					lowCacheHitRate = true
+				} else {
+					if lowCacheHitRate {
+						log.Printf("Cache hit rate for %s is back to %.1f%%", *urlFlag, rate)
+					}
+					lowCacheHitRate = false
+				}
+			}
+		}
+		
+		// Compare against the alternate host, logging any divergence
+		if checkErr == nil && compareURL != "" {
+			if err := compareResponse(client, compareURL, result, *compareBodyFlag); err != nil {
+				log.Printf("WARNING: Comparison failure between %s and %s (%s): %v", *urlFlag, compareURL, classifyError(err, nil), err)
+			} else if *verboseFlag {
+				log.Printf("Response from %s matches %s", compareURL, *urlFlag)
+			}
+		}
+		
+		// Run the ELF binary once when the response size leaves its usual range
+		if checkErr == nil && sizes != nil && result != nil {
+			anomaly, rebaselined, deviations, mean := sizes.observe(len(result.Body))
+			if rebaselined {
+				log.Printf("Response size for %s has been anomalous for %d checks, using a new baseline of %.0f bytes", *urlFlag, *sizeRebaselineChecksFlag, sizes.mean)
+			} else if anomaly {
+				message := fmt.Sprintf("response size %d bytes is %.1f standard deviations from the mean of %.0f bytes", len(result.Body), deviations, mean)
+				log.Printf("WARNING: Size anomaly for %s: %s", *urlFlag, message)
+				if !sizeAnomaly {
+					log.Printf("Executing ELF binary...")
+					elfs.run(*elfPathFlag, newEvent(EventSizeAnomaly, SeverityWarning, *urlFlag, message), nil)
+				}
+			} else if sizeAnomaly {
+				log.Printf("Response size for %s is back to normal (%d bytes)", *urlFlag, len(result.Body))
+			}
+			sizeAnomaly = anomaly
+		}
+		
+		// Run the canary ELF binary once when the canary stops matching the primary
+		// The primary stays up, so the down ELF binary is not run
+		if checkErr == nil && canary != nil {
+			if err := canary.compare(ctx, client, checkOpts, result); err != nil {
+				log.Printf("WARNING: Canary %s does not match %s: %v", canary.url, *urlFlag, err)
+				if !canaryMismatch {
+					event := newEvent(EventCanary, SeverityWarning, *urlFlag, fmt.Sprintf("canary %s: %v", canary.url, err))
+					event.FailureReason = classifyError(err, nil)
+					log.Printf("Executing canary ELF binary...")
+					elfs.run(canaryELF, event, nil)
+					if healing != nil {
+						healing.run(event)
+					}
+				}
+				canaryMismatch = true
+			} else {
+				if canaryMismatch {
+					log.Printf("Canary %s matches %s again", canary.url, *urlFlag)
+				} else if *verboseFlag {
+					log.Printf("Canary %s matches %s", canary.url, *urlFlag)
+				}
+				canaryMismatch = false
+			}
+		}
+		
+		// Verify the expected HTTP/2 push promises; missing pushes are only a warning
+		if checkErr == nil && len(expectedPushes) > 0 {
+			missing, err := checkServerPush(*urlFlag, expectedPushes, timeout)
+			if err != nil {
+				log.Printf("WARNING: Server push check failed for %s: %v", *urlFlag, err)
+			} else if len(missing) > 0 {
+				log.Printf("WARNING: %s did not push expected resources: %s", *urlFlag, strings.Join(missing, ", "))
+			} else if *verboseFlag {
+				log.Printf("Received all %d expected push promises", len(expectedPushes))
+			}
+		}
+		
+		// The failure rate counts every failed check, whatever the evaluation mode
+		checkFailed := checkErr != nil
+		
+		// In window mode the site is down while enough of the recent checks failed,
+		// rather than on every failure
+		if evaluation != nil {
+			checkErr = evaluation.evaluate(*urlFlag, checkErr)
+		}
+		
+		if checkErr != nil {
+			// Fall back to the mirrors before declaring a full outage
+			upMirror := ""
+			if mirrors != nil {
+				upMirror = mirrors.firstUpMirror(ctx, client, checkOpts)
+			}
+			
+			var event Event
+			if upMirror != "" {
+				message := fmt.Sprintf("primary down (%v), mirror %s is up", checkErr, upMirror)
+				log.Printf("Website %s is DEGRADED (%s)%s", *urlFlag, message, formatResolvedIPs(resolvedIPs))
+				event = newEvent(EventDegraded, SeverityWarning, *urlFlag, message)
+			} else {
+				log.Printf("Website %s is DOWN (%v)!%s", *urlFlag, checkErr, formatResolvedIPs(resolvedIPs))
+				event = newEvent(EventDown, SeverityCritical, *urlFlag, checkErr.Error())
+			}
+			event.Error = checkErr
+			event.ResolvedIPs = resolvedIPs
+			event.Phases = checkPhases(result, checkErr)
+			event.FinalURL = lastFinalURL
+			event.FailureReason = classifyError(checkErr, nil)
+			
+			// Capture the network route to help diagnose where the failure is
+			if *tracerouteOnFailureFlag {
+				route, err := traceroute(targetURL.Hostname())
+				if err != nil {
+					log.Printf("Traceroute to %s failed: %v", targetURL.Hostname(), err)
+				} else {
+					log.Printf("Route to %s:\n%s", targetURL.Hostname(), route)
+					event.Diagnostic = route
+				}
+			}
+			
+			// Run the remediation registered for this kind of failure
+			if healing != nil {
+				if *allowOverlapFlag && !*onceFlag {
+					go healing.run(event)
+				} else {
+					healing.run(event)
+				}
+			}
+			
+			// Stop running the ELF binary once the per-outage limit is reached
+			// or the binary asks for its own suppression
+			// The down notification carries the ELF result, so with a background
+			// ELF binary it is sent once the binary exits
+			notifyDown := consecutiveFailures == 0
+			notifiedByELF := false
+			if *elfPathFlag != "" {
+				if outage, ok := elfExecutions.begin(); !ok {
+					if *verboseFlag {
+						log.Printf("ELF execution suspended for this outage")
+					}
+				} else {
+					elfPath := *elfPathFlag
+					record := func(ran Event) {
+						if sentryReporter != nil && ran.ELFExitCode != 0 && ran.ELFExitCode != elfExitSuppress {
+							if err := sentryReporter.captureELFFailure(elfPath, ran); err != nil {
+								log.Printf("Failed to report ELF failure to Sentry: %v", err)
+							}
+						}
+						if reason := elfExecutions.finish(outage, ran.ELFExitCode); reason != "" {
+							log.Printf("%s, suspending ELF execution until %s recovers", reason, *urlFlag)
+						}
+						if !elfs.async {
+							event = ran
+						} else if notifyDown {
+							ran.ConsecutiveFailures = 1
+							notifyAll(notifiers, ran)
+						}
+					}
+					
+					log.Printf("Executing ELF binary...")
+					if !elfs.run(elfPath, event, record) {
+						elfExecutions.cancel()
+					} else {
+						notifiedByELF = elfs.async && notifyDown
+						if alertSilencer != nil {
+							alertSilencer.silence(*urlFlag, fmt.Sprintf("websitecheck is handling the outage: %s", event.Message))
+						}
+					}
+				}
+			}
+			
+			// Increment failure counter and calculate new backoff
+			// A backoff that has not been reset yet keeps growing on a flapping site
+			consecutiveFailures++
+			continuousUpSince = time.Time{}
+			if consecutiveFailures == 1 && !notifiedByELF {
+				event.ConsecutiveFailures = consecutiveFailures
+				notifications = notifyAll(notifiers, event)
+			}
+			if consecutiveFailures > 1 || currentBackoff > *initialBackoffFlag {
+				currentBackoff = calculateNextBackoff(currentBackoff, *initialBackoffFlag, *backoffIncrementFlag, *backoffFactorFlag, *maxBackoffFlag, backoffMode)
+				
+				log.Printf("Consecutive failures: %d. Next check in %d seconds", consecutiveFailures, currentBackoff)
+				nextCheck = time.Duration(currentBackoff) * time.Second
+			}
+		} else {
+			if *verboseFlag {
+				log.Printf("Website %s is UP", *urlFlag)
+			}
+			// A recovery ends the outage, so the ELF binary may run again next time
+			if elfExecutions.recover() {
+				log.Printf("Website %s recovered, resuming ELF execution", *urlFlag)
+			}
+			
+			// Check slow sites less often and fast sites more often
+			if *adaptiveIntervalFlag && result != nil && result.Latency > 0 {
+				next := adjustInterval(adaptiveInterval, result.Latency, timeout, *minIntervalFlag, max(*maxBackoffFlag, *intervalFlag))
+				if next != adaptiveInterval {
+					log.Printf("Latency %s for %s, adjusting check interval from %ds to %ds", result.Latency.Round(time.Millisecond), *urlFlag, adaptiveInterval, next)
+					adaptiveInterval = next
+				}
+				nextCheck = applyJitter(time.Duration(adaptiveInterval)*time.Second, jitterMode)
+			}
+			
+			if consecutiveFailures > 0 {
+				recovery := newEvent(EventRecovery, SeverityInfo, *urlFlag, fmt.Sprintf("recovered after %d failed checks", consecutiveFailures))
+				recovery.ConsecutiveFailures = consecutiveFailures
+				recovery.FinalURL = lastFinalURL
+				recovery.ResolvedIPs = resolvedIPs
+				recovery.Phases = checkPhases(result, nil)
+				notifications = notifyAll(notifiers, recovery)
+			}
+			
+			// Lift the Alertmanager silence, including one left open by an earlier run
+			if alertSilencer != nil {
+				alertSilencer.expire()
+			}
+			
+			// Reset backoff once the site has been continuously up long enough
+			consecutiveFailures = 0
+			if continuousUpSince.IsZero() {
+				continuousUpSince = time.Now()
+			}
+			if currentBackoff != *initialBackoffFlag && time.Since(continuousUpSince) >= backoffResetAfter {
+				if backoffResetAfter > 0 {
+					log.Printf("Website %s has been up for %s, resetting backoff", *urlFlag, time.Since(continuousUpSince).Round(time.Second))
+				}
+				currentBackoff = *initialBackoffFlag
+			}
+		}
+		
+		// Alert once when the failure rate goes over the limit, even if backoff
+		// or the per-outage ELF limit has held back the individual failures
+		if failureRate != nil {
+			failureRate.record(time.Now(), checkFailed)
+			if rate, failures, ok := failureRate.rate(); ok {
+				if rate > failureRateLimit {
+					if !failureRateExceeded {
+						message := fmt.Sprintf("%d of the last %d checks failed (%.2f/min, limit %.2f/min)", failures, *failureRateWindowFlag, rate, failureRateLimit)
+						log.Printf("WARNING: Failure rate for %s is too high: %s", *urlFlag, message)
+						log.Printf("Executing ELF binary...")
+						elfs.run(*elfPathFlag, newEvent(EventFailureRate, SeverityWarning, *urlFlag, message), nil)
+					}
+					failureRateExceeded = true
+				} else {
+					if failureRateExceeded {
+						log.Printf("Failure rate for %s is back below %.2f/min (%.2f/min)", *urlFlag, failureRateLimit, rate)
+					}
+					failureRateExceeded = false
+				}
+			}
+		}
+		
+		// Alert once when real users are getting server errors, whatever the active check found
+		if serverLog != nil {
+			if err := serverLog.check(*verboseFlag); err != nil {
+				if !serverLogErrors {
+					log.Printf("WARNING: Server log %s: %v", *tailLogFlag, err)
+					log.Printf("Executing ELF binary...")
+					elfs.run(*elfPathFlag, newEvent(EventServerErrors, SeverityWarning, *urlFlag, err.Error()), nil)
+				}
+				serverLogErrors = true
+			} else {
+				if serverLogErrors {
+					log.Printf("5xx rate in server log %s is back below %.1f%%", *tailLogFlag, *errorRateThresholdFlag)
+				}
+				serverLogErrors = false
+			}
+		}
+		
+		status.record(*urlFlag, result, checkErr, consecutiveFailures, nextCheck)
+		if alive != nil {
+			// Only the first failure of an outage raises a down event
+			alive.recordCheck(checkErr != nil && consecutiveFailures == 1)
+		}
+		
+		// Push the outcome for runs too short-lived to be scraped
+		if *pushgatewayAddrFlag != "" {
+			if err := pushMetrics(*pushgatewayAddrFlag, *urlFlag, result, checkErr, consecutiveFailures, timeout); err != nil {
+				log.Printf("Failed to push metrics to %s: %v", *pushgatewayAddrFlag, err)
+			} else if *verboseFlag {
+				log.Printf("Pushed metrics to %s", *pushgatewayAddrFlag)
+			}
+		}
+		
+		if cloudWatch != nil {
+			cloudWatch.publish(*urlFlag, result, checkErr, consecutiveFailures)
+		}
+		
+		if *onceFlag {
+			// Let background publishing finish before exiting
+			if cloudWatch != nil {
+				cloudWatch.wait()
+			}
+			if notifications != nil {
+				notifications.Wait()
+			}
+			if checkErr != nil {
+				lock.release()
+				os.Exit(1)
+			}
+			return
+		}
+		
+		// Wait for the normal check interval, or the backoff after repeated failures
+		if cycleWatchdog != nil {
+			cycleWatchdog.end()
+		}
+		cycleLock.Unlock()
+		select {
+		case <-time.After(nextCheck):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkOptions controls how checkWebsiteDown checks a URL
+type checkOptions struct {
+	retries int
+	verbose bool
+	
+	// certPin is the expected base64 SHA-256 digest of the leaf certificate's SPKI
+	certPin string
+	
+	// allowedCiphers, if set, are the cipher suites the connection may negotiate
+	allowedCiphers map[uint16]bool
+	
+	// minTLSVersion, if set, is the oldest TLS version the TLS check accepts
+	minTLSVersion uint16
+	
+	// decompress asks for a compressed response and decompresses the body before it is checked
+	decompress bool
+	
+	// readBody makes checkWebsiteDown read the response body into the result
+	readBody bool
+	
+	// ebpfLatency also times the response in the kernel with an eBPF socket filter
+	ebpfLatency bool
+	
+	// bustCache bypasses CDN caches with a unique query parameter and no-cache headers
+	bustCache bool
+	
+	// retry selects which failures are worth another attempt
+	retry retryPolicy
+	
+	// method, body and contentType describe the request; the default is a plain GET
+	method      string
+	body        []byte
+	contentType string
+}
+
+// maxBodySize limits how much of a response body is read into memory
+const maxBodySize = 10 << 20
+
+// checkResult describes the response to the last successful check attempt
+type checkResult struct {
+	StatusCode int
+	Header     http.Header
+	TLS        *tls.ConnectionState
+	Body       []byte
+	
+	// Latency is the time until the response headers arrived
+	Latency time.Duration
+	
+	// KernelLatency is the time from the request being written until the response
+	// reached the kernel, if measured with -ebpf-latency
+	KernelLatency time.Duration
+	
+	// Redirects is the chain of responses that led to this one, ending with it
+	Redirects []redirectHop
+	
+	// Phases are the spans of the attempt that returned the response
+	Phases []phaseSpan
+}
+
+// finalURL returns the URL the result was served from after any redirects
+func (r *checkResult) finalURL() string {
+	if r == nil || len(r.Redirects) == 0 {
+		return ""
+	}
+	return r.Redirects[len(r.Redirects)-1].URL
+}
+
+// checkWebsiteDown checks if a website is down by making HTTP requests
+// Returns a non-nil error if the website is considered down, otherwise the response
+func checkWebsiteDown(ctx context.Context, url string, client *http.Client, opts checkOptions) (*checkResult, error) {
+	var lastErr error
+	var lastPhases []phaseSpan
+	for i := 0; i < opts.retries; i++ {
+		// Wait a little between attempts
+		if i > 0 {
+			select {
+			case <-time.After(2 * time.Second):
+			case <-ctx.Done():
+				return nil, attemptError(url, i, opts.retries, ctx.Err())
+			}
+		}
+		
+		result, phases, retry, err := checkAttempt(ctx, url, i, client, opts)
+		if err == nil {
+			return result, nil
+		}
+		lastErr, lastPhases = err, phases
+		if !retry {
+			break
+		}
+	}
+	
+	if lastErr == nil {
+		lastErr = errors.New("no check attempts were made")
+	}
+	return nil, withPhases(lastErr, lastPhases) // Website is down after all retries failed
+}
+
+// checkAttempt makes attempt i of a check of url
+// On failure it returns the spans of the attempt, if it got far enough to
+// record them, and whether the check may be retried
+func checkAttempt(ctx context.Context, url string, i int, client *http.Client, opts checkOptions) (*checkResult, []phaseSpan, bool, error) {
+	// Each attempt has its own deadline, and stops early if ctx is cancelled
+	// Cancelling it when the attempt returns releases its timer and connection
+	attemptCtx, cancel := attemptContext(ctx, client.Timeout)
+	defer cancel()
+	
+	// Time each phase of the attempt
+	phases, attemptCtx := newPhaseRecorder(attemptCtx)
+	req, err := newCheckRequest(attemptCtx, url, opts)
+	if err != nil {
+		return nil, nil, false, attemptError(url, i, opts.retries, err)
+	}
+	if opts.body != nil && opts.verbose {
+		log.Printf("Sending %s %s with a %d-byte body (attempt %d/%d)", req.Method, req.URL, len(opts.body), i+1, opts.retries)
+	}
+	
+	// Follow the request to its connection so the kernel can time the response
+	var probe *ebpfProbe
+	if opts.ebpfLatency {
+		if probe, err = newEBPFProbe(); err != nil {
+			log.Printf("eBPF latency measurement failed: %v", err)
+			probe = nil
+		} else {
+			defer probe.close()
+			trace := &httptrace.ClientTrace{
+				GotConn: func(info httptrace.GotConnInfo) {
+					if err := probe.attach(info.Conn); err != nil {
+						log.Printf("eBPF latency measurement failed: %v", err)
+					}
+				},
+				WroteRequest: func(httptrace.WroteRequestInfo) { probe.requestWritten() },
+			}
+			req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+		}
+	}
+	
+	start := time.Now()
+	resp, err := client.Do(req)
+	latency := time.Since(start)
+	var kernelLatency, appLatency time.Duration
+	var probeErr error
+	if probe != nil {
+		kernelLatency, appLatency, probeErr = probe.measure()
+	}
+	
+	if err != nil {
+		spans := phases.finish()
+		if opts.verbose {
+			log.Printf("Request failed (attempt %d/%d): %v", i+1, opts.retries, err)
+			logPhases(url, i, opts.retries, spans)
+		}
+		return nil, spans, ctx.Err() == nil && opts.retry.retryError(err), attemptError(url, i, opts.retries, err)
+	}
+	
+	defer resp.Body.Close()
+	
+	// A pin mismatch will not fix itself on retry
+	if opts.certPin != "" {
+		if err := verifyCertPin(resp.TLS, opts.certPin); err != nil {
+			log.Printf("WARNING: %s: %v", url, err)
+			return nil, nil, false, attemptError(url, i, opts.retries, err)
+		}
+	}
+	if opts.allowedCiphers != nil {
+		if err := verifyCipherSuite(resp.TLS, opts.allowedCiphers); err != nil {
+			log.Printf("WARNING: %s: %v", url, err)
+			return nil, nil, false, attemptError(url, i, opts.retries, err)
+		}
+	}
+	
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		spans := phases.finish()
+		if opts.verbose {
+			log.Printf("Bad status code (attempt %d/%d): %d", i+1, opts.retries, resp.StatusCode)
+			logPhases(url, i, opts.retries, spans)
+		}
+		err := withReason(classifyError(nil, resp), fmt.Errorf("bad status code %d", resp.StatusCode))
+		return nil, spans, opts.retry.retryStatus(resp.StatusCode), attemptError(url, i, opts.retries, err)
+	}
+	
+	// If we get here, the website is up
+	result := &checkResult{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		TLS:        resp.TLS,
+		Latency:    latency,
+		Redirects:  redirectChain(resp),
+	}
+	if probe != nil {
+		if probeErr != nil {
+			log.Printf("eBPF latency measurement failed: %v", probeErr)
+		} else {
+			result.KernelLatency = kernelLatency
+			log.Printf("Response latency for %s: kernel %s, Go %s, Go runtime overhead %s", url, kernelLatency, appLatency, appLatency-kernelLatency)
+		}
+	}
+	if opts.readBody {
+		if opts.decompress {
+			result.Body, err = readDecodedBody(resp.Body, resp.Header.Get("Content-Encoding"))
+		} else {
+			result.Body, err = io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+		}
+		if err != nil {
+			if opts.verbose {
+				log.Printf("Reading body failed (attempt %d/%d): %v", i+1, opts.retries, err)
+			}
+			return nil, phases.finish(), true, attemptError(url, i, opts.retries, fmt.Errorf("reading response body: %w", err))
+		}
+	}
+	result.Phases = phases.finish()
+	if opts.verbose {
+		logPhases(url, i, opts.retries, result.Phases)
+	}
+	return result, result.Phases, false, nil
+}
+
+// newCheckRequest builds the request a check of url sends, with the method,
+// body and headers set by opts
+func newCheckRequest(ctx context.Context, url string, opts checkOptions) (*http.Request, error) {
+	requestURL := url
+	if opts.bustCache {
+		requestURL = cacheBustURL(url)
+	}
+	method := opts.method
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if opts.body != nil {
+		body = bytes.NewReader(opts.body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if opts.contentType != "" {
+		req.Header.Set("Content-Type", opts.contentType)
+	}
+	if opts.bustCache {
+		setNoCacheHeaders(req)
+	}
+	// Setting Accept-Encoding stops the transport decompressing gzip itself
+	if opts.decompress {
+		req.Header.Set("Accept-Encoding", decompressEncodings)
+	}
+	return req, nil
+}
+
+// attemptContext returns the context of one check attempt, with timeout as its
+// deadline unless it is zero
+func attemptContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// attemptError wraps err from attempt i of a check of url with that context
+// The chain is kept so errors.Is and errors.As still see the cause
+func attemptError(url string, i, retries int, err error) error {
+	return fmt.Errorf("checking %s (attempt %d/%d): %w", url, i+1, retries, err)
+}
+
+// verifyCertPin compares the SPKI digest of the leaf certificate against pin
+func verifyCertPin(state *tls.ConnectionState, pin string) error {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return withReason(FailureCertificatePinMismatch, errors.New("certificate pin mismatch: no TLS certificate presented"))
+	}
+	
+	digest := sha256.Sum256(state.PeerCertificates[0].RawSubjectPublicKeyInfo)
+	actual := base64.StdEncoding.EncodeToString(digest[:])
+	if actual != pin {
+		return withReason(FailureCertificatePinMismatch, fmt.Errorf("certificate pin mismatch: got %s", actual))
+	}
+	return nil
+}
+
+// errHTTPDowngrade is returned from the redirect policy when an HTTPS URL
+// redirects to plain HTTP and -fail-on-http-downgrade is set
+var errHTTPDowngrade = errors.New("redirect downgraded HTTPS to HTTP")
+
+// checkRedirect returns a redirect policy that detects HTTPS to HTTP downgrades
+// It keeps the default limit of 10 redirects and logs each hop in verbose mode
+func checkRedirect(failOnDowngrade, verbose bool) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		if verbose && req.Response != nil {
+			log.Printf("Redirect %d: %s -> %s (%d)", len(via), via[len(via)-1].URL, req.URL, req.Response.StatusCode)
+		}
+		
+		original := via[0].URL
+		if original.Scheme == "https" && req.URL.Scheme == "http" {
+			log.Printf("WARNING: %s redirected to insecure URL %s (HTTPS downgraded to HTTP)", original, req.URL)
+			if failOnDowngrade {
+				return errHTTPDowngrade
+			}
+		}
+		
+		return nil
+	}
+}
+
+// loadK8sSettings applies flag values from a Kubernetes ConfigMap and Secret
+// It re-reads them on SIGHUP and delivers the new values on the returned channel,
+// so they can be applied between check cycles. Settings derived at startup,
+// such as the HTTP client timeout, only change after a restart.
+func loadK8sSettings(configMap, secret string, timeout time.Duration, commandLine map[string]bool) chan map[string]string {
+	source, err := newK8sConfigSource(configMap, secret)
+	if err != nil {
+		log.Fatalf("Error: Cannot connect to Kubernetes: %v", err)
+	}
+	
+	settings, err := source.load(timeout)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	applyK8sSettings(settings, commandLine)
+	
+	updates := make(chan map[string]string, 1)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			settings, err := source.load(timeout)
+			if err != nil {
+				log.Printf("Failed to reload Kubernetes config: %v", err)
+				continue
+			}
+			
+			// Replace any update that has not been applied yet
+			select {
+			case <-updates:
+			default:
+			}
+			updates <- settings
+		}
+	}()
+	return updates
+}
+
+// applyK8sSettings sets flags from Kubernetes settings, logging the names applied
+// Secret values are never logged
+func applyK8sSettings(settings map[string]string, commandLine map[string]bool) {
+	applied, err := applySettings(settings, commandLine)
+	if err != nil {
+		log.Printf("Warning: Kubernetes config: %v", err)
+	}
+	if len(applied) > 0 {
+		log.Printf("Applied Kubernetes config settings: %s", strings.Join(applied, ", "))
+	}
+}
+
+// observeDNS resolves the tracked hostname and reports changes to its addresses
+// A change before the previous answer's TTL expired is logged as unexpected
+// Returns the new answer, or nil if the lookup failed
+func observeDNS(tracker *dnsTracker, url string, timeout time.Duration, elfPath string, verbose bool) *dnsAnswer {
+	answer, err := resolveWithTTL(tracker.host, timeout)
+	if err != nil {
+		log.Printf("DNS lookup for %s failed: %v", tracker.host, err)
+		return nil
+	}
+	
+	prev := tracker.last
+	changed, afterTTL := tracker.observe(answer)
+	if verbose {
+		log.Printf("%s resolves to %s (TTL %s)", tracker.host, strings.Join(answer.addrs, ", "), answer.ttl)
+	}
+	if !changed {
+		return answer
+	}
+	
+	message := fmt.Sprintf("%s changed from %s to %s", tracker.host, strings.Join(prev.addrs, ", "), strings.Join(answer.addrs, ", "))
+	if afterTTL {
+		log.Printf("DNS answer %s after TTL expiry", message)
+	} else {
+		message += fmt.Sprintf(" %s before TTL expiry", prev.expires().Sub(answer.resolvedAt).Round(time.Second))
+		log.Printf("WARNING: Unexpected DNS change: %s", message)
+	}
+	
+	if elfPath != "" {
+		executeELF(elfPath, newEvent(EventDNSChange, SeverityWarning, url, message))
+	}
+	return answer
+}
+
+// newHTTPClient creates an HTTP client, optionally bound to a local source address
+func newHTTPClient(timeout time.Duration, localIP net.IP, redirectPolicy func(*http.Request, []*http.Request) error) *http.Client {
+	client := &http.Client{
+		Timeout:       timeout,
+		CheckRedirect: redirectPolicy,
+	}
+	
+	if localIP != nil {
+		dialer := &net.Dialer{
+			LocalAddr: &net.TCPAddr{IP: localIP},
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = dialer.DialContext
+		client.Transport = transport
+	}
+	
+	return client
+}
+
+// executeELF runs the specified ELF binary
+// Details of the event are passed in WEBSITECHECK_* environment variables
+// Returns the exit code, or -1 if the binary could not be run, and its stderr output
+func executeELF(elfPath string, event Event) (int, string) {
+	// Other systems can suppress alerts for a URL through POST /suppress
+	if until, ok := alertSuppressions.active(event.URL); ok {
+		log.Printf("ELF execution for %s suppressed until %s", event.URL, until.Format(time.RFC3339))
+		return 0, ""
+	}
+	
+	cmd := exec.Command(elfPath)
+	cmd.Env = event.environ()
+	
+	// Capture output
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	
+	// Log the output
+	if stdout.Len() > 0 {
+		fmt.Println("ELF binary output:")
+		fmt.Println(stdout.String())
+	}
+	if stderr.Len() > 0 {
+		log.Printf("ELF binary stderr: %s", strings.TrimSpace(stderr.String()))
+	}
+	
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), stderr.String()
+		}
+		log.Printf("Failed to execute ELF binary: %v", err)
+		return -1, stderr.String()
+	}
+	
+	return 0, stderr.String()
 }
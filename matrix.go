@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reachability verdicts combining the local result with peer results
+const (
+	ReachabilityUp             = "up"
+	ReachabilityRegionallyDown = "regionally down"
+	ReachabilityGloballyDown   = "globally down"
+)
+
+// peerStatus is the last status fetched from a peer instance
+type peerStatus struct {
+	Peer    string      `json:"peer"`
+	Fetched time.Time   `json:"fetched,omitempty"`
+	Error   string      `json:"error,omitempty"`
+	Checks  []urlStatus `json:"checks,omitempty"`
+}
+
+// matrixEntry is the reachability of one URL from this instance and its peers
+type matrixEntry struct {
+	URL      string          `json:"url"`
+	Verdict  string          `json:"verdict"`
+	Up       map[string]bool `json:"up"`
+	DownFrom []string        `json:"down_from,omitempty"`
+}
+
+// peerPoller periodically fetches GET /status from peer instances
+type peerPoller struct {
+	peers  []string
+	client *http.Client
+
+	mu      sync.RWMutex
+	results map[string]peerStatus
+}
+
+// parsePeers parses a comma-separated list of peer API addresses
+// Addresses without a scheme are assumed to be plain HTTP
+func parsePeers(list string) []string {
+	var peers []string
+	for _, peer := range strings.Split(list, ",") {
+		peer = strings.TrimSpace(peer)
+		if peer == "" {
+			continue
+		}
+		if !strings.Contains(peer, "://") {
+			peer = "http://" + peer
+		}
+		peers = append(peers, strings.TrimSuffix(peer, "/"))
+	}
+	return peers
+}
+
+// newPeerPoller creates a poller for peers
+func newPeerPoller(peers []string, timeout time.Duration) *peerPoller {
+	return &peerPoller{
+		peers:   peers,
+		client:  &http.Client{Timeout: timeout},
+		results: make(map[string]peerStatus),
+	}
+}
+
+// run polls every peer each interval until the process exits
+func (p *peerPoller) run(interval time.Duration) {
+	for {
+		for _, peer := range p.peers {
+			status := p.fetch(peer)
+			if status.Error != "" {
+				log.Printf("Failed to poll matrix peer %s: %s", peer, status.Error)
+			}
+			p.mu.Lock()
+			p.results[peer] = status
+			p.mu.Unlock()
+		}
+		time.Sleep(interval)
+	}
+}
+
+// fetch reads the local checks reported by a peer
+func (p *peerPoller) fetch(peer string) peerStatus {
+	status := peerStatus{Peer: peer}
+
+	resp, err := p.client.Get(peer + "/status")
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		status.Error = fmt.Sprintf("unexpected status %s", resp.Status)
+		return status
+	}
+
+	var report statusReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		status.Error = fmt.Sprintf("decoding status: %v", err)
+		return status
+	}
+	status.Fetched = time.Now().UTC()
+	status.Checks = report.Checks
+	return status
+}
+
+// snapshot returns the latest result from every peer
+func (p *peerPoller) snapshot() []peerStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	statuses := make([]peerStatus, 0, len(p.peers))
+	for _, peer := range p.peers {
+		if status, ok := p.results[peer]; ok {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// buildMatrix combines local and peer results into a verdict per URL
+// Peers that could not be reached do not count towards the verdict
+func buildMatrix(local []urlStatus, peers []peerStatus) []matrixEntry {
+	entries := make(map[string]*matrixEntry)
+	observe := func(source string, status urlStatus) {
+		entry, ok := entries[status.URL]
+		if !ok {
+			entry = &matrixEntry{URL: status.URL, Up: make(map[string]bool)}
+			entries[status.URL] = entry
+		}
+		entry.Up[source] = status.Up
+	}
+
+	for _, status := range local {
+		observe("local", status)
+	}
+	for _, peer := range peers {
+		for _, status := range peer.Checks {
+			observe(peer.Peer, status)
+		}
+	}
+
+	matrix := make([]matrixEntry, 0, len(entries))
+	for _, entry := range entries {
+		for source, up := range entry.Up {
+			if !up {
+				entry.DownFrom = append(entry.DownFrom, source)
+			}
+		}
+		sort.Strings(entry.DownFrom)
+
+		switch len(entry.DownFrom) {
+		case 0:
+			entry.Verdict = ReachabilityUp
+		case len(entry.Up):
+			entry.Verdict = ReachabilityGloballyDown
+		default:
+			entry.Verdict = ReachabilityRegionallyDown
+		}
+		matrix = append(matrix, *entry)
+	}
+	sort.Slice(matrix, func(i, j int) bool { return matrix[i].URL < matrix[j].URL })
+	return matrix
+}
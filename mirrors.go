@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// mirror is an alternative URL serving the same content as the primary URL
+type mirror struct {
+	url    string
+	weight int
+
+	// current is the running weight used by smooth weighted round-robin
+	current int
+}
+
+// mirrorPool selects mirrors using smooth weighted round-robin
+type mirrorPool struct {
+	mu      sync.Mutex
+	mirrors []*mirror
+}
+
+// parseMirrors parses a comma-separated list of mirror URLs with optional
+// ":weight" suffixes after an explicit port, e.g.
+// "primary.example.com:443:3,secondary.example.com:8443". A single number
+// after the host is always the port, so a mirror is only weighted when its port
+// is given. URLs without a scheme inherit defaultScheme.
+func parseMirrors(list, defaultScheme string) (*mirrorPool, error) {
+	pool := &mirrorPool{}
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		weight := 1
+		if i := strings.LastIndex(entry, ":"); i >= 0 && mirrorHasPort(entry[:i], defaultScheme) {
+			if w, err := strconv.Atoi(entry[i+1:]); err == nil {
+				if w < 1 {
+					return nil, fmt.Errorf("mirror %q: weight must be at least 1", entry)
+				}
+				weight = w
+				entry = entry[:i]
+			}
+		}
+
+		if !strings.Contains(entry, "://") {
+			entry = defaultScheme + "://" + entry
+		}
+		if _, err := url.ParseRequestURI(entry); err != nil {
+			return nil, fmt.Errorf("mirror %q: %v", entry, err)
+		}
+		normalized, err := normalizeURL(entry)
+		if err != nil {
+			return nil, fmt.Errorf("mirror %q: %v", entry, err)
+		}
+		if seen[normalized] {
+			log.Printf("Warning: ignoring duplicate mirror %s", normalized)
+			continue
+		}
+		seen[normalized] = true
+
+		pool.mirrors = append(pool.mirrors, &mirror{url: normalized, weight: weight})
+	}
+	return pool, nil
+}
+
+// mirrorHasPort reports whether a mirror entry names its port, so that a
+// number after it can be read as the weight
+func mirrorHasPort(entry, defaultScheme string) bool {
+	if !strings.Contains(entry, "://") {
+		entry = defaultScheme + "://" + entry
+	}
+	u, err := url.Parse(entry)
+	return err == nil && u.Port() != ""
+}
+
+// order returns the mirrors in the order they should be tried. The first
+// mirror is picked by smooth weighted round-robin so repeated outages spread
+// load across mirrors; the rest follow in descending weight order.
+func (p *mirrorPool) order() []*mirror {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.mirrors) == 0 {
+		return nil
+	}
+
+	total := 0
+	var best *mirror
+	for _, m := range p.mirrors {
+		m.current += m.weight
+		total += m.weight
+		if best == nil || m.current > best.current {
+			best = m
+		}
+	}
+	best.current -= total
+
+	ordered := []*mirror{best}
+	rest := make([]*mirror, 0, len(p.mirrors)-1)
+	for _, m := range p.mirrors {
+		if m != best {
+			rest = append(rest, m)
+		}
+	}
+	sort.SliceStable(rest, func(i, j int) bool { return rest[i].weight > rest[j].weight })
+	return append(ordered, rest...)
+}
+
+// firstUpMirror checks the mirrors in weighted order and returns the first one that is up
+// Returns an empty string if every mirror is down
+// Mirrors usually serve their own certificates so the primary's pin is not applied
+func (p *mirrorPool) firstUpMirror(ctx context.Context, client *http.Client, opts checkOptions) string {
+	opts.certPin = ""
+	for _, m := range p.order() {
+		if _, err := checkWebsiteDown(ctx, m.url, client, opts); err == nil {
+			return m.url
+		}
+		if opts.verbose {
+			log.Printf("Mirror %s is also DOWN", m.url)
+		}
+	}
+	return ""
+}
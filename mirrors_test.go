@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestParseMirrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    string
+		urls    []string
+		weights []int
+	}{
+		{"host", "mirror.example.com", []string{"https://mirror.example.com/"}, []int{1}},
+		{"host and port", "mirror.example.com:8443", []string{"https://mirror.example.com:8443/"}, []int{1}},
+		{"host, port and weight", "mirror.example.com:8443:3", []string{"https://mirror.example.com:8443/"}, []int{3}},
+		{"url with port and weight", "http://mirror.example.com:8080/health:2", []string{"http://mirror.example.com:8080/health"}, []int{2}},
+		{"several", "a.example.com:443:3, b.example.com:8443", []string{"https://a.example.com:443/", "https://b.example.com:8443/"}, []int{3, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, err := parseMirrors(tt.list, "https")
+			if err != nil {
+				t.Fatalf("parseMirrors(%q): %v", tt.list, err)
+			}
+			if len(pool.mirrors) != len(tt.urls) {
+				t.Fatalf("parseMirrors(%q) returned %d mirrors, want %d", tt.list, len(pool.mirrors), len(tt.urls))
+			}
+			for i, m := range pool.mirrors {
+				if m.url != tt.urls[i] || m.weight != tt.weights[i] {
+					t.Errorf("mirror %d = %s weight %d, want %s weight %d", i, m.url, m.weight, tt.urls[i], tt.weights[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseMirrorsInvalidWeight(t *testing.T) {
+	if _, err := parseMirrors("mirror.example.com:443:0", "https"); err == nil {
+		t.Error("parseMirrors accepted a weight of 0")
+	}
+}
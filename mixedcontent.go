@@ -0,0 +1,21 @@
+package main
+
+import "regexp"
+
+// mixedContentPattern finds src=, href= and url() references to plain HTTP URLs
+// A regular expression is enough here and avoids an HTML parser dependency
+var mixedContentPattern = regexp.MustCompile(`(?i)(?:\b(?:src|href)\s*=\s*["']?|\burl\(\s*["']?)(http://[^\s"'()<>]+)`)
+
+// findMixedContent returns each distinct http:// reference in body, in order
+func findMixedContent(body []byte) []string {
+	var refs []string
+	seen := make(map[string]bool)
+	for _, match := range mixedContentPattern.FindAllSubmatch(body, -1) {
+		ref := string(match[1])
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
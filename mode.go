@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Mode selects how the target is checked
+type Mode string
+
+// Supported check modes
+const (
+	ModeHTTP      Mode = "http"
+	ModeGraphQL   Mode = "graphql"
+	ModeTCP       Mode = "tcp"
+	ModeCompound  Mode = "compound"
+	ModeICMP      Mode = "icmp"
+	ModeGRPC      Mode = "grpc"
+	ModeWebSocket Mode = "websocket"
+	ModeDNS       Mode = "dns"
+)
+
+// parseMode validates a -mode value
+func parseMode(s string) (Mode, error) {
+	switch mode := Mode(s); mode {
+	case ModeHTTP, ModeGraphQL, ModeTCP, ModeCompound, ModeICMP, ModeGRPC, ModeWebSocket, ModeDNS:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown mode %q (expected http, graphql, tcp, compound, icmp, grpc, websocket or dns)", s)
+	}
+}
+
+// inferMode returns the check mode for rawURL's scheme and the target that mode checks:
+// host:port for tcp:// and grpc://, the host for icmp:// and dns://, and an
+// http or https URL for ws:// and wss://. HTTP URLs are returned unchanged.
+func inferMode(rawURL string) (Mode, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", err
+	}
+	if u.Host == "" {
+		return "", "", fmt.Errorf("missing host in %s", rawURL)
+	}
+
+	switch scheme := strings.ToLower(u.Scheme); scheme {
+	case "http", "https":
+		return ModeHTTP, rawURL, nil
+	case "tcp", "grpc":
+		if u.Port() == "" {
+			return "", "", fmt.Errorf("%s:// URLs need a port, got %s", scheme, rawURL)
+		}
+		return Mode(scheme), u.Host, nil
+	case "icmp", "dns":
+		if u.Port() != "" || (u.Path != "" && u.Path != "/") {
+			return "", "", fmt.Errorf("%s:// URLs take only a host, got %s", scheme, rawURL)
+		}
+		return Mode(scheme), u.Hostname(), nil
+	case "ws", "wss":
+		u.Scheme = "http" + strings.TrimPrefix(scheme, "ws")
+		return ModeWebSocket, u.String(), nil
+	default:
+		return "", "", fmt.Errorf("unsupported scheme %q (expected http, https, tcp, icmp, grpc, ws, wss or dns)", u.Scheme)
+	}
+}
+
+// modeURLs are the URLs each non-HTTP mode checks, for error messages
+var modeURLs = map[Mode]string{
+	ModeTCP:       "tcp://host:port",
+	ModeICMP:      "icmp://host",
+	ModeGRPC:      "grpc://host:port",
+	ModeWebSocket: "ws:// or wss://",
+	ModeDNS:       "dns://host",
+}
+
+// resolveMode combines the -mode flag with the mode inferred from rawURL
+// The default http mode gives way to the URL's scheme; any other mode must agree with it
+func resolveMode(flagMode Mode, rawURL string) (Mode, string, error) {
+	inferred, target, err := inferMode(rawURL)
+	if err != nil {
+		return "", "", err
+	}
+	if inferred == ModeHTTP {
+		if want, ok := modeURLs[flagMode]; ok {
+			return "", "", fmt.Errorf("%s mode requires a %s URL, got %s", flagMode, want, rawURL)
+		}
+		return flagMode, target, nil
+	}
+	if flagMode != ModeHTTP && flagMode != inferred {
+		return "", "", fmt.Errorf("%s URLs cannot be checked in %s mode", inferred, flagMode)
+	}
+	return inferred, target, nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"log"
+	"sync"
+)
+
+// Notifier delivers down and recovery events to an external system
+type Notifier interface {
+	Name() string
+	Notify(event Event) error
+}
+
+// notifyAll sends event to every notifier in the background so a slow or
+// unreachable system never holds up the monitoring loop. Failures are logged.
+// The returned WaitGroup completes when every notifier has finished.
+func notifyAll(notifiers []Notifier, event Event) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	for _, n := range notifiers {
+		wg.Add(1)
+		go func(n Notifier) {
+			defer wg.Done()
+			if err := n.Notify(event); err != nil {
+				log.Printf("Failed to send %s notification via %s: %v", event.Type, n.Name(), err)
+			}
+		}(n)
+	}
+	return &wg
+}
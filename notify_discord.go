@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Embed colors for Discord messages
+const (
+	discordColorDown     = 0xFF0000
+	discordColorRecovery = 0x00FF00
+)
+
+// discordRateLimitRetries is how many times a rate limited message is resent
+// after waiting the retry_after Discord asks for
+const discordRateLimitRetries = 3
+
+// discordEmbedField is a name/value pair shown in an embed
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// discordEmbed is the rich message posted for each event
+type discordEmbed struct {
+	Title       string              `json:"title"`
+	Description string              `json:"description,omitempty"`
+	Color       int                 `json:"color"`
+	Fields      []discordEmbedField `json:"fields"`
+	Timestamp   string              `json:"timestamp"`
+}
+
+// discordNotifier posts down and recovery events to a Discord webhook as embeds
+type discordNotifier struct {
+	webhook string
+	client  *http.Client
+}
+
+// newDiscordNotifier creates a notifier posting to the webhook URL
+func newDiscordNotifier(webhook string, timeout time.Duration) (*discordNotifier, error) {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return nil, fmt.Errorf("webhook must be an http or https URL, got %q", webhook)
+	}
+	return &discordNotifier{webhook: webhook, client: &http.Client{Timeout: timeout}}, nil
+}
+
+// Name implements Notifier
+func (n *discordNotifier) Name() string {
+	return "Discord"
+}
+
+// Notify implements Notifier
+// A rate limited message is resent after the delay Discord returns
+func (n *discordNotifier) Notify(event Event) error {
+	body, err := json.Marshal(map[string][]discordEmbed{"embeds": {discordEmbedFor(event)}})
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := n.post(body)
+		if err == nil || retryAfter == 0 {
+			return err
+		}
+		if attempt >= discordRateLimitRetries {
+			return fmt.Errorf("still rate limited after %d retries", discordRateLimitRetries)
+		}
+		time.Sleep(retryAfter)
+	}
+}
+
+// post sends one webhook request
+// A rate limited request returns the delay to wait before retrying along with the error
+func (n *discordNotifier) post(body []byte) (time.Duration, error) {
+	resp, err := n.client.Post(n.webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The webhook URL contains its token, so only the underlying error is returned
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		var limited struct {
+			RetryAfter float64 `json:"retry_after"`
+		}
+		json.Unmarshal(data, &limited)
+		retryAfter := time.Duration(limited.RetryAfter * float64(time.Second))
+		if retryAfter <= 0 {
+			retryAfter = time.Second
+		}
+		return retryAfter, fmt.Errorf("rate limited, retry after %s", retryAfter)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, fmt.Errorf("Discord returned status code %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return 0, nil
+}
+
+// discordEmbedFor builds the embed for event, red while down and green on recovery
+func discordEmbedFor(event Event) discordEmbed {
+	embed := discordEmbed{
+		Title:       "Website down",
+		Description: event.Message,
+		Color:       discordColorDown,
+		Fields:      []discordEmbedField{{Name: "URL", Value: event.URL}},
+		Timestamp:   event.Time.Format(time.RFC3339),
+	}
+	if event.Type == EventRecovery {
+		embed.Title, embed.Color = "Website recovered", discordColorRecovery
+	}
+	if event.FailureReason != "" {
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: "Failure reason", Value: string(event.FailureReason), Inline: true})
+	}
+	if len(event.ResolvedIPs) > 0 {
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: "Resolved IPs", Value: strings.Join(event.ResolvedIPs, ", "), Inline: true})
+	}
+	if event.ConsecutiveFailures > 0 {
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: "Consecutive failures", Value: strconv.Itoa(event.ConsecutiveFailures), Inline: true})
+	}
+	embed.Fields = append(embed.Fields, discordEmbedField{Name: "Timestamp", Value: event.Time.Format(time.RFC3339), Inline: true})
+	return embed
+}
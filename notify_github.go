@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// githubActionsNotifier writes events as GitHub Actions workflow commands so
+// failures show up as annotations on the check run
+type githubActionsNotifier struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// inGitHubActions reports whether the process is running in a GitHub Actions job
+func inGitHubActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// Name implements Notifier
+func (n *githubActionsNotifier) Name() string {
+	return "GitHub Actions annotations"
+}
+
+// Notify implements Notifier
+// Failures are written as error annotations and recoveries as notices
+func (n *githubActionsNotifier) Notify(event Event) error {
+	command, title := "error", "Site Down"
+	switch event.Type {
+	case EventRecovery:
+		command, title = "notice", "Site Recovered"
+	case EventDegraded:
+		command, title = "warning", "Site Degraded"
+	}
+	if event.FailureReason != "" && event.Type != EventRecovery {
+		title += " (" + string(event.FailureReason) + ")"
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, err := fmt.Fprintf(n.out, "::%s title=%s::%s\n", command, escapeWorkflowProperty(title),
+		escapeWorkflowData(event.URL+" "+event.Message))
+	return err
+}
+
+// escapeWorkflowData escapes the message of a workflow command
+func escapeWorkflowData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeWorkflowProperty escapes a property value of a workflow command
+func escapeWorkflowProperty(s string) string {
+	return strings.NewReplacer(":", "%3A", ",", "%2C").Replace(escapeWorkflowData(s))
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// iftttTriggerURL is the IFTTT Webhooks endpoint, filled in with the event name and key
+const iftttTriggerURL = "https://maker.ifttt.com/trigger/%s/with/key/%s"
+
+// iftttNotifier triggers an IFTTT Webhooks event when the website goes down
+// value1 is the URL, value2 the failure reason and value3 the consecutive failure count
+type iftttNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// newIFTTTNotifier creates a notifier triggering event with the Webhooks key
+func newIFTTTNotifier(key, event string, timeout time.Duration) *iftttNotifier {
+	return &iftttNotifier{
+		url:    fmt.Sprintf(iftttTriggerURL, url.PathEscape(event), url.PathEscape(key)),
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Name implements Notifier
+func (n *iftttNotifier) Name() string {
+	return "IFTTT Webhooks"
+}
+
+// Notify implements Notifier
+// Only down events trigger IFTTT; recoveries are ignored
+func (n *iftttNotifier) Notify(event Event) error {
+	if event.Type != EventDown {
+		return nil
+	}
+	body, err := json.Marshal(map[string]string{
+		"value1": event.URL,
+		"value2": string(event.FailureReason),
+		"value3": strconv.Itoa(event.ConsecutiveFailures),
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The request URL contains the key, so only the underlying error is returned
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodySize))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("IFTTT returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
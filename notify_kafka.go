@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// kafkaQueueSize limits the events kept in memory while no broker is reachable
+const kafkaQueueSize = 1000
+
+// Queued events are retried with exponential backoff between these delays
+const (
+	kafkaMinRetryDelay = time.Second
+	kafkaMaxRetryDelay = time.Minute
+)
+
+// kafkaNotifier publishes events as JSON to a Kafka topic, keyed by URL so
+// every event for a URL lands on the same partition
+// Events that cannot be written are queued and retried in order
+type kafkaNotifier struct {
+	writer  *kafka.Writer
+	topic   string
+	timeout time.Duration
+	buffer  *eventBuffer
+}
+
+// newKafkaNotifier creates a notifier writing to topic on the comma-separated brokers
+// With partitions above 0 the topic is created with that many partitions if it does not exist
+func newKafkaNotifier(brokerList, topic string, partitions int, timeout time.Duration) (*kafkaNotifier, error) {
+	var brokers []string
+	for _, broker := range strings.Split(brokerList, ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			brokers = append(brokers, broker)
+		}
+	}
+	if len(brokers) == 0 || topic == "" {
+		return nil, errors.New("-kafka-brokers and -kafka-topic are both required")
+	}
+	if partitions < 0 {
+		return nil, fmt.Errorf("-kafka-partition-count must not be negative, got %d", partitions)
+	}
+
+	n := &kafkaNotifier{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireOne,
+			MaxAttempts:  1,
+			BatchTimeout: 10 * time.Millisecond,
+			WriteTimeout: timeout,
+		},
+		topic:   topic,
+		timeout: timeout,
+	}
+	n.buffer = newEventBuffer("Kafka topic "+topic, kafkaQueueSize, kafkaMinRetryDelay, kafkaMaxRetryDelay, n.publish)
+	if partitions > 0 {
+		if err := createKafkaTopic(brokers, topic, partitions, timeout); err != nil {
+			log.Printf("WARNING: Cannot create Kafka topic %s: %v", topic, err)
+		}
+	}
+	return n, nil
+}
+
+// createKafkaTopic creates topic with the given partition count through the cluster controller
+// An existing topic is left as it is
+func createKafkaTopic(brokers []string, topic string, partitions int, timeout time.Duration) error {
+	dialer := &kafka.Dialer{Timeout: timeout}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var conn *kafka.Conn
+	var err error
+	for _, broker := range brokers {
+		if conn, err = dialer.DialContext(ctx, "tcp", broker); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	controller, err := conn.Controller()
+	if err != nil {
+		return err
+	}
+	controllerConn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(controller.Host, strconv.Itoa(controller.Port)))
+	if err != nil {
+		return err
+	}
+	defer controllerConn.Close()
+
+	err = controllerConn.CreateTopics(kafka.TopicConfig{Topic: topic, NumPartitions: partitions, ReplicationFactor: 1})
+	if errors.Is(err, kafka.TopicAlreadyExists) {
+		return nil
+	}
+	return err
+}
+
+// Name implements Notifier
+func (n *kafkaNotifier) Name() string {
+	return "Kafka topic " + n.topic
+}
+
+// Notify implements Notifier
+// Queued events are written first so consumers see events in order
+func (n *kafkaNotifier) Notify(event Event) error {
+	return n.buffer.add(event)
+}
+
+// publish writes events in one batch, so either all or none are accepted
+func (n *kafkaNotifier) publish(events []Event) (int, error) {
+	messages := make([]kafka.Message, 0, len(events))
+	for _, event := range events {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return 0, err
+		}
+		messages = append(messages, kafka.Message{Key: []byte(event.URL), Value: value, Time: event.Time})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), n.timeout)
+	defer cancel()
+	if err := n.writer.WriteMessages(ctx, messages...); err != nil {
+		return 0, err
+	}
+	return len(events), nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// mqttMessage is the payload published on each status change
+type mqttMessage struct {
+	URL       string `json:"url"`
+	Status    string `json:"status"`
+	Timestamp string `json:"timestamp"`
+	Failures  int    `json:"failures"`
+}
+
+// mqttNotifier publishes status changes to an MQTT broker
+// The client reconnects on its own, so a broker outage only fails the
+// messages published while it lasts
+type mqttNotifier struct {
+	client mqtt.Client
+
+	// broker is the broker address for log messages, with any password redacted
+	broker  string
+	topic   string
+	qos     byte
+	timeout time.Duration
+}
+
+// newMQTTNotifier connects to broker in the background and publishes to topic
+// %host%, %port% and %path% in topic are replaced from monitoredURL
+func newMQTTNotifier(broker, topic, clientID string, qos int, monitoredURL string, timeout time.Duration) (*mqttNotifier, error) {
+	if qos < 0 || qos > 2 {
+		return nil, fmt.Errorf("QoS must be 0, 1 or 2, got %d", qos)
+	}
+	target, err := url.Parse(monitoredURL)
+	if err != nil {
+		return nil, err
+	}
+	topic = strings.NewReplacer(
+		"%host%", target.Hostname(),
+		"%port%", target.Port(),
+		"%path%", strings.Trim(target.Path, "/"),
+	).Replace(topic)
+	if topic == "" || strings.ContainsAny(topic, "+#") {
+		return nil, fmt.Errorf("invalid topic %q", topic)
+	}
+	if clientID == "" {
+		hostname, _ := os.Hostname()
+		clientID = fmt.Sprintf("websitecheck-%s-%d", hostname, os.Getpid())
+	}
+
+	options := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(clientID).
+		SetConnectTimeout(timeout).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			log.Printf("WARNING: Lost connection to MQTT broker %s: %v", redactURL(broker), err)
+		})
+	if u, err := url.Parse(broker); err == nil && u.User != nil {
+		password, _ := u.User.Password()
+		options.SetUsername(u.User.Username()).SetPassword(password)
+	}
+
+	n := &mqttNotifier{client: mqtt.NewClient(options), broker: redactURL(broker), topic: topic, qos: byte(qos), timeout: timeout}
+	n.client.Connect()
+	return n, nil
+}
+
+// Name implements Notifier
+func (n *mqttNotifier) Name() string {
+	return "MQTT topic " + n.topic
+}
+
+// Notify implements Notifier
+func (n *mqttNotifier) Notify(event Event) error {
+	status := "down"
+	if event.Type == EventRecovery {
+		status = "up"
+	}
+	payload, err := json.Marshal(mqttMessage{
+		URL:       event.URL,
+		Status:    status,
+		Timestamp: event.Time.Format(time.RFC3339),
+		Failures:  event.ConsecutiveFailures,
+	})
+	if err != nil {
+		return err
+	}
+
+	token := n.client.Publish(n.topic, n.qos, false, payload)
+	if !token.WaitTimeout(n.timeout) {
+		return fmt.Errorf("publishing to %s timed out after %s", n.broker, n.timeout)
+	}
+	return token.Error()
+}
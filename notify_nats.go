@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// natsStream and natsDurable name the JetStream stream that stores events and
+// the durable consumer subscribers can bind to with -nats-jetstream
+const (
+	natsStream  = "WEBSITECHECK"
+	natsDurable = "websitecheck"
+)
+
+// natsTokenEscaper replaces the characters that cannot appear in a subject token
+var natsTokenEscaper = strings.NewReplacer(".", "_", " ", "_", "*", "_", ">", "_")
+
+// natsNotifier publishes events as JSON to NATS subjects
+// {tag}, {status} and {host} in the subject are filled in per event; with several
+// -tags the event is published once per tag so wildcard subscriptions can pick tags
+type natsNotifier struct {
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+	subject string
+	tags    []string
+	host    string
+	timeout time.Duration
+
+	// streamReady is set once the JetStream stream is known to exist
+	mu          sync.Mutex
+	streamReady bool
+}
+
+// newNATSNotifier connects to the NATS server at serverURL, reconnecting for as long as the monitor runs
+// With jetStream, events are stored in a stream with a durable consumer and
+// deduplicated by message ID so each one is delivered once
+func newNATSNotifier(serverURL, subject string, jetStream bool, tags []string, monitoredURL string, timeout time.Duration) (*natsNotifier, error) {
+	if subject == "" {
+		return nil, fmt.Errorf("-nats-subject is required")
+	}
+	target, err := url.Parse(monitoredURL)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		tags = []string{"untagged"}
+	}
+
+	conn, err := nats.Connect(serverURL,
+		nats.Name("websitecheck"),
+		nats.Timeout(timeout),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				log.Printf("WARNING: Disconnected from NATS: %v", err)
+			}
+		}),
+		nats.ReconnectHandler(func(c *nats.Conn) {
+			log.Printf("Reconnected to NATS at %s", c.ConnectedUrl())
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	n := &natsNotifier{conn: conn, subject: subject, tags: tags, host: target.Hostname(), timeout: timeout}
+	if jetStream {
+		if n.js, err = conn.JetStream(nats.MaxWait(timeout)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if err := n.ensureStream(); err != nil {
+			log.Printf("WARNING: Cannot set up JetStream stream %s yet: %v", natsStream, err)
+		}
+	}
+	return n, nil
+}
+
+// ensureStream creates the event stream and its durable consumer if they do not exist
+// The stream captures every subject the -nats-subject template can produce
+func (n *natsNotifier) ensureStream() error {
+	wildcard := strings.NewReplacer("{tag}", "*", "{status}", "*", "{host}", "*").Replace(n.subject)
+	_, err := n.js.StreamInfo(natsStream)
+	if err == nats.ErrStreamNotFound {
+		_, err = n.js.AddStream(&nats.StreamConfig{Name: natsStream, Subjects: []string{wildcard}, Duplicates: 10 * time.Minute})
+	}
+	if err != nil {
+		return err
+	}
+	_, err = n.js.ConsumerInfo(natsStream, natsDurable)
+	if err == nats.ErrConsumerNotFound {
+		_, err = n.js.AddConsumer(natsStream, &nats.ConsumerConfig{Durable: natsDurable, AckPolicy: nats.AckExplicitPolicy})
+	}
+	n.streamReady = err == nil
+	return err
+}
+
+// Name implements Notifier
+func (n *natsNotifier) Name() string {
+	if n.js != nil {
+		return "NATS JetStream subject " + n.subject
+	}
+	return "NATS subject " + n.subject
+}
+
+// Notify implements Notifier
+func (n *natsNotifier) Notify(event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	status := "down"
+	if event.Type == EventRecovery {
+		status = "up"
+	}
+	if n.js != nil {
+		n.mu.Lock()
+		var err error
+		if !n.streamReady {
+			err = n.ensureStream()
+		}
+		n.mu.Unlock()
+		if err != nil {
+			return fmt.Errorf("setting up JetStream stream %s: %v", natsStream, err)
+		}
+	}
+
+	for _, tag := range n.tags {
+		subject := strings.NewReplacer(
+			"{tag}", natsTokenEscaper.Replace(tag),
+			"{status}", status,
+			"{host}", natsTokenEscaper.Replace(n.host),
+		).Replace(n.subject)
+
+		if n.js == nil {
+			if err := n.conn.Publish(subject, payload); err != nil {
+				return err
+			}
+			continue
+		}
+		// The message ID lets JetStream drop a retried publish it has already stored
+		id := fmt.Sprintf("%s|%s|%d|%s", event.URL, event.Type, event.Time.UnixNano(), subject)
+		if _, err := n.js.Publish(subject, payload, nats.MsgId(id)); err != nil {
+			return fmt.Errorf("publishing to %s: %v", subject, err)
+		}
+	}
+	if n.js == nil {
+		return n.conn.FlushTimeout(n.timeout)
+	}
+	return nil
+}
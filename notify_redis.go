@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisFlushInterval is how often events buffered while Redis was unreachable are retried
+const redisFlushInterval = 5 * time.Second
+
+// redisNotifier publishes events as JSON to a Redis Pub/Sub channel
+// Events that cannot be published are kept, up to bufferSize of them, and sent
+// in order once Redis is reachable again
+type redisNotifier struct {
+	client  *redis.Client
+	channel string
+	timeout time.Duration
+	buffer  *eventBuffer
+}
+
+// newRedisNotifier creates a notifier publishing to channel on the Redis server at addr
+// addr is host:port or a redis:// URL, which may carry a password and database
+func newRedisNotifier(addr, channel string, bufferSize int, timeout time.Duration) (*redisNotifier, error) {
+	if channel == "" {
+		return nil, fmt.Errorf("-redis-channel is required")
+	}
+	if bufferSize < 1 {
+		return nil, fmt.Errorf("-redis-buffer-size must be positive, got %d", bufferSize)
+	}
+	options := &redis.Options{Addr: addr}
+	if parsed, err := redis.ParseURL(addr); err == nil {
+		options = parsed
+	}
+	options.DialTimeout, options.ReadTimeout, options.WriteTimeout = timeout, timeout, timeout
+
+	n := &redisNotifier{client: redis.NewClient(options), channel: channel, timeout: timeout}
+	n.buffer = newEventBuffer("Redis "+options.Addr, bufferSize, redisFlushInterval, redisFlushInterval, n.publish)
+	return n, nil
+}
+
+// Name implements Notifier
+func (n *redisNotifier) Name() string {
+	return "Redis channel " + n.channel
+}
+
+// Notify implements Notifier
+// Buffered events are published first so subscribers see events in order
+func (n *redisNotifier) Notify(event Event) error {
+	return n.buffer.add(event)
+}
+
+// publish publishes events in order, stopping at the first failure
+func (n *redisNotifier) publish(events []Event) (int, error) {
+	for i, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return i, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), n.timeout)
+		err = n.client.Publish(ctx, n.channel, payload).Err()
+		cancel()
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(events), nil
+}
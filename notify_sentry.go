@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// sentryFlushTimeout limits how long a notification waits for Sentry to accept the event
+const sentryFlushTimeout = 5 * time.Second
+
+// sentryNotifier reports failures and ELF errors to Sentry
+// Events are fingerprinted by URL and failure reason so that repeated failures
+// are grouped into one issue
+type sentryNotifier struct {
+	host string
+}
+
+// newSentryNotifier initializes the Sentry SDK with dsn
+func newSentryNotifier(dsn string) (*sentryNotifier, error) {
+	parsed, err := sentry.NewDsn(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := sentry.Init(sentry.ClientOptions{Dsn: dsn, Release: "websitecheck@" + Version}); err != nil {
+		return nil, err
+	}
+	return &sentryNotifier{host: parsed.GetHost()}, nil
+}
+
+// Name implements Notifier
+func (n *sentryNotifier) Name() string {
+	return "Sentry at " + n.host
+}
+
+// Notify implements Notifier
+// Recoveries are sent as info messages and everything else as errors
+func (n *sentryNotifier) Notify(event Event) error {
+	hub := sentry.CurrentHub().Clone()
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetTag("url", event.URL)
+		scope.SetTag("event", event.Type)
+		scope.SetContext("check", sentry.Context{
+			"url":                  event.URL,
+			"failure_reason":       string(event.FailureReason),
+			"consecutive_failures": event.ConsecutiveFailures,
+			"final_url":            event.FinalURL,
+		})
+	})
+
+	if event.Type == EventRecovery {
+		hub.ConfigureScope(func(scope *sentry.Scope) {
+			scope.SetLevel(sentry.LevelInfo)
+		})
+		hub.CaptureMessage(fmt.Sprintf("%s recovered: %s", event.URL, event.Message))
+	} else {
+		reason := event.FailureReason
+		if reason == "" {
+			reason = FailureUnknown
+		}
+		hub.ConfigureScope(func(scope *sentry.Scope) {
+			scope.SetTag("failure_reason", string(reason))
+			scope.SetFingerprint([]string{event.URL, string(reason)})
+		})
+		hub.CaptureException(errors.New(event.Message))
+	}
+	return n.flush(hub)
+}
+
+// captureELFFailure reports an ELF binary that did not exit cleanly
+func (n *sentryNotifier) captureELFFailure(elfPath string, event Event) error {
+	hub := sentry.CurrentHub().Clone()
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetTag("url", event.URL)
+		scope.SetTag("event", event.Type)
+		scope.SetContext("elf", sentry.Context{
+			"path":      elfPath,
+			"exit_code": event.ELFExitCode,
+			"stderr":    event.ELFStderr,
+		})
+		scope.SetFingerprint([]string{"elf", elfPath, event.URL})
+	})
+	hub.CaptureException(fmt.Errorf("ELF binary %s exited with code %d for %s event", elfPath, event.ELFExitCode, event.Type))
+	return n.flush(hub)
+}
+
+// flush waits for queued events to be sent
+func (n *sentryNotifier) flush(hub *sentry.Hub) error {
+	if !hub.Flush(sentryFlushTimeout) {
+		return fmt.Errorf("timed out after %s", sentryFlushTimeout)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// Standard OIDs included in every trap
+const (
+	oidSysUpTime   = "1.3.6.1.2.1.1.3.0"
+	oidSnmpTrapOID = "1.3.6.1.6.3.1.1.4.1.0"
+	oidSysDescr    = "1.3.6.1.2.1.1.1.0"
+)
+
+// snmpNotifier sends SNMP v2c traps for down and recovery events
+// The URL, status, consecutive failures and failure reason are bound under the
+// trap OID as <oid>.1 to <oid>.4
+type snmpNotifier struct {
+	host      string
+	port      uint16
+	community string
+	trapOID   string
+	started   time.Time
+	timeout   time.Duration
+}
+
+// newSNMPNotifier creates a notifier sending traps to addr (host or host:port, default port 162)
+func newSNMPNotifier(addr, community, trapOID string, timeout time.Duration) (*snmpNotifier, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, portStr = addr, "162"
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SNMP trap port %q", portStr)
+	}
+
+	return &snmpNotifier{
+		host:      host,
+		port:      uint16(port),
+		community: community,
+		trapOID:   trapOID,
+		started:   time.Now(),
+		timeout:   timeout,
+	}, nil
+}
+
+// Name implements Notifier
+func (n *snmpNotifier) Name() string {
+	return "SNMP trap to " + net.JoinHostPort(n.host, strconv.Itoa(int(n.port)))
+}
+
+// Notify implements Notifier
+func (n *snmpNotifier) Notify(event Event) error {
+	// A new session per trap keeps concurrent notifications independent
+	client := &gosnmp.GoSNMP{
+		Target:    n.host,
+		Port:      n.port,
+		Community: n.community,
+		Version:   gosnmp.Version2c,
+		Timeout:   n.timeout,
+	}
+	if err := client.Connect(); err != nil {
+		return err
+	}
+	defer client.Conn.Close()
+
+	status := "down"
+	if event.Type == EventRecovery {
+		status = "up"
+	}
+	uptime := uint32(time.Since(n.started) / (10 * time.Millisecond))
+
+	trap := gosnmp.SnmpTrap{Variables: []gosnmp.SnmpPDU{
+		{Name: oidSysUpTime, Type: gosnmp.TimeTicks, Value: uptime},
+		{Name: oidSnmpTrapOID, Type: gosnmp.ObjectIdentifier, Value: n.trapOID},
+		{Name: oidSysDescr, Type: gosnmp.OctetString, Value: "websitecheck " + Version},
+		{Name: n.trapOID + ".1", Type: gosnmp.OctetString, Value: event.URL},
+		{Name: n.trapOID + ".2", Type: gosnmp.OctetString, Value: status},
+		{Name: n.trapOID + ".3", Type: gosnmp.Integer, Value: event.ConsecutiveFailures},
+		{Name: n.trapOID + ".4", Type: gosnmp.OctetString, Value: string(event.FailureReason)},
+	}}
+	_, err := client.SendTrap(trap)
+	return err
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Theme colors for Teams cards
+const (
+	teamsColorDown     = "FF0000"
+	teamsColorRecovery = "00FF00"
+)
+
+// teamsFact is one name/value row of a card section
+type teamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// teamsSection holds the facts shown on a card
+type teamsSection struct {
+	ActivityTitle string      `json:"activityTitle"`
+	Facts         []teamsFact `json:"facts"`
+}
+
+// teamsTarget is where an OpenUri action links to
+type teamsTarget struct {
+	OS  string `json:"os"`
+	URI string `json:"uri"`
+}
+
+// teamsAction is a button on a card
+type teamsAction struct {
+	Type    string        `json:"@type"`
+	Name    string        `json:"name"`
+	Targets []teamsTarget `json:"targets"`
+}
+
+// teamsCard is the MessageCard schema accepted by Teams incoming webhooks,
+// which unlike Adaptive Cards carries a theme color
+type teamsCard struct {
+	Type            string         `json:"@type"`
+	Context         string         `json:"@context"`
+	Summary         string         `json:"summary"`
+	ThemeColor      string         `json:"themeColor"`
+	Title           string         `json:"title"`
+	Text            string         `json:"text,omitempty"`
+	Sections        []teamsSection `json:"sections"`
+	PotentialAction []teamsAction  `json:"potentialAction,omitempty"`
+}
+
+// teamsNotifier posts down and recovery cards to a Microsoft Teams incoming webhook
+type teamsNotifier struct {
+	webhook    string
+	statusPage string
+	client     *http.Client
+}
+
+// newTeamsNotifier creates a notifier posting to the webhook URL
+// Cards link to statusPage with a View Status button if it is set
+func newTeamsNotifier(webhook, statusPage string, timeout time.Duration) (*teamsNotifier, error) {
+	for _, raw := range []string{webhook, statusPage} {
+		if raw == "" {
+			continue
+		}
+		if u, err := url.Parse(raw); err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+			return nil, fmt.Errorf("%q is not an http or https URL", raw)
+		}
+	}
+	return &teamsNotifier{webhook: webhook, statusPage: statusPage, client: &http.Client{Timeout: timeout}}, nil
+}
+
+// Name implements Notifier
+func (n *teamsNotifier) Name() string {
+	return "Microsoft Teams"
+}
+
+// Notify implements Notifier
+func (n *teamsNotifier) Notify(event Event) error {
+	body, err := json.Marshal(n.card(event))
+	if err != nil {
+		return err
+	}
+	resp, err := n.client.Post(n.webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The webhook URL contains its secret, so only the underlying error is returned
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Teams returned status code %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
+
+// card builds the card for event, red while down and green on recovery
+func (n *teamsNotifier) card(event Event) teamsCard {
+	status, color := "DOWN", teamsColorDown
+	if event.Type == EventRecovery {
+		status, color = "UP", teamsColorRecovery
+	}
+	facts := []teamsFact{{Name: "URL", Value: event.URL}, {Name: "Status", Value: status}}
+	if event.FailureReason != "" {
+		facts = append(facts, teamsFact{Name: "Failure reason", Value: string(event.FailureReason)})
+	}
+	if len(event.ResolvedIPs) > 0 {
+		facts = append(facts, teamsFact{Name: "Resolved IPs", Value: strings.Join(event.ResolvedIPs, ", ")})
+	}
+	if event.ConsecutiveFailures > 0 {
+		facts = append(facts, teamsFact{Name: "Consecutive failures", Value: strconv.Itoa(event.ConsecutiveFailures)})
+	}
+	facts = append(facts, teamsFact{Name: "Time", Value: event.Time.Format(time.RFC3339)})
+
+	card := teamsCard{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		Summary:    event.URL + " is " + status,
+		ThemeColor: color,
+		Title:      event.URL + " is " + status,
+		Text:       event.Message,
+		Sections:   []teamsSection{{ActivityTitle: "websitecheck", Facts: facts}},
+	}
+	if n.statusPage != "" {
+		card.PotentialAction = []teamsAction{{Type: "OpenUri", Name: "View Status", Targets: []teamsTarget{{OS: "default", URI: n.statusPage}}}}
+	}
+	return card
+}
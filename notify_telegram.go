@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// telegramAPIURL is the Bot API sendMessage endpoint, filled in with the bot token
+const telegramAPIURL = "https://api.telegram.org/bot%s/sendMessage"
+
+// Telegram allows one message per second in a chat; failed sends are retried
+// with exponential backoff starting at telegramRetryDelay
+const (
+	telegramChatInterval = time.Second
+	telegramRetries      = 3
+	telegramRetryDelay   = time.Second
+)
+
+// telegramEscaper escapes the characters reserved in MarkdownV2 text
+var telegramEscaper = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`, "~", `\~`, "`", "\\`",
+	">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`, "|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// telegramNotifier sends down and recovery messages to a Telegram chat through a bot
+type telegramNotifier struct {
+	url    string
+	chatID string
+	client *http.Client
+
+	// mu serializes sends so messages to the chat are at least telegramChatInterval apart
+	mu   sync.Mutex
+	last time.Time
+}
+
+// newTelegramNotifier creates a notifier sending to chatID as the bot with token
+func newTelegramNotifier(token, chatID string, timeout time.Duration) *telegramNotifier {
+	return &telegramNotifier{
+		url:    fmt.Sprintf(telegramAPIURL, token),
+		chatID: chatID,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Name implements Notifier
+func (n *telegramNotifier) Name() string {
+	return "Telegram"
+}
+
+// Notify implements Notifier
+func (n *telegramNotifier) Notify(event Event) error {
+	text := telegramMessage(event)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delay := telegramRetryDelay
+	var err error
+	for attempt := 0; attempt <= telegramRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if wait := telegramChatInterval - time.Since(n.last); wait > 0 {
+			time.Sleep(wait)
+		}
+		var retryAfter time.Duration
+		retryAfter, err = n.send(text)
+		n.last = time.Now()
+		if err == nil {
+			return nil
+		}
+		delay = max(delay, retryAfter)
+	}
+	return fmt.Errorf("giving up after %d retries: %v", telegramRetries, err)
+}
+
+// send makes one sendMessage call
+// When Telegram rate limits the bot it also returns how long to wait before retrying
+func (n *telegramNotifier) send(text string) (time.Duration, error) {
+	body, err := json.Marshal(map[string]string{"chat_id": n.chatID, "text": text, "parse_mode": "MarkdownV2"})
+	if err != nil {
+		return 0, err
+	}
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The request URL contains the bot token, so only the underlying error is returned
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+		Parameters  struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("invalid response with status code %d: %v", resp.StatusCode, err)
+	}
+	if !result.OK {
+		return time.Duration(result.Parameters.RetryAfter) * time.Second, fmt.Errorf("status code %d: %s", resp.StatusCode, result.Description)
+	}
+	return 0, nil
+}
+
+// telegramMessage formats event as MarkdownV2 with the URL in bold
+func telegramMessage(event Event) string {
+	if event.Type == EventRecovery {
+		return fmt.Sprintf("🟢 *%s* is UP\n%s", telegramEscaper.Replace(event.URL), telegramEscaper.Replace(event.Message))
+	}
+	text := fmt.Sprintf("🔴 *%s* is DOWN", telegramEscaper.Replace(event.URL))
+	if event.FailureReason != "" {
+		text += " \\(" + telegramEscaper.Replace(string(event.FailureReason)) + "\\)"
+	}
+	return text + "\n" + telegramEscaper.Replace(event.Message)
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIMethods are the operation keys of an OpenAPI path item
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// pathParamPattern matches {name} templates in OpenAPI paths
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// endpointCheck is a single check generated from an OpenAPI operation
+type endpointCheck struct {
+	method string
+	path   string
+	url    string
+	schema *jsonschema.Schema
+}
+
+// name identifies the check in log messages
+func (c endpointCheck) name() string {
+	return c.method + " " + c.path
+}
+
+// endpointResult is the outcome of one endpoint check
+type endpointResult struct {
+	check endpointCheck
+	err   error
+}
+
+// openAPIMonitor generates endpoint checks from an OpenAPI spec file
+// The spec is reloaded whenever the file changes so new checks are picked up without a restart
+type openAPIMonitor struct {
+	specPath string
+	baseURL  *url.URL
+	params   map[string]string
+	allPaths bool
+
+	mu      sync.Mutex
+	modTime time.Time
+	checks  []endpointCheck
+}
+
+// newOpenAPIMonitor loads specPath and generates its endpoint checks
+// baseURL is used when the spec does not list an absolute server URL
+func newOpenAPIMonitor(specPath string, baseURL *url.URL, paramsPath string, allPaths bool) (*openAPIMonitor, error) {
+	m := &openAPIMonitor{
+		specPath: specPath,
+		baseURL:  baseURL,
+		params:   make(map[string]string),
+		allPaths: allPaths,
+	}
+
+	if paramsPath != "" {
+		data, err := os.ReadFile(paramsPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &m.params); err != nil {
+			return nil, fmt.Errorf("invalid path parameters file %s: %v", paramsPath, err)
+		}
+	}
+
+	if _, err := m.refresh(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// refresh reloads the spec if the file has changed since it was last loaded
+// Returns true if the checks were regenerated; on error the previous checks are kept
+func (m *openAPIMonitor) refresh() (bool, error) {
+	info, err := os.Stat(m.specPath)
+	if err != nil {
+		return false, err
+	}
+
+	m.mu.Lock()
+	unchanged := info.ModTime().Equal(m.modTime)
+	m.mu.Unlock()
+	if unchanged {
+		return false, nil
+	}
+
+	checks, err := m.load()
+	if err != nil {
+		return false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	added, removed := diffEndpointChecks(m.checks, checks)
+	if !m.modTime.IsZero() {
+		log.Printf("Reloaded OpenAPI spec %s: %d endpoint checks (%d added, %d removed)", m.specPath, len(checks), added, removed)
+	}
+	m.modTime = info.ModTime()
+	m.checks = checks
+	return true, nil
+}
+
+// current returns the endpoint checks from the last successful load
+func (m *openAPIMonitor) current() []endpointCheck {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.checks
+}
+
+// load parses the spec file and generates checks for the monitored operations
+// Operations with x-monitor: true are checked with their own method; a path item
+// with x-monitor: true, or every path when allPaths is set, has its GET operation checked
+func (m *openAPIMonitor) load() ([]endpointCheck, error) {
+	data, err := os.ReadFile(m.specPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// YAML is a superset of JSON so this handles both spec formats
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI spec %s: %v", m.specPath, err)
+	}
+	spec, ok := normalizeYAML(raw).(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid OpenAPI spec %s: not an object", m.specPath)
+	}
+	paths, _ := spec["paths"].(map[string]any)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("OpenAPI spec %s has no paths", m.specPath)
+	}
+
+	base := m.serverURL(spec)
+	draft := jsonschema.Draft4
+	if version, _ := spec["openapi"].(string); strings.HasPrefix(version, "3.1") {
+		draft = jsonschema.Draft2020
+	}
+
+	// Sort the paths so checks run and are logged in a stable order
+	names := make([]string, 0, len(paths))
+	for path := range paths {
+		names = append(names, path)
+	}
+	sort.Strings(names)
+
+	var checks []endpointCheck
+	for _, path := range names {
+		item, ok := paths[path].(map[string]any)
+		if !ok {
+			continue
+		}
+		pathMonitored := m.allPaths || isMonitored(item)
+
+		for _, method := range openAPIMethods {
+			operation, ok := item[method].(map[string]any)
+			if !ok {
+				continue
+			}
+			if !isMonitored(operation) && !(pathMonitored && method == "get") {
+				continue
+			}
+
+			expanded, err := expandPathParams(path, m.params)
+			if err != nil {
+				log.Printf("WARNING: skipping OpenAPI endpoint %s %s: %v", strings.ToUpper(method), path, err)
+				continue
+			}
+
+			check := endpointCheck{
+				method: strings.ToUpper(method),
+				path:   path,
+				url:    strings.TrimSuffix(base, "/") + expanded,
+			}
+			if schema := responseSchema(operation); schema != nil {
+				check.schema, err = compileOpenAPISchema(schema, spec["components"], draft, check.name())
+				if err != nil {
+					return nil, fmt.Errorf("%s: invalid response schema: %v", check.name(), err)
+				}
+			}
+			checks = append(checks, check)
+		}
+	}
+
+	if len(checks) == 0 {
+		return nil, fmt.Errorf("OpenAPI spec %s has no monitored endpoints (mark operations with x-monitor: true)", m.specPath)
+	}
+	return checks, nil
+}
+
+// serverURL returns the base URL for the spec's endpoints
+// Relative server URLs such as /v1 are resolved against the monitored URL
+func (m *openAPIMonitor) serverURL(spec map[string]any) string {
+	servers, _ := spec["servers"].([]any)
+	if len(servers) > 0 {
+		if server, ok := servers[0].(map[string]any); ok {
+			if raw, ok := server["url"].(string); ok {
+				if ref, err := url.Parse(raw); err == nil {
+					return m.baseURL.ResolveReference(ref).String()
+				}
+			}
+		}
+	}
+	return m.baseURL.String()
+}
+
+// isMonitored reports whether a path item or operation has x-monitor: true
+func isMonitored(node map[string]any) bool {
+	monitored, _ := node["x-monitor"].(bool)
+	return monitored
+}
+
+// expandPathParams substitutes {name} templates in path from params
+func expandPathParams(path string, params map[string]string) (string, error) {
+	var missing []string
+	expanded := pathParamPattern.ReplaceAllStringFunc(path, func(match string) string {
+		name := match[1 : len(match)-1]
+		value, ok := params[name]
+		if !ok {
+			missing = append(missing, name)
+			return match
+		}
+		return url.PathEscape(value)
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("no value for path parameters %s in -path-params", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// responseSchema returns the JSON schema of an operation's success response, if any
+func responseSchema(operation map[string]any) map[string]any {
+	responses, _ := operation["responses"].(map[string]any)
+
+	// Prefer 200, then any other 2xx, then the default response
+	var response map[string]any
+	if r, ok := responses["200"].(map[string]any); ok {
+		response = r
+	} else {
+		codes := make([]string, 0, len(responses))
+		for code := range responses {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			if strings.HasPrefix(code, "2") {
+				response, _ = responses[code].(map[string]any)
+				break
+			}
+		}
+		if response == nil {
+			response, _ = responses["default"].(map[string]any)
+		}
+	}
+
+	content, _ := response["content"].(map[string]any)
+	for mediaType, value := range content {
+		if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+			continue
+		}
+		if media, ok := value.(map[string]any); ok {
+			schema, _ := media["schema"].(map[string]any)
+			return schema
+		}
+	}
+	return nil
+}
+
+// compileOpenAPISchema compiles a response schema from the spec
+// The spec's components are copied alongside so #/components/... references resolve
+func compileOpenAPISchema(schema map[string]any, components any, draft *jsonschema.Draft, name string) (*jsonschema.Schema, error) {
+	doc := make(map[string]any, len(schema)+1)
+	for key, value := range schema {
+		doc[key] = value
+	}
+	if components != nil {
+		doc["components"] = components
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	resource := "openapi:" + url.PathEscape(name) + ".json"
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = draft
+	if err := compiler.AddResource(resource, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return compiler.Compile(resource)
+}
+
+// normalizeYAML converts the map[interface{}]interface{} values produced for YAML
+// mappings with non-string keys (such as unquoted response codes) to map[string]any
+func normalizeYAML(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalizeYAML(item)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = normalizeYAML(item)
+		}
+		return m
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeYAML(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// diffEndpointChecks counts the checks added and removed between two loads
+func diffEndpointChecks(old, updated []endpointCheck) (added, removed int) {
+	seen := make(map[string]bool, len(old))
+	for _, check := range old {
+		seen[check.name()] = true
+	}
+	for _, check := range updated {
+		if seen[check.name()] {
+			delete(seen, check.name())
+		} else {
+			added++
+		}
+	}
+	return added, len(seen)
+}
+
+// runEndpointChecks checks every endpoint using a pool of workers
+// Results are returned in the same order as checks
+func runEndpointChecks(ctx context.Context, checks []endpointCheck, client *http.Client, opts checkOptions, workers int) []endpointResult {
+	results := make([]endpointResult, len(checks))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = checkEndpoint(ctx, checks[i], client, opts)
+			}
+		}()
+	}
+	for i := range checks {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// checkEndpoint requests a single endpoint and validates its response schema
+func checkEndpoint(ctx context.Context, check endpointCheck, client *http.Client, opts checkOptions) endpointResult {
+	opts.method = check.method
+	opts.body = nil
+	opts.contentType = ""
+	opts.readBody = check.schema != nil
+
+	result, err := checkWebsiteDown(ctx, check.url, client, opts)
+	if err == nil && check.schema != nil {
+		err = withReason(FailureBodyAssertionFailed, validateJSONBody(result.Body, check.schema))
+	}
+	return endpointResult{check: check, err: err}
+}
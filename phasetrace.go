@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Phases of a check attempt, in the order they happen
+const (
+	phaseDNSResolve   = "dns_resolve"
+	phaseTCPConnect   = "tcp_connect"
+	phaseTLSHandshake = "tls_handshake"
+	phaseRequestWrite = "request_write"
+	phaseResponseRead = "response_read"
+)
+
+// phaseSpan is the time one phase of a check took
+// A reused connection has no dns_resolve, tcp_connect or tls_handshake span
+type phaseSpan struct {
+	Name string `json:"name"`
+
+	// StartMS is when the phase started, in milliseconds after the request was sent
+	StartMS    float64 `json:"start_ms"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// phaseRecorder collects the spans of one check attempt
+// The transport and the client trace report to it from their own goroutines
+type phaseRecorder struct {
+	start time.Time
+
+	mu        sync.Mutex
+	spans     []phaseSpan
+	dnsStart  time.Time
+	connReady time.Time
+	tlsStart  time.Time
+	wrote     time.Time
+
+	// dials holds when each address was dialled, as Happy Eyeballs may dial several at once
+	dials     map[string]time.Time
+	connected bool
+}
+
+// phaseRecorderKey is the context key of the recorder for a request
+type phaseRecorderKey struct{}
+
+// newPhaseRecorder returns a recorder and a copy of ctx whose client trace reports to it
+func newPhaseRecorder(ctx context.Context) (*phaseRecorder, context.Context) {
+	r := &phaseRecorder{start: time.Now(), dials: make(map[string]time.Time)}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			r.mu.Lock()
+			r.dnsStart = time.Now()
+			r.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			r.mu.Lock()
+			start := r.dnsStart
+			r.mu.Unlock()
+			if !start.IsZero() {
+				r.add(phaseDNSResolve, start, time.Now())
+			}
+		},
+		ConnectStart: func(network, addr string) {
+			r.mu.Lock()
+			r.dials[network+" "+addr] = time.Now()
+			r.mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			now := time.Now()
+			r.mu.Lock()
+			start, ok := r.dials[network+" "+addr]
+			first := err == nil && ok && !r.connected
+			if first {
+				r.connected = true
+			}
+			r.mu.Unlock()
+			// Only the address that won the race is the connection the request used
+			if first {
+				r.add(phaseTCPConnect, start, now)
+			}
+		},
+		GotConn: func(httptrace.GotConnInfo) {
+			r.mu.Lock()
+			r.connReady = time.Now()
+			r.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			r.mu.Lock()
+			r.tlsStart = time.Now()
+			r.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			r.mu.Lock()
+			start := r.tlsStart
+			r.mu.Unlock()
+			if !start.IsZero() {
+				r.add(phaseTLSHandshake, start, time.Now())
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			now := time.Now()
+			r.mu.Lock()
+			start := r.connReady
+			r.wrote = now
+			r.mu.Unlock()
+			if !start.IsZero() {
+				r.add(phaseRequestWrite, start, now)
+			}
+		},
+	}
+	ctx = context.WithValue(ctx, phaseRecorderKey{}, r)
+	return r, httptrace.WithClientTrace(ctx, trace)
+}
+
+// phaseRecorderFrom returns the recorder of the request ctx belongs to, if any
+func phaseRecorderFrom(ctx context.Context) *phaseRecorder {
+	r, _ := ctx.Value(phaseRecorderKey{}).(*phaseRecorder)
+	return r
+}
+
+// add records a span for the phase from start to end
+func (r *phaseRecorder) add(name string, start, end time.Time) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, phaseSpan{
+		Name:       name,
+		StartMS:    milliseconds(start.Sub(r.start)),
+		DurationMS: milliseconds(end.Sub(start)),
+	})
+}
+
+// finish records the response_read span, from the request being written until
+// the response was read, and returns every span
+// Nothing is added if the request was never written
+func (r *phaseRecorder) finish() []phaseSpan {
+	r.mu.Lock()
+	wrote := r.wrote
+	r.mu.Unlock()
+	if !wrote.IsZero() {
+		r.add(phaseResponseRead, wrote, time.Now())
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]phaseSpan(nil), r.spans...)
+}
+
+// phasesError attaches the spans of the last failed attempt to a check error
+type phasesError struct {
+	spans []phaseSpan
+	err   error
+}
+
+func (e *phasesError) Error() string { return e.err.Error() }
+func (e *phasesError) Unwrap() error { return e.err }
+
+// withPhases attaches spans to err so a down event can show where the check failed
+func withPhases(err error, spans []phaseSpan) error {
+	if err == nil || len(spans) == 0 {
+		return err
+	}
+	return &phasesError{spans: spans, err: err}
+}
+
+// checkPhases returns the spans of a check from its result, or if it failed
+// without one, from its error
+func checkPhases(result *checkResult, checkErr error) []phaseSpan {
+	if result != nil {
+		return result.Phases
+	}
+	var failed *phasesError
+	if errors.As(checkErr, &failed) {
+		return failed.spans
+	}
+	return nil
+}
+
+// milliseconds returns d in milliseconds, rounded to the microsecond
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Round(time.Microsecond)) / float64(time.Millisecond)
+}
+
+// formatPhases returns the spans as name=duration pairs for logging
+func formatPhases(spans []phaseSpan) string {
+	parts := make([]string, 0, len(spans))
+	for _, span := range spans {
+		parts = append(parts, fmt.Sprintf("%s=%.3fms", span.Name, span.DurationMS))
+	}
+	return strings.Join(parts, " ")
+}
+
+// logPhases logs the spans of attempt i of a check of url
+func logPhases(url string, i, retries int, spans []phaseSpan) {
+	if len(spans) == 0 {
+		return
+	}
+	log.Printf("Trace for %s (attempt %d/%d): %s", url, i+1, retries, formatPhases(spans))
+}
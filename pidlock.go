@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// exitLockHeld is the exit status when -lock-file is held by another instance
+const exitLockHeld = 2
+
+// lockHeldError reports the running instance that holds the lock file
+type lockHeldError struct {
+	pid int
+}
+
+func (e *lockHeldError) Error() string {
+	return fmt.Sprintf("another instance is running (PID %d)", e.pid)
+}
+
+// pidLock is a lock file holding the PID of the instance that owns it
+type pidLock struct {
+	path string
+}
+
+// acquirePIDLock creates the lock file at path with this process's PID
+// A lock file left by a process that is no longer running is replaced; one held
+// by a running websitecheck fails with a *lockHeldError
+func acquirePIDLock(path string) (*pidLock, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = fmt.Fprintf(file, "%d\n", os.Getpid())
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, err
+			}
+			return &pidLock{path: path}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		pid, err := readLockPID(path)
+		if err == nil && pid != os.Getpid() && isWebsitecheckProcess(pid) {
+			return nil, &lockHeldError{pid: pid}
+		}
+		if err != nil {
+			log.Printf("Removing invalid lock file %s: %v", path, err)
+		} else {
+			log.Printf("Removing stale lock file %s left by PID %d", path, pid)
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("lock file %s was recreated by another process", path)
+}
+
+// readLockPID returns the PID in the lock file at path
+func readLockPID(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("no PID in %q", strings.TrimSpace(string(data)))
+	}
+	return pid, nil
+}
+
+// release removes the lock file unless another instance has since taken it over
+func (l *pidLock) release() {
+	if l == nil {
+		return
+	}
+	if pid, err := readLockPID(l.path); err != nil || pid != os.Getpid() {
+		return
+	}
+	if err := os.Remove(l.path); err != nil {
+		log.Printf("WARNING: Cannot remove lock file %s: %v", l.path, err)
+	}
+}
@@ -0,0 +1,37 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// isWebsitecheckProcess reports whether pid is running this program
+// /proc/<pid>/exe names the running binary; a binary replaced since it started
+// is shown with a " (deleted)" suffix, so the base names are compared
+// A process whose binary cannot be read, as it belongs to another user or
+// /proc is mounted with hidepid, is taken to be running this program, so only
+// a lock whose process is gone is ever removed
+func isWebsitecheckProcess(pid int) bool {
+	exe, err := os.Readlink(filepath.Join("/proc", strconv.Itoa(pid), "exe"))
+	if errors.Is(err, syscall.ESRCH) {
+		return false
+	}
+	if os.IsNotExist(err) {
+		// hidepid=2 hides other users' processes, which signal 0 still finds
+		return !errors.Is(syscall.Kill(pid, 0), syscall.ESRCH)
+	}
+	if err != nil {
+		return true
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return true
+	}
+	return filepath.Base(strings.TrimSuffix(exe, " (deleted)")) == filepath.Base(self)
+}
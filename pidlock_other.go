@@ -0,0 +1,26 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// isWebsitecheckProcess reports whether pid is running
+// Without /proc the program cannot be told, so any running process holds the lock
+func isWebsitecheckProcess(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// FindProcess only succeeds for running processes on Windows
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	// Signalling another user's process fails with EPERM, but it is running
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
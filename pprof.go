@@ -0,0 +1,29 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/http/pprof"
+)
+
+// pprofHandler returns the net/http/pprof routes under /debug/pprof/
+// They are registered on their own mux so they are never served on -api-addr
+func pprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// startPprof serves the profiling endpoints on addr in the background
+func startPprof(addr string) {
+	go func() {
+		log.Printf("Profiling endpoints listening on %s/debug/pprof/", addr)
+		if err := http.ListenAndServe(addr, pprofHandler()); err != nil {
+			log.Fatalf("Error: Profiling server failed: %v", err)
+		}
+	}()
+}
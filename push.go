@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// pushWait is how long to keep listening for push promises after the main response ends
+const pushWait = 2 * time.Second
+
+// parsePushPaths parses a comma-separated list of expected push paths
+func parsePushPaths(list string) []string {
+	var paths []string
+	for _, path := range strings.Split(list, ",") {
+		path = strings.TrimSpace(path)
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// checkServerPush requests rawURL over a raw HTTP/2 connection with push enabled
+// and returns the expected paths that were not promised by the server.
+// The standard library client disables server push, so this speaks h2 directly.
+func checkServerPush(rawURL string, expected []string, timeout time.Duration) ([]string, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if target.Scheme != "https" {
+		return nil, errors.New("server push can only be checked on https URLs")
+	}
+
+	addr := target.Host
+	if target.Port() == "" {
+		addr = net.JoinHostPort(target.Hostname(), "443")
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{
+		ServerName: target.Hostname(),
+		NextProtos: []string{http2.NextProtoTLS},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if proto := conn.ConnectionState().NegotiatedProtocol; proto != http2.NextProtoTLS {
+		return nil, fmt.Errorf("server did not negotiate HTTP/2 (got %q)", proto)
+	}
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write([]byte(http2.ClientPreface)); err != nil {
+		return nil, err
+	}
+
+	framer := http2.NewFramer(conn, conn)
+	if err := framer.WriteSettings(http2.Setting{ID: http2.SettingEnablePush, Val: 1}); err != nil {
+		return nil, err
+	}
+
+	// Encode and send the GET request on stream 1
+	var headerBuf bytes.Buffer
+	encoder := hpack.NewEncoder(&headerBuf)
+	path := target.RequestURI()
+	for _, field := range []hpack.HeaderField{
+		{Name: ":method", Value: "GET"},
+		{Name: ":scheme", Value: "https"},
+		{Name: ":authority", Value: target.Host},
+		{Name: ":path", Value: path},
+		{Name: "user-agent", Value: "websitecheck"},
+	} {
+		encoder.WriteField(field)
+	}
+	err = framer.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      1,
+		BlockFragment: headerBuf.Bytes(),
+		EndStream:     true,
+		EndHeaders:    true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// All header blocks must go through one decoder to keep its dynamic table in sync
+	var promisedPath string
+	decoder := hpack.NewDecoder(4096, func(field hpack.HeaderField) {
+		if field.Name == ":path" {
+			promisedPath = field.Value
+		}
+	})
+
+	promised := make(map[string]bool)
+	inPromise := false
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+			return nil, err
+		}
+
+		streamEnded := false
+		switch f := frame.(type) {
+		case *http2.SettingsFrame:
+			if !f.IsAck() {
+				framer.WriteSettingsAck()
+			}
+		case *http2.PingFrame:
+			if !f.IsAck() {
+				framer.WritePing(true, f.Data)
+			}
+		case *http2.PushPromiseFrame:
+			promisedPath = ""
+			decoder.Write(f.HeaderBlockFragment())
+			inPromise = !f.HeadersEnded()
+			if !inPromise {
+				promised[promisedPath] = true
+			}
+		case *http2.HeadersFrame:
+			decoder.Write(f.HeaderBlockFragment())
+			streamEnded = f.StreamID == 1 && f.StreamEnded()
+		case *http2.ContinuationFrame:
+			decoder.Write(f.HeaderBlockFragment())
+			if inPromise && f.HeadersEnded() {
+				inPromise = false
+				promised[promisedPath] = true
+			}
+		case *http2.DataFrame:
+			// Keep the flow control windows open so the response can complete
+			if n := uint32(len(f.Data())); n > 0 {
+				framer.WriteWindowUpdate(0, n)
+				framer.WriteWindowUpdate(f.StreamID, n)
+			}
+			streamEnded = f.StreamID == 1 && f.StreamEnded()
+		case *http2.RSTStreamFrame:
+			if f.StreamID == 1 {
+				return nil, fmt.Errorf("server reset the stream: %v", f.ErrCode)
+			}
+		case *http2.GoAwayFrame:
+			return missingPushes(expected, promised), nil
+		}
+
+		// Wait briefly for late push promises once the main response is complete
+		if streamEnded {
+			conn.SetDeadline(time.Now().Add(pushWait))
+		}
+	}
+
+	return missingPushes(expected, promised), nil
+}
+
+// missingPushes returns the expected paths absent from promised
+func missingPushes(expected []string, promised map[string]bool) []string {
+	var missing []string
+	for _, path := range expected {
+		if !promised[path] {
+			missing = append(missing, path)
+		}
+	}
+	return missing
+}
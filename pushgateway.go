@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pushgatewayJob is the job label used for pushed metrics
+const pushgatewayJob = "websitecheck"
+
+// pushgatewayInstance derives a stable instance label from url
+// URLs contain characters that are awkward in a path segment, so a hash is used
+func pushgatewayInstance(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:8])
+}
+
+// pushMetrics replaces the metrics for url on the Pushgateway at addr with the
+// outcome of the latest check, using the text exposition format
+func pushMetrics(addr, url string, result *checkResult, checkErr error, consecutiveFailures int, timeout time.Duration) error {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	endpoint := fmt.Sprintf("%s/metrics/job/%s/instance/%s", strings.TrimSuffix(addr, "/"), pushgatewayJob, pushgatewayInstance(url))
+
+	labels := fmt.Sprintf(`{url=%s}`, strconv.Quote(url))
+	up := 0
+	if checkErr == nil {
+		up = 1
+	}
+
+	var body bytes.Buffer
+	writeMetric := func(name, help string, value float64) {
+		fmt.Fprintf(&body, "# HELP %s %s\n# TYPE %s gauge\n%s%s %s\n", name, help, name, name, labels, strconv.FormatFloat(value, 'g', -1, 64))
+	}
+	writeMetric("websitecheck_up", "Whether the last check succeeded", float64(up))
+	writeMetric("websitecheck_consecutive_failures", "Number of consecutive failed checks", float64(consecutiveFailures))
+	writeMetric("websitecheck_last_check_timestamp_seconds", "Unix time of the last check", float64(time.Now().Unix()))
+	if result != nil {
+		writeMetric("websitecheck_latency_seconds", "Latency of the last successful check", result.Latency.Seconds())
+		if result.StatusCode != 0 {
+			writeMetric("websitecheck_status_code", "HTTP status code of the last successful check", float64(result.StatusCode))
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPut, endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("pushgateway returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// redirectHop is one response in a redirect chain
+type redirectHop struct {
+	URL        string
+	StatusCode int
+}
+
+// redirectChain returns every URL requested to obtain resp, in order, with the
+// status code each returned. The last hop is the final response.
+func redirectChain(resp *http.Response) []redirectHop {
+	hops := []redirectHop{{URL: resp.Request.URL.String(), StatusCode: resp.StatusCode}}
+	for req := resp.Request; req.Response != nil; req = req.Response.Request {
+		hops = append(hops, redirectHop{URL: req.Response.Request.URL.String(), StatusCode: req.Response.StatusCode})
+	}
+
+	// The hops were collected from the final response backwards
+	for i, j := 0, len(hops)-1; i < j; i, j = i+1, j-1 {
+		hops[i], hops[j] = hops[j], hops[i]
+	}
+	return hops
+}
+
+// formatRedirectChain renders hops as "url (301) -> url (200)"
+func formatRedirectChain(hops []redirectHop) string {
+	parts := make([]string, len(hops))
+	for i, hop := range hops {
+		parts[i] = fmt.Sprintf("%s (%d)", hop.URL, hop.StatusCode)
+	}
+	return strings.Join(parts, " -> ")
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net"
+	"syscall"
+	"time"
+)
+
+// retryPolicy selects which classes of failure are retried within a check
+// Failures outside these classes, such as DNS or TLS errors, are always retried
+type retryPolicy struct {
+	onTimeout           bool
+	onConnectionRefused bool
+	on5xx               bool
+	on4xx               bool
+}
+
+// retryError reports whether a failed request should be retried
+func (p retryPolicy) retryError(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return p.onConnectionRefused
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return p.onTimeout
+	}
+	return true
+}
+
+// retryStatus reports whether a bad status code should be retried
+func (p retryPolicy) retryStatus(code int) bool {
+	switch {
+	case code >= 500 && code <= 599:
+		return p.on5xx
+	case code >= 400 && code <= 499:
+		return p.on4xx
+	default:
+		return true
+	}
+}
+
+// retryCheck makes up to opts.retries attempts, like checkWebsiteDown, for the
+// checks that are not HTTP requests. kind names the check in verbose logs
+func retryCheck(kind string, opts checkOptions, attempt func() (*checkResult, error)) (*checkResult, error) {
+	var lastErr error
+	for i := 0; i < opts.retries; i++ {
+		// Wait a little between attempts
+		if i > 0 {
+			time.Sleep(2 * time.Second)
+		}
+
+		result, err := attempt()
+		if err != nil {
+			if opts.verbose {
+				log.Printf("%s check failed (attempt %d/%d): %v", kind, i+1, opts.retries, err)
+			}
+			lastErr = err
+			if !opts.retry.retryError(err) {
+				break
+			}
+			continue
+		}
+		return result, nil
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("no check attempts were made")
+	}
+	return nil, lastErr
+}
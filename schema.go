@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// maxSchemaErrors is how many validation errors are included in a failure message
+const maxSchemaErrors = 3
+
+// loadJSONSchema compiles the JSON Schema file at path
+func loadJSONSchema(path string) (*jsonschema.Schema, error) {
+	return jsonschema.Compile(path)
+}
+
+// validateJSONBody parses body as JSON and validates it against schema
+// The returned error lists the first few validation failures
+func validateJSONBody(body []byte, schema *jsonschema.Schema) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return fmt.Errorf("response is not valid JSON: %v", err)
+	}
+
+	err := schema.Validate(doc)
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+
+	leaves := schemaErrorLeaves(validationErr, nil)
+	messages := make([]string, 0, maxSchemaErrors)
+	for _, leaf := range leaves {
+		if len(messages) == maxSchemaErrors {
+			break
+		}
+		location := leaf.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		messages = append(messages, fmt.Sprintf("%s: %s", location, leaf.Message))
+	}
+
+	summary := fmt.Sprintf("response does not match schema (%d errors): %s", len(leaves), strings.Join(messages, "; "))
+	if len(leaves) > maxSchemaErrors {
+		summary += "; ..."
+	}
+	return errors.New(summary)
+}
+
+// schemaErrorLeaves collects the innermost causes of a validation error,
+// which name the specific values that failed rather than the enclosing schemas
+func schemaErrorLeaves(err *jsonschema.ValidationError, leaves []*jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return append(leaves, err)
+	}
+	for _, cause := range err.Causes {
+		leaves = schemaErrorLeaves(cause, leaves)
+	}
+	return leaves
+}
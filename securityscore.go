@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// securityHeaderRule scores a single security header
+// A header that is present earns half its points; a header that also passes
+// the quality check earns all of them
+type securityHeaderRule struct {
+	header string
+	points int
+	good   func(value string) bool
+}
+
+// securityHeaderRules is the scoring rubric; the points add up to 100
+var securityHeaderRules = []securityHeaderRule{
+	{"Strict-Transport-Security", 25, func(v string) bool {
+		return checkHSTS(http.Header{"Strict-Transport-Security": {v}}, hstsPolicy{minMaxAge: 31536000}) == nil
+	}},
+	{"Content-Security-Policy", 25, func(v string) bool {
+		return !strings.Contains(v, "'unsafe-inline'") && !strings.Contains(v, "'unsafe-eval'")
+	}},
+	{"X-Content-Type-Options", 15, func(v string) bool {
+		return strings.EqualFold(v, "nosniff")
+	}},
+	{"X-Frame-Options", 10, func(v string) bool {
+		return strings.EqualFold(v, "DENY") || strings.EqualFold(v, "SAMEORIGIN")
+	}},
+	{"Referrer-Policy", 10, func(v string) bool {
+		switch strings.ToLower(v) {
+		case "no-referrer", "same-origin", "strict-origin", "strict-origin-when-cross-origin":
+			return true
+		}
+		return false
+	}},
+	{"Permissions-Policy", 10, func(v string) bool {
+		return true
+	}},
+	{"X-XSS-Protection", 5, func(v string) bool {
+		// "0" disables the legacy filter, which is the current recommendation
+		return v == "0" || strings.HasPrefix(strings.ReplaceAll(v, " ", ""), "1;mode=block")
+	}},
+}
+
+// securityScore rates the security headers in header from 0 to 100
+// Returns the score along with a finding for every header that lost points
+func securityScore(header http.Header) (int, []string) {
+	score := 0
+	var findings []string
+	for _, rule := range securityHeaderRules {
+		value := strings.TrimSpace(header.Get(rule.header))
+		switch {
+		case value == "":
+			findings = append(findings, fmt.Sprintf("%s missing", rule.header))
+		case !rule.good(value):
+			score += rule.points / 2
+			findings = append(findings, fmt.Sprintf("%s weak (%q)", rule.header, value))
+		default:
+			score += rule.points
+		}
+	}
+	return score, findings
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+	"runtime"
+	"time"
+)
+
+// selfCheck logs the process's own goroutine count and heap usage and warns
+// when either passes its limit, to catch leaks in long-running monitors
+type selfCheck struct {
+	maxGoroutines int
+	maxHeapMB     int
+}
+
+// start runs the check every interval in the background
+func (c selfCheck) start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			c.run()
+		}
+	}()
+}
+
+// run logs the current usage once; a zero limit is never exceeded
+func (c selfCheck) run() {
+	goroutines := runtime.NumGoroutine()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	heapMB := float64(stats.HeapAlloc) / (1 << 20)
+
+	log.Printf("Self check: %d goroutines, %.1f MB heap allocated, %d GC cycles", goroutines, heapMB, stats.NumGC)
+	if c.maxGoroutines > 0 && goroutines > c.maxGoroutines {
+		log.Printf("WARNING: %d goroutines running, more than -max-goroutines %d; goroutines may be leaking", goroutines, c.maxGoroutines)
+	}
+	if c.maxHeapMB > 0 && heapMB > float64(c.maxHeapMB) {
+		log.Printf("WARNING: %.1f MB heap allocated, more than -max-heap-mb %d; memory may be leaking", heapMB, c.maxHeapMB)
+	}
+}
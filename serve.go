@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// maxCheckBodySize limits the size of a check definition sent to POST /checks
+const maxCheckBodySize = 1 << 20
+
+// checkRunTimeout limits a check run through POST /check
+const checkRunTimeout = 5 * time.Minute
+
+// apiCheckSettings are the settings a check added through POST /checks may use,
+// with a token or as any tenant. Flags naming an executable, a file or a notification target can only be given
+// on the serve command line or in -config-dir files, which the API cannot write
+var apiCheckSettings = map[string]bool{
+	"url": true, "mode": true, "interval": true, "timeout": true, "retries": true, "verbose": true, "tags": true,
+	"max-backoff": true, "initial-backoff": true, "backoff-factor": true, "backoff-mode": true, "backoff-increment": true,
+	"backoff-reset-after": true, "jitter-mode": true, "eval-mode": true, "eval-failures": true, "eval-window": true,
+	"method": true, "request-body": true, "request-body-content-type": true, "decompress": true, "bust-cache": true,
+	"watch-headers": true, "fail-on-header-change": true, "fail-on-http-downgrade": true, "min-tls-version": true,
+	"check-hsts": true, "min-hsts-max-age": true, "fail-on-missing-hsts": true, "require-hsts-include-subdomains": true,
+	"require-hsts-preload": true, "check-mixed-content": true, "fail-on-mixed-content": true, "check-csp": true,
+	"min-csp-directives": true, "expect-csp": true, "fail-on-csp": true, "retry-on-timeout": true, "retry-on-connection-refused": true,
+	"retry-on-5xx": true, "retry-on-4xx": true, tenantSetting: true,
+}
+
+// checkAPISettings rejects every setting a check added through the API may not use
+func checkAPISettings(settings map[string]string) error {
+	var refused []string
+	for name := range settings {
+		if !apiCheckSettings[name] {
+			refused = append(refused, name)
+		}
+	}
+	if len(refused) > 0 {
+		sort.Strings(refused)
+		return fmt.Errorf("settings not allowed through the API: %s", strings.Join(refused, ", "))
+	}
+	return nil
+}
+
+// managedCheck describes a check in the serve API
+type managedCheck struct {
+	ID       string            `json:"id"`
+	Settings map[string]string `json:"settings"`
+}
+
+// checkStatus is the state of a check as reported by GET /status
+type checkStatus struct {
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Tenant string `json:"tenant,omitempty"`
+	processState
+	LastRun *checkRun `json:"last_run,omitempty"`
+}
+
+// checkRun is the outcome of a check run through POST /check
+type checkRun struct {
+	ID        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	LocalTime string    `json:"local_time,omitempty"`
+	Up        bool      `json:"up"`
+	Output    string    `json:"output,omitempty"`
+}
+
+// checkAPI lets checks be added and removed over HTTP
+// Each check runs as a child monitor, as with -config-dir
+// With tenants set, every request must identify its tenant and only sees
+// the checks that tenant owns; otherwise every request must carry token
+type checkAPI struct {
+	checks  *checkSupervisor
+	nextID  atomic.Int64
+	tenants tenantKeys
+	token   string
+
+	mu   sync.Mutex
+	runs map[string]checkRun
+}
+
+// handler returns the routes served by the check API
+func (a *checkAPI) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /checks", a.handleList)
+	mux.HandleFunc("POST /checks", a.handleAdd)
+	mux.HandleFunc("GET /checks/{id}", a.handleGet)
+	mux.HandleFunc("DELETE /checks/{id}", a.handleDelete)
+	mux.HandleFunc("GET /status", a.handleStatus)
+	mux.HandleFunc("POST /check", a.handleRun)
+	mux.HandleFunc("GET /config", a.handleConfig)
+	return mux
+}
+
+// authorize returns the tenant making the request, or writes an error response
+// and returns false. Without tenants the request must carry the bearer token
+func (a *checkAPI) authorize(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if a.tenants == nil {
+		return "", requireBearerToken(w, r, a.token, "the check API")
+	}
+	tenant, err := a.tenants.authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return "", false
+	}
+	return tenant, true
+}
+
+// owned returns the settings of the check for id if tenant owns it
+// Checks of other tenants are reported as missing so their IDs are not revealed
+func (a *checkAPI) owned(tenant, id string) (map[string]string, bool) {
+	settings, ok := a.checks.checkSettings(id)
+	if !ok || (a.tenants != nil && settings[tenantSetting] != tenant) {
+		return nil, false
+	}
+	return settings, true
+}
+
+// ownedIDs returns the IDs of the running checks tenant owns
+func (a *checkAPI) ownedIDs(tenant string) []string {
+	var ids []string
+	for _, id := range a.checks.keys() {
+		if _, ok := a.owned(tenant, id); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// handleList returns every running check
+func (a *checkAPI) handleList(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := a.authorize(w, r)
+	if !ok {
+		return
+	}
+	checks := []managedCheck{}
+	for _, id := range a.ownedIDs(tenant) {
+		if settings, ok := a.checks.checkSettings(id); ok {
+			checks = append(checks, managedCheck{ID: id, Settings: settings})
+		}
+	}
+	writeJSON(w, http.StatusOK, checks)
+}
+
+// handleAdd starts a check from a JSON or YAML object of flag names to values,
+// the same format as a -config-dir file
+func (a *checkAPI) handleAdd(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := a.authorize(w, r)
+	if !ok {
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxCheckBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	settings, err := parseCheckSettings(data)
+	if err == nil {
+		err = checkAPISettings(settings)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if a.tenants != nil {
+		if owner, ok := settings[tenantSetting]; ok && owner != tenant {
+			http.Error(w, "cannot add a check for another tenant", http.StatusForbidden)
+			return
+		}
+		settings[tenantSetting] = tenant
+	}
+
+	id := strconv.FormatInt(a.nextID.Add(1), 10)
+	a.checks.start(id, settings)
+	writeJSON(w, http.StatusCreated, managedCheck{ID: id, Settings: settings})
+}
+
+// handleGet returns one check
+func (a *checkAPI) handleGet(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := a.authorize(w, r)
+	if !ok {
+		return
+	}
+	id := r.PathValue("id")
+	settings, ok := a.owned(tenant, id)
+	if !ok {
+		http.Error(w, "no check "+id, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, managedCheck{ID: id, Settings: settings})
+}
+
+// handleDelete stops a check
+func (a *checkAPI) handleDelete(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := a.authorize(w, r)
+	if !ok {
+		return
+	}
+	id := r.PathValue("id")
+	if _, ok := a.owned(tenant, id); !ok || !a.checks.stop(id) {
+		http.Error(w, "no check "+id, http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleConfig returns the flags shared by every check with secrets redacted
+func (a *checkAPI) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if _, ok := a.authorize(w, r); !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, effectiveConfig())
+}
+
+// handleStatus returns the state of every check the tenant owns
+func (a *checkAPI) handleStatus(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := a.authorize(w, r)
+	if !ok {
+		return
+	}
+	statuses := []checkStatus{}
+	for _, id := range a.ownedIDs(tenant) {
+		settings, ok := a.checks.checkSettings(id)
+		state, running := a.checks.processState(id)
+		if !ok || !running {
+			continue
+		}
+		status := checkStatus{ID: id, URL: settings["url"], Tenant: settings[tenantSetting], processState: state}
+		a.mu.Lock()
+		if run, ok := a.runs[id]; ok {
+			status.LastRun = &run
+		}
+		a.mu.Unlock()
+		statuses = append(statuses, status)
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+// handleRun runs the check given by the id parameter once and returns the outcome
+// The run is separate from the check's own monitor, which keeps its schedule
+func (a *checkAPI) handleRun(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := a.authorize(w, r)
+	if !ok {
+		return
+	}
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "id parameter is required", http.StatusBadRequest)
+		return
+	}
+	settings, ok := a.owned(tenant, id)
+	if !ok {
+		http.Error(w, "no check "+id, http.StatusNotFound)
+		return
+	}
+
+	log.Printf("Running check %s for %s (requested by %s)", id, settings["url"], r.RemoteAddr)
+	run := runCheckOnce(r.Context(), checkArgs(a.checks.shared, settings))
+	run.ID = id
+	a.mu.Lock()
+	a.runs[id] = run
+	a.mu.Unlock()
+	writeJSON(w, http.StatusOK, run)
+}
+
+// runCheckOnce runs a check with args as the once subcommand
+// The check is up if the child exits with status 0
+func runCheckOnce(ctx context.Context, args []string) checkRun {
+	ctx, cancel := context.WithTimeout(ctx, checkRunTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, executablePath(), append([]string{commandOnce}, args...)...)
+	output, err := cmd.CombinedOutput()
+	now := time.Now().UTC()
+	run := checkRun{Time: now, LocalTime: localTime(now), Up: err == nil, Output: strings.TrimSpace(string(output))}
+	if ctx.Err() != nil {
+		run.Output = strings.TrimSpace(run.Output + "\ntimed out after " + checkRunTimeout.String())
+	}
+	return run
+}
+
+// writeJSON writes v as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// runServe serves the check API on addr until interrupted, then stops every check
+// The checks in configDir, if set, are started first with their file name as ID.
+// With tenantsFile, requests must authenticate as one of its tenants, otherwise
+// with token as a bearer token
+func runServe(addr, configDir, tenantsFile, token string, shared []string) {
+	if addr == "" {
+		log.Fatal("Error: serve requires -api-addr")
+	}
+	api := &checkAPI{checks: newCheckSupervisor(shared), token: token, runs: make(map[string]checkRun)}
+	if tenantsFile != "" {
+		tenants, err := loadTenantKeys(tenantsFile)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		api.tenants = tenants
+		log.Printf("Requiring %s and %s headers for %d tenants", tenantIDHeader, apiKeyHeader, len(tenants))
+	} else if token == "" {
+		log.Fatal("Error: serve requires -api-token or -tenants-file")
+	}
+	if configDir != "" {
+		checks, err := loadCheckDir(configDir)
+		if err != nil {
+			log.Fatalf("Error: Cannot read check directory: %v", err)
+		}
+		paths := make([]string, 0, len(checks))
+		for path := range checks {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			api.checks.start(filepath.Base(path), checks[path])
+		}
+	}
+
+	go func() {
+		log.Printf("Check API listening on %s", addr)
+		if err := http.ListenAndServe(addr, api.handler()); err != nil {
+			log.Fatalf("Error: Check API server failed: %v", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-quit
+	log.Printf("Received %s, stopping %d checks", sig, len(api.checks.keys()))
+	api.checks.stopAll()
+}
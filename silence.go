@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// silenceRequest is the optional body of POST /alerts/{url}/silence
+type silenceRequest struct {
+	DurationSeconds *int   `json:"duration_seconds"`
+	Reason          string `json:"reason"`
+}
+
+// handleSilence acknowledges the alert for the URL in the path and suppresses
+// its ELF executions for duration_seconds, default 300. Checks keep running,
+// so the status still shows whether the URL has recovered
+func (s *apiServer) handleSilence(w http.ResponseWriter, r *http.Request) {
+	url, ok := s.monitoredURL(w, r)
+	if !ok {
+		return
+	}
+	var request silenceRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxCheckBodySize)).Decode(&request); err != nil && err != io.EOF {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	duration := defaultSuppressDuration
+	if request.DurationSeconds != nil {
+		if *request.DurationSeconds <= 0 {
+			http.Error(w, "duration_seconds must be a positive number of seconds", http.StatusBadRequest)
+			return
+		}
+		duration = time.Duration(*request.DurationSeconds) * time.Second
+	}
+
+	s.silence(w, r, url, duration, request.Reason)
+}
+
+// silence suppresses the ELF executions for url for duration and returns the silence
+func (s *apiServer) silence(w http.ResponseWriter, r *http.Request, url string, duration time.Duration, reason string) {
+	silence := alertSuppressions.silence(url, duration, reason)
+	if reason != "" {
+		log.Printf("Alert for %s acknowledged until %s: %s (requested by %s)", url, silence.Until.Format(time.RFC3339), reason, r.RemoteAddr)
+	} else {
+		log.Printf("Alert for %s acknowledged until %s (requested by %s)", url, silence.Until.Format(time.RFC3339), r.RemoteAddr)
+	}
+	writeJSON(w, http.StatusOK, silence)
+}
+
+// handleUnsilence lifts the silence for the URL in the path early
+func (s *apiServer) handleUnsilence(w http.ResponseWriter, r *http.Request) {
+	url, ok := s.monitoredURL(w, r)
+	if !ok {
+		return
+	}
+	s.unsilence(w, r, url)
+}
+
+// unsilence lifts the silence for url
+func (s *apiServer) unsilence(w http.ResponseWriter, r *http.Request, url string) {
+	if !alertSuppressions.lift(url) {
+		http.Error(w, "no silence for "+url, http.StatusNotFound)
+		return
+	}
+	log.Printf("Lifted silence for %s (requested by %s)", url, r.RemoteAddr)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// monitoredURL returns the monitored URL in the escaped url path value, or
+// writes an error response and returns false
+func (s *apiServer) monitoredURL(w http.ResponseWriter, r *http.Request) (string, bool) {
+	return s.checkedURL(w, r.PathValue("url"))
+}
+
+// checkedURL returns raw as a monitored URL, or writes an error response and returns false
+func (s *apiServer) checkedURL(w http.ResponseWriter, raw string) (string, bool) {
+	if raw == "" {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return "", false
+	}
+	url, err := normalizeURL(raw)
+	if err != nil {
+		http.Error(w, "invalid url", http.StatusBadRequest)
+		return "", false
+	}
+	if !s.monitors(url) {
+		http.Error(w, "not monitoring "+url, http.StatusNotFound)
+		return "", false
+	}
+	return url, true
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// discoveryWorkers is how many pages discovered from the sitemaps are checked concurrently
+const discoveryWorkers = 4
+
+// maxSitemapDepth limits how many levels of sitemap indexes are followed
+const maxSitemapDepth = 2
+
+// sitemapDocument is a sitemap or a sitemap index; only one of the lists is set
+type sitemapDocument struct {
+	URLs []struct {
+		Loc      string `xml:"loc"`
+		Priority string `xml:"priority"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// sitemapPage is a page listed in a sitemap
+type sitemapPage struct {
+	url      string
+	priority float64
+}
+
+// discoverFromRobots returns checks for up to max pages listed in the sitemaps
+// named by the Sitemap: directives of base's robots.txt
+// Pages with a higher <priority> are picked first, then in sitemap order
+func discoverFromRobots(client *http.Client, base *url.URL, max int) ([]endpointCheck, error) {
+	robots := &url.URL{Scheme: base.Scheme, Host: base.Host, Path: "/robots.txt"}
+	sitemaps, err := robotsSitemaps(client, robots.String())
+	if err != nil {
+		return nil, err
+	}
+	if len(sitemaps) == 0 {
+		return nil, fmt.Errorf("%s has no Sitemap: directives", robots)
+	}
+
+	var pages []sitemapPage
+	for _, sitemap := range sitemaps {
+		found, err := sitemapPages(client, sitemap, maxSitemapDepth)
+		if err != nil {
+			log.Printf("WARNING: Cannot read sitemap %s: %v", sitemap, err)
+			continue
+		}
+		pages = append(pages, found...)
+	}
+	sort.SliceStable(pages, func(i, j int) bool { return pages[i].priority > pages[j].priority })
+
+	seen := make(map[string]bool)
+	var checks []endpointCheck
+	offHost := 0
+	for _, page := range pages {
+		if len(checks) == max {
+			break
+		}
+		u, err := url.Parse(page.url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || seen[u.String()] {
+			continue
+		}
+		// A sitemap can list any URL, so only pages of the monitored site are checked
+		if !strings.EqualFold(u.Host, base.Host) {
+			offHost++
+			continue
+		}
+		seen[u.String()] = true
+		checks = append(checks, endpointCheck{method: http.MethodGet, path: u.RequestURI(), url: u.String()})
+	}
+	if offHost > 0 {
+		log.Printf("WARNING: Skipped %d sitemap pages not on %s", offHost, base.Host)
+	}
+	return checks, nil
+}
+
+// robotsSitemaps returns the URLs of the Sitemap: directives in the robots.txt at robotsURL
+func robotsSitemaps(client *http.Client, robotsURL string) ([]string, error) {
+	body, err := fetchDiscoveryDocument(client, robotsURL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var sitemaps []string
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		// Only the first colon ends the directive name, as the URL has its own
+		name, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), "sitemap") {
+			continue
+		}
+		if value, _, _ = strings.Cut(value, "#"); strings.TrimSpace(value) != "" {
+			sitemaps = append(sitemaps, strings.TrimSpace(value))
+		}
+	}
+	return sitemaps, scanner.Err()
+}
+
+// sitemapPages returns the pages listed in the sitemap at sitemapURL
+// A sitemap index is followed for up to depth levels
+func sitemapPages(client *http.Client, sitemapURL string, depth int) ([]sitemapPage, error) {
+	body, err := fetchDiscoveryDocument(client, sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var reader io.Reader = body
+	if strings.HasSuffix(strings.ToLower(sitemapURL), ".gz") {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = &sizeLimitReader{r: gz, remaining: maxBodySize}
+	}
+	var doc sitemapDocument
+	if err := xml.NewDecoder(reader).Decode(&doc); errors.Is(err, errSitemapTooLarge) {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("invalid sitemap XML: %v", err)
+	}
+
+	var pages []sitemapPage
+	for _, entry := range doc.URLs {
+		page := sitemapPage{url: strings.TrimSpace(entry.Loc), priority: 0.5}
+		if priority, err := strconv.ParseFloat(strings.TrimSpace(entry.Priority), 64); err == nil {
+			page.priority = priority
+		}
+		pages = append(pages, page)
+	}
+	for _, entry := range doc.Sitemaps {
+		if depth <= 1 {
+			log.Printf("WARNING: Not following sitemap %s nested more than %d levels deep", entry.Loc, maxSitemapDepth)
+			continue
+		}
+		nested, err := sitemapPages(client, strings.TrimSpace(entry.Loc), depth-1)
+		if err != nil {
+			log.Printf("WARNING: Cannot read sitemap %s: %v", entry.Loc, err)
+			continue
+		}
+		pages = append(pages, nested...)
+	}
+	return pages, nil
+}
+
+// errSitemapTooLarge is returned when a gzip sitemap decompresses to more than maxBodySize
+var errSitemapTooLarge = fmt.Errorf("sitemap is larger than %d bytes decompressed", maxBodySize)
+
+// sizeLimitReader fails with errSitemapTooLarge once more than remaining bytes
+// are read, where a LimitReader would cut the XML short and hide the cause
+type sizeLimitReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	// Read one byte past the limit to tell a stream that ends there from one that goes on
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.remaining {
+		return int(l.remaining), errSitemapTooLarge
+	}
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// fetchDiscoveryDocument returns the body of a successful GET of rawURL, limited to maxBodySize
+func fetchDiscoveryDocument(client *http.Client, rawURL string) (io.ReadCloser, error) {
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s returned status %d", rawURL, resp.StatusCode)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(resp.Body, maxBodySize), resp.Body}, nil
+}
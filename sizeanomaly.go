@@ -0,0 +1,70 @@
+package main
+
+import "math"
+
+// sizeTracker keeps the running mean and standard deviation of response body sizes
+// using Welford's algorithm, so no sizes need to be stored
+type sizeTracker struct {
+	warmup     int
+	limit      float64
+	rebaseline int
+
+	count int
+	mean  float64
+	m2    float64
+
+	// anomalies holds the sizes of the current run of anomalous checks
+	anomalies []float64
+}
+
+// newSizeTracker flags sizes more than limit standard deviations from the mean
+// once warmup sizes have been observed. After rebaseline anomalies in a row the
+// anomalous sizes become the new baseline (0 never re-baselines)
+func newSizeTracker(warmup int, limit float64, rebaseline int) *sizeTracker {
+	return &sizeTracker{warmup: warmup, limit: limit, rebaseline: rebaseline}
+}
+
+// observe reports whether size is anomalous, with its distance from the mean
+// in standard deviations and the mean it was compared against
+// Anomalous sizes are left out of the mean so that a page that stays truncated
+// keeps being reported, until the run of anomalies is long enough to be taken
+// as a permanent change, when rebaselined is true and the run replaces the
+// baseline. The standard deviation is taken to be at least 1% of the mean so
+// that a page whose size never changed does not alert on every byte
+func (t *sizeTracker) observe(size int) (anomaly, rebaselined bool, deviations, mean float64) {
+	x := float64(size)
+	if t.count >= t.warmup && t.count > 1 {
+		stddev := math.Max(math.Sqrt(t.m2/float64(t.count-1)), t.mean/100)
+		if stddev > 0 {
+			deviations = math.Abs(x-t.mean) / stddev
+		} else if x != t.mean {
+			deviations = math.Inf(1)
+		}
+		anomaly = deviations > t.limit
+	}
+	mean = t.mean
+	if !anomaly {
+		t.anomalies = t.anomalies[:0]
+		t.add(x)
+		return false, false, deviations, mean
+	}
+
+	t.anomalies = append(t.anomalies, x)
+	if t.rebaseline == 0 || len(t.anomalies) < t.rebaseline {
+		return true, false, deviations, mean
+	}
+	t.count, t.mean, t.m2 = 0, 0, 0
+	for _, size := range t.anomalies {
+		t.add(size)
+	}
+	t.anomalies = t.anomalies[:0]
+	return false, true, deviations, mean
+}
+
+// add folds x into the running mean and variance
+func (t *sizeTracker) add(x float64) {
+	t.count++
+	delta := x - t.mean
+	t.mean += delta / float64(t.count)
+	t.m2 += delta * (x - t.mean)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// sourceClient is an HTTP client bound to a single local source address
+type sourceClient struct {
+	addr   string
+	client *http.Client
+}
+
+// parseBindAddresses parses a comma-separated list of local IP addresses
+func parseBindAddresses(list string) ([]net.IP, error) {
+	var ips []net.IP
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		ip := net.ParseIP(field)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid bind address %q", field)
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+// checkFromSources checks url from every source address in parallel
+// Returns the source addresses from which the website is down and the first successful response
+// The returned error is non-nil only when the website is down from every source
+func checkFromSources(ctx context.Context, url string, sources []sourceClient, opts checkOptions) ([]string, *checkResult, error) {
+	errs := make([]error, len(sources))
+	results := make([]*checkResult, len(sources))
+
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func(i int, source sourceClient) {
+			defer wg.Done()
+			results[i], errs[i] = checkWebsiteDown(ctx, url, source.client, opts)
+		}(i, source)
+	}
+	wg.Wait()
+
+	var failed []string
+	var result *checkResult
+	for i, source := range sources {
+		if errs[i] != nil {
+			failed = append(failed, source.addr)
+		} else if result == nil {
+			result = results[i]
+		}
+	}
+
+	if len(failed) == len(sources) {
+		return failed, nil, fmt.Errorf("unreachable from all source addresses: %w", errors.Join(errs...))
+	}
+	return failed, result, nil
+}
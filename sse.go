@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sseHistorySize is how many check results are kept for clients reconnecting with Last-Event-ID
+const sseHistorySize = 1000
+
+// sseClientBuffer is how many results a GET /events/sse client may fall behind by
+// before it is disconnected
+const sseClientBuffer = 100
+
+// sseKeepAlive is how often an idle stream sends a comment so proxies keep it open
+const sseKeepAlive = 15 * time.Second
+
+// sseEntry is one check result as sent in the stream
+type sseEntry struct {
+	id   uint64
+	data []byte
+}
+
+// resultStream sends every check result to GET /events/sse clients as server-sent events
+// The last sseHistorySize results are kept so a reconnecting client can catch up
+type resultStream struct {
+	mu      sync.Mutex
+	nextID  uint64
+	history []sseEntry
+	clients map[chan sseEntry]struct{}
+}
+
+// newResultStream creates a stream with no results or clients
+func newResultStream() *resultStream {
+	return &resultStream{nextID: 1, clients: make(map[chan sseEntry]struct{})}
+}
+
+// publish numbers a check result and sends it to every client
+// A client that has fallen too far behind is disconnected rather than waited for
+func (s *resultStream) publish(status urlStatus) {
+	data, err := json.Marshal(status)
+	if err != nil {
+		log.Printf("Failed to encode check result for the event stream: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := sseEntry{id: s.nextID, data: data}
+	s.nextID++
+	if len(s.history) == sseHistorySize {
+		s.history = append(s.history[:0], s.history[1:]...)
+	}
+	s.history = append(s.history, entry)
+	for client := range s.clients {
+		select {
+		case client <- entry:
+		default:
+			delete(s.clients, client)
+			close(client)
+		}
+	}
+}
+
+// subscribe registers a client and returns the kept results after lastID
+func (s *resultStream) subscribe(lastID uint64) (chan sseEntry, []sseEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var missed []sseEntry
+	for _, entry := range s.history {
+		if entry.id > lastID {
+			missed = append(missed, entry)
+		}
+	}
+	client := make(chan sseEntry, sseClientBuffer)
+	s.clients[client] = struct{}{}
+	return client, missed
+}
+
+// unsubscribe removes a client unless it was already dropped
+func (s *resultStream) unsubscribe(client chan sseEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.clients[client]; ok {
+		delete(s.clients, client)
+		close(client)
+	}
+}
+
+// handleSSE streams check results as text/event-stream until the client disconnects
+// A Last-Event-ID header resumes after that result if it is still kept
+func (s *resultStream) handleSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	var lastID uint64
+	if header := r.Header.Get("Last-Event-ID"); header != "" {
+		id, err := strconv.ParseUint(strings.TrimSpace(header), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid Last-Event-ID header", http.StatusBadRequest)
+			return
+		}
+		lastID = id
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	client, missed := s.subscribe(lastID)
+	defer s.unsubscribe(client)
+	for _, entry := range missed {
+		if err := writeSSE(w, entry); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case entry, ok := <-client:
+			if !ok {
+				log.Printf("WARNING: Event stream client %s fell %d results behind, disconnecting", r.RemoteAddr, sseClientBuffer)
+				return
+			}
+			if err := writeSSE(w, entry); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeSSE writes one check result in server-sent events format
+func writeSSE(w http.ResponseWriter, entry sseEntry) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: check\ndata: %s\n\n", entry.id, entry.data)
+	return err
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// defaultStatePath returns the state file for kind when its flag is not set
+// The name includes a hash of the monitored URL, so checks run side by side,
+// such as under -config-dir, do not overwrite each other's state
+func defaultStatePath(kind, url string) string {
+	digest := sha256.Sum256([]byte(url))
+	return fmt.Sprintf("websitecheck-%s-%s.state", kind, hex.EncodeToString(digest[:6]))
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// urlStatus is the latest check outcome for a URL as reported by GET /status
+type urlStatus struct {
+	URL                 string    `json:"url"`
+	Up                  bool      `json:"up"`
+	LastCheck           time.Time `json:"last_check"`
+	LastCheckLocal      string    `json:"last_check_local,omitempty"`
+	LastError           string    `json:"last_error,omitempty"`
+	StatusCode          int       `json:"status_code,omitempty"`
+	LatencyMS           int64     `json:"latency_ms,omitempty"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	FinalURL            string    `json:"final_url,omitempty"`
+
+	// KernelLatencyMS is the latency measured in the kernel with -ebpf-latency
+	KernelLatencyMS float64 `json:"kernel_latency_ms,omitempty"`
+
+	// Phases are the spans of the last check, showing which phase was slow
+	Phases []phaseSpan `json:"phases,omitempty"`
+
+	// NextCheck is when the URL is checked again, later than usual while backing off
+	NextCheck time.Time `json:"next_check"`
+
+	// Uptime30d is the percentage uptime over the last 30 days, if known
+	Uptime30d *float64 `json:"uptime_30d,omitempty"`
+}
+
+// statusTracker holds the latest status of each monitored URL
+// The monitoring loop writes it and the API server reads it
+type statusTracker struct {
+	history checkHistory
+
+	mu     sync.RWMutex
+	checks map[string]urlStatus
+
+	// results, if set, streams every recorded outcome to GET /events/sse
+	results *resultStream
+}
+
+// newStatusTracker creates an empty status tracker that records outcomes in history
+func newStatusTracker(history checkHistory) *statusTracker {
+	return &statusTracker{history: history, checks: make(map[string]urlStatus)}
+}
+
+// record stores the outcome of a check cycle for url
+func (t *statusTracker) record(url string, result *checkResult, checkErr error, consecutiveFailures int, nextCheck time.Duration) {
+	now := time.Now().UTC()
+	status := urlStatus{
+		URL:                 url,
+		Up:                  checkErr == nil,
+		LastCheck:           now,
+		ConsecutiveFailures: consecutiveFailures,
+		NextCheck:           now.Add(nextCheck),
+	}
+	if checkErr != nil {
+		status.LastError = checkErr.Error()
+	}
+	status.Phases = checkPhases(result, checkErr)
+	if result != nil {
+		status.StatusCode = result.StatusCode
+		status.LatencyMS = result.Latency.Milliseconds()
+		status.KernelLatencyMS = milliseconds(result.KernelLatency)
+		status.FinalURL = result.finalURL()
+	}
+
+	t.history.record(url, status.LastCheck, status.Up)
+
+	t.mu.Lock()
+	t.checks[url] = status
+	t.mu.Unlock()
+
+	if t.results != nil {
+		status.LastCheckLocal = localTime(status.LastCheck)
+		t.results.publish(status)
+	}
+}
+
+// snapshot returns the status of every URL sorted by URL
+func (t *statusTracker) snapshot() []urlStatus {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	now := time.Now()
+	statuses := make([]urlStatus, 0, len(t.checks))
+	for _, status := range t.checks {
+		status.LastCheckLocal = localTime(status.LastCheck)
+		if uptime, ok := t.history.uptime(status.URL, now); ok {
+			status.Uptime30d = &uptime
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].URL < statuses[j].URL })
+	return statuses
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultSuppressDuration is used when a silence or POST /suppress has no duration
+const defaultSuppressDuration = 300 * time.Second
+
+// alertSilence is a suppression as listed in GET /status
+type alertSilence struct {
+	URL    string    `json:"url"`
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// suppressionList records URLs whose ELF executions are suppressed until a deadline
+// It lives in memory only, so suppressions survive SIGHUP reloads but not restarts
+type suppressionList struct {
+	mu       sync.Mutex
+	silences map[string]alertSilence
+}
+
+// alertSuppressions is consulted before every ELF execution
+var alertSuppressions = &suppressionList{silences: make(map[string]alertSilence)}
+
+// silence suppresses url for d with an optional reason, such as an operator's
+// acknowledgement, replacing any earlier suppression. A zero duration lifts it
+func (s *suppressionList) silence(url string, d time.Duration, reason string) alertSilence {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d <= 0 {
+		delete(s.silences, url)
+		return alertSilence{}
+	}
+	now := time.Now()
+	silence := alertSilence{URL: url, Since: now, Until: now.Add(d), Reason: reason}
+	s.silences[url] = silence
+	return silence
+}
+
+// lift ends the suppression for url early, reporting whether one was in effect
+func (s *suppressionList) lift(url string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	silence, ok := s.silences[url]
+	delete(s.silences, url)
+	return ok && time.Now().Before(silence.Until)
+}
+
+// active returns when the suppression for url ends, if one is in effect
+func (s *suppressionList) active(url string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	silence, ok := s.silences[url]
+	if !ok {
+		return time.Time{}, false
+	}
+	if !time.Now().Before(silence.Until) {
+		delete(s.silences, url)
+		return time.Time{}, false
+	}
+	return silence.Until, true
+}
+
+// list returns the suppressions in effect, sorted by URL
+func (s *suppressionList) list() []alertSilence {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	silences := make([]alertSilence, 0, len(s.silences))
+	for url, silence := range s.silences {
+		if !now.Before(silence.Until) {
+			delete(s.silences, url)
+			continue
+		}
+		silences = append(silences, silence)
+	}
+	sort.Slice(silences, func(i, j int) bool { return silences[i].URL < silences[j].URL })
+	return silences
+}
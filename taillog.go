@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tailPollInterval is how often the log file is checked for new lines
+const tailPollInterval = time.Second
+
+// logFormats are the built-in access log formats for -log-format
+var logFormats = map[string]string{
+	"common":   `^(?P<client>\S+) \S+ (?P<user>\S+) \[(?P<timestamp>[^\]]+)\] "(?P<request>[^"]*)" (?P<status>\d{3}) (?P<bytes>\S+)`,
+	"combined": `^(?P<client>\S+) \S+ (?P<user>\S+) \[(?P<timestamp>[^\]]+)\] "(?P<request>[^"]*)" (?P<status>\d{3}) (?P<bytes>\S+) "(?P<referrer>[^"]*)" "(?P<agent>[^"]*)"`,
+}
+
+// grokPatterns are the named patterns available as %{NAME:field} in -log-pattern
+var grokPatterns = map[string]string{
+	"INT":               `[+-]?\d+`,
+	"NUMBER":            `[+-]?\d+(?:\.\d+)?`,
+	"WORD":              `\w+`,
+	"NOTSPACE":          `\S+`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"IP":                `[0-9A-Fa-f:.]+`,
+	"IPORHOST":          `\S+`,
+	"QS":                `"(?:[^"\\]|\\.)*"`,
+	"HTTPDATE":          `\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`,
+	"TIMESTAMP_ISO8601": `\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})?`,
+}
+
+// grokReference matches %{NAME} and %{NAME:field} in a grok pattern
+var grokReference = regexp.MustCompile(`%\{(\w+)(?::(\w+))?\}`)
+
+// compileGrok converts a grok-style pattern into a regular expression
+// Text outside %{...} references is used as regular expression syntax
+func compileGrok(pattern string) (*regexp.Regexp, error) {
+	var unknown []string
+	expanded := grokReference.ReplaceAllStringFunc(pattern, func(ref string) string {
+		parts := grokReference.FindStringSubmatch(ref)
+		re, ok := grokPatterns[parts[1]]
+		if !ok {
+			unknown = append(unknown, parts[1])
+			return ref
+		}
+		if parts[2] == "" {
+			return "(?:" + re + ")"
+		}
+		return "(?P<" + parts[2] + ">" + re + ")"
+	})
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown grok patterns: %s", strings.Join(unknown, ", "))
+	}
+	return regexp.Compile(expanded)
+}
+
+// logLineParser extracts the status code and time from access log lines
+type logLineParser struct {
+	re        *regexp.Regexp
+	status    int
+	timestamp int
+}
+
+// newLogLineParser builds a parser from a -log-format name or a custom grok pattern
+// The pattern must capture a status field and may capture a timestamp field
+func newLogLineParser(format, pattern string) (*logLineParser, error) {
+	var re *regexp.Regexp
+	var err error
+	if pattern != "" {
+		re, err = compileGrok(pattern)
+	} else if builtin, ok := logFormats[format]; ok {
+		re, err = regexp.Compile(builtin)
+	} else {
+		return nil, fmt.Errorf("unknown log format %q (expected common or combined)", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p := &logLineParser{re: re, status: re.SubexpIndex("status"), timestamp: re.SubexpIndex("timestamp")}
+	if p.status < 0 {
+		return nil, errors.New("log pattern must capture a status field, e.g. %{NUMBER:status}")
+	}
+	return p, nil
+}
+
+// parse returns the status code and time of a log line
+// Lines without a parseable timestamp use the time they were read
+func (p *logLineParser) parse(line string, now time.Time) (int, time.Time, bool) {
+	match := p.re.FindStringSubmatch(line)
+	if match == nil {
+		return 0, time.Time{}, false
+	}
+	status, err := strconv.Atoi(match[p.status])
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+
+	at := now
+	if p.timestamp >= 0 {
+		for _, layout := range []string{"02/Jan/2006:15:04:05 -0700", time.RFC3339Nano, "2006-01-02 15:04:05"} {
+			if t, err := time.Parse(layout, match[p.timestamp]); err == nil {
+				at = t
+				break
+			}
+		}
+	}
+	return status, at, true
+}
+
+// logRequest is one request seen in the log
+type logRequest struct {
+	at          time.Time
+	serverError bool
+}
+
+// logTailer follows an access log and tracks the 5xx rate over a sliding window
+// Only lines written after it starts are counted
+type logTailer struct {
+	path      string
+	parser    *logLineParser
+	window    time.Duration
+	threshold float64
+
+	mu       sync.Mutex
+	requests []logRequest
+}
+
+// newLogTailer opens path and starts following it from the current end
+func newLogTailer(path string, parser *logLineParser, window time.Duration, threshold float64) (*logTailer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	t := &logTailer{path: path, parser: parser, window: window, threshold: threshold}
+	go t.follow(f)
+	return t, nil
+}
+
+// follow reads new lines as they are appended
+// The file is reopened from the start when it is truncated or rotated
+func (t *logTailer) follow(f *os.File) {
+	reader := bufio.NewReader(f)
+	var partial string
+	for {
+		line, err := reader.ReadString('\n')
+		if err == nil {
+			t.add(partial + line)
+			partial = ""
+			continue
+		}
+		partial += line
+		time.Sleep(tailPollInterval)
+
+		if reopened := t.reopenIfRotated(f); reopened != nil {
+			f.Close()
+			f = reopened
+			reader.Reset(f)
+			partial = ""
+		}
+	}
+}
+
+// reopenIfRotated returns a new handle on the log path if it no longer refers to f
+// or if f has been truncated below the current read position
+func (t *logTailer) reopenIfRotated(f *os.File) *os.File {
+	current, err := f.Stat()
+	if err != nil {
+		return nil
+	}
+	latest, err := os.Stat(t.path)
+	if err != nil {
+		return nil
+	}
+	offset, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil
+	}
+	if os.SameFile(current, latest) && latest.Size() >= offset {
+		return nil
+	}
+
+	reopened, err := os.Open(t.path)
+	if err != nil {
+		log.Printf("Cannot reopen rotated log %s: %v", t.path, err)
+		return nil
+	}
+	log.Printf("Log %s was rotated or truncated, reading from the start", t.path)
+	return reopened
+}
+
+// add records the request on a log line, ignoring lines that do not match
+func (t *logTailer) add(line string) {
+	status, at, ok := t.parser.parse(strings.TrimRight(line, "\r\n"), time.Now())
+	if !ok {
+		return
+	}
+
+	t.mu.Lock()
+	t.requests = append(t.requests, logRequest{at: at, serverError: status >= 500 && status <= 599})
+	t.mu.Unlock()
+}
+
+// check computes the 5xx rate over the window
+// Returns an error if the rate exceeds the threshold
+func (t *logTailer) check(verbose bool) error {
+	cutoff := time.Now().Add(-t.window)
+
+	t.mu.Lock()
+	kept := t.requests[:0]
+	for _, r := range t.requests {
+		if !r.at.Before(cutoff) {
+			kept = append(kept, r)
+		}
+	}
+	t.requests = kept
+	total := len(kept)
+	errorCount := 0
+	for _, r := range kept {
+		if r.serverError {
+			errorCount++
+		}
+	}
+	t.mu.Unlock()
+
+	if total == 0 {
+		if verbose {
+			log.Printf("No requests logged in %s in the last %s", t.path, t.window)
+		}
+		return nil
+	}
+
+	rate := float64(errorCount) * 100 / float64(total)
+	if verbose {
+		log.Printf("Error rate in %s: %.1f%% (%d of %d requests) over the last %s", t.path, rate, errorCount, total, t.window)
+	}
+	if rate > t.threshold {
+		return fmt.Errorf("5xx error rate %.1f%% (%d of %d requests) over the last %s exceeds %.1f%%", rate, errorCount, total, t.window, t.threshold)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// tcpEscapes expands the escape sequences accepted in -tcp-send and -tcp-expect
+var tcpEscapes = strings.NewReplacer(`\\`, `\`, `\r`, "\r", `\n`, "\n", `\t`, "\t")
+
+// parseTCPEscapes expands \r, \n, \t and \\ in s
+func parseTCPEscapes(s string) string {
+	return tcpEscapes.Replace(s)
+}
+
+// tcpProbe describes a text protocol handshake: bytes to send and a reply to wait for
+type tcpProbe struct {
+	send    []byte
+	expect  []byte
+	timeout time.Duration
+}
+
+// checkTCP connects to addr and runs probe, retrying like checkWebsiteDown
+// The response read while waiting for the expected string is returned as the body
+func checkTCP(addr string, probe tcpProbe, opts checkOptions) (*checkResult, error) {
+	return retryCheck("TCP", opts, func() (*checkResult, error) {
+		return probe.run(addr)
+	})
+}
+
+// run performs a single connection attempt
+// The whole exchange, including the connect, must finish within the probe timeout
+func (p tcpProbe) run(addr string) (*checkResult, error) {
+	start := time.Now()
+	deadline := start.Add(p.timeout)
+
+	conn, err := net.DialTimeout("tcp", addr, p.timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(deadline)
+
+	if len(p.send) > 0 {
+		if _, err := conn.Write(p.send); err != nil {
+			return nil, fmt.Errorf("sending to %s: %v", addr, err)
+		}
+	}
+
+	var received []byte
+	if len(p.expect) > 0 {
+		buf := make([]byte, 4096)
+		for !bytes.Contains(received, p.expect) {
+			n, err := conn.Read(buf)
+			received = append(received, buf[:n]...)
+			if len(received) > maxBodySize {
+				return nil, fmt.Errorf("%q not found in the first %d bytes from %s", p.expect, maxBodySize, addr)
+			}
+			if err != nil && !bytes.Contains(received, p.expect) {
+				return nil, fmt.Errorf("%q not received from %s (got %q): %v", p.expect, addr, tcpSnippet(received), err)
+			}
+		}
+	}
+
+	return &checkResult{Body: received, Latency: time.Since(start)}, nil
+}
+
+// tcpSnippet shortens a received payload for error messages
+func tcpSnippet(received []byte) string {
+	const max = 80
+	s := strings.TrimSpace(string(received))
+	if len(s) > max {
+		s = s[:max] + "..."
+	}
+	return s
+}
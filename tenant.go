@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Headers identifying the tenant making a serve API request
+const (
+	tenantIDHeader = "X-Tenant-ID"
+	apiKeyHeader   = "X-API-Key"
+)
+
+// tenantKeys maps each tenant to its API key, as read from -tenants-file
+type tenantKeys map[string]string
+
+// loadTenantKeys reads a -tenants-file of the form
+//
+//	api_keys:
+//	  team-a: secret
+func loadTenantKeys(path string) (tenantKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		APIKeys map[string]string `yaml:"api_keys"`
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid tenants file %s: %v", path, err)
+	}
+	if len(file.APIKeys) == 0 {
+		return nil, fmt.Errorf("tenants file %s defines no api_keys", path)
+	}
+	for tenant, key := range file.APIKeys {
+		if tenant == "" || key == "" {
+			return nil, fmt.Errorf("tenants file %s: tenant names and API keys must not be empty", path)
+		}
+	}
+	return tenantKeys(file.APIKeys), nil
+}
+
+// authenticate returns the tenant whose ID and API key the request carries
+func (k tenantKeys) authenticate(r *http.Request) (string, error) {
+	tenant := r.Header.Get(tenantIDHeader)
+	if tenant == "" {
+		return "", errors.New(tenantIDHeader + " header is required")
+	}
+	want, ok := k[tenant]
+	got := r.Header.Get(apiKeyHeader)
+	if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+		return "", errors.New("invalid tenant or API key")
+	}
+	return tenant, nil
+}
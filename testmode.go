@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// mockControlPath is the endpoint used to switch the mock server between up and down
+// POST /__websitecheck/up or /__websitecheck/down; GET reports the current state
+const mockControlPath = "/__websitecheck/"
+
+// mockServer is the local server monitored in -test-mode
+type mockServer struct {
+	*httptest.Server
+	status  int
+	body    string
+	latency time.Duration
+
+	mu   sync.Mutex
+	down bool
+}
+
+// newMockServer starts a server that answers every request with status and body after latency
+// While toggled down it answers with 503 instead
+func newMockServer(status int, body string, latency time.Duration) *mockServer {
+	m := &mockServer{status: status, body: body, latency: latency}
+	mux := http.NewServeMux()
+	mux.HandleFunc(mockControlPath, m.control)
+	mux.HandleFunc("/", m.serve)
+	m.Server = httptest.NewServer(mux)
+	return m
+}
+
+// serve answers a monitored request
+func (m *mockServer) serve(w http.ResponseWriter, r *http.Request) {
+	time.Sleep(m.latency)
+
+	m.mu.Lock()
+	down := m.down
+	m.mu.Unlock()
+
+	if down {
+		http.Error(w, "mock server is down", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(m.status)
+	fmt.Fprint(w, m.body)
+}
+
+// control switches the mock server between up and down
+func (m *mockServer) control(w http.ResponseWriter, r *http.Request) {
+	state := r.URL.Path[len(mockControlPath):]
+	if r.Method == http.MethodPost {
+		switch state {
+		case "up", "down":
+			m.mu.Lock()
+			m.down = state == "down"
+			m.mu.Unlock()
+			log.Printf("Mock server toggled %s", state)
+		default:
+			http.Error(w, "expected "+mockControlPath+"up or "+mockControlPath+"down", http.StatusNotFound)
+			return
+		}
+	} else if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	m.mu.Lock()
+	down := m.down
+	m.mu.Unlock()
+	if down {
+		fmt.Fprintln(w, "down")
+	} else {
+		fmt.Fprintln(w, "up")
+	}
+}
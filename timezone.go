@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io"
+	"log"
+	"time"
+)
+
+// localTimeFormat is how times are shown to people in the -timezone location
+const localTimeFormat = "2006-01-02 15:04:05 MST"
+
+// displayLocation is the -timezone location for log and status page timestamps
+// Stored and machine-readable times stay in UTC
+var displayLocation = time.UTC
+
+// setTimezone loads name with time.LoadLocation and stamps log lines in it from now on
+func setTimezone(name string, out io.Writer) error {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return err
+	}
+	displayLocation = loc
+	log.SetFlags(0)
+	log.SetOutput(&zonedLogWriter{out: out})
+	return nil
+}
+
+// zonedLogWriter prefixes each log line with the time in displayLocation
+// The log package serializes writes, so no locking is needed here. The zone is
+// only named outside UTC, so the default output is unchanged
+type zonedLogWriter struct {
+	out io.Writer
+}
+
+func (w *zonedLogWriter) Write(p []byte) (int, error) {
+	layout := "2006/01/02 15:04:05 "
+	if displayLocation != time.UTC {
+		layout = "2006/01/02 15:04:05 MST "
+	}
+	line := append([]byte(time.Now().In(displayLocation).Format(layout)), p...)
+	if _, err := w.out.Write(line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// localTime formats t in displayLocation for human-readable fields
+// It is empty in UTC, where the UTC timestamp alongside it already says the same
+func localTime(t time.Time) string {
+	if displayLocation == time.UTC || t.IsZero() {
+		return ""
+	}
+	return t.In(displayLocation).Format(localTimeFormat)
+}
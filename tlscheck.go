@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+)
+
+// checkTLS performs a TLS handshake with addr and verifies the certificate for serverName
+// Retries follow the same policy as the HTTP and TCP checks
+func checkTLS(addr, serverName string, timeout time.Duration, opts checkOptions) (*checkResult, error) {
+	var lastErr error
+	for i := 0; i < opts.retries; i++ {
+		// Wait a little between attempts
+		if i > 0 {
+			time.Sleep(2 * time.Second)
+		}
+
+		start := time.Now()
+		dialer := &net.Dialer{Timeout: timeout}
+		config := &tls.Config{ServerName: serverName}
+		requireTLSVersion(config, opts.minTLSVersion)
+		conn, err := tls.DialWithDialer(dialer, "tcp", addr, config)
+		if err != nil {
+			if opts.verbose {
+				log.Printf("TLS handshake failed (attempt %d/%d): %v", i+1, opts.retries, err)
+			}
+			lastErr = fmt.Errorf("TLS handshake with %s: %w", addr, err)
+			if !opts.retry.retryError(err) {
+				break
+			}
+			continue
+		}
+		state := conn.ConnectionState()
+		conn.Close()
+
+		// A pin mismatch will not fix itself on retry
+		if opts.certPin != "" {
+			if err := verifyCertPin(&state, opts.certPin); err != nil {
+				return nil, err
+			}
+		}
+		if opts.allowedCiphers != nil {
+			if err := verifyCipherSuite(&state, opts.allowedCiphers); err != nil {
+				return nil, err
+			}
+		}
+		return &checkResult{TLS: &state, Latency: time.Since(start)}, nil
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("no check attempts were made")
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// tlsVersions maps -min-tls-version values to crypto/tls versions
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// parseTLSVersion validates a -min-tls-version value
+func parseTLSVersion(s string) (uint16, error) {
+	version, ok := tlsVersions[s]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q (expected 1.0, 1.1, 1.2 or 1.3)", s)
+	}
+	return version, nil
+}
+
+// tlsVersionError is returned when a server negotiates an older TLS version than required
+type tlsVersionError struct {
+	version uint16
+	min     uint16
+}
+
+func (e *tlsVersionError) Error() string {
+	return fmt.Sprintf("server negotiated %s, older than the required %s", tls.VersionName(e.version), tls.VersionName(e.min))
+}
+
+// requireTLSVersion makes handshakes with config fail with a *tlsVersionError
+// when the server negotiates a version older than min, 0 meaning Go's default of TLS 1.2
+// The handshake itself is allowed down to TLS 1.0 so an old server is reported
+// as such, and is failed before any request is sent
+func requireTLSVersion(config *tls.Config, min uint16) {
+	if min == 0 {
+		min = tls.VersionTLS12
+	}
+	config.MinVersion = tls.VersionTLS10
+	verify := config.VerifyConnection
+	config.VerifyConnection = func(state tls.ConnectionState) error {
+		if state.Version < min {
+			return &tlsVersionError{version: state.Version, min: min}
+		}
+		if verify != nil {
+			return verify(state)
+		}
+		return nil
+	}
+}
+
+// withMinTLSVersion makes client refuse servers that cannot negotiate at least version,
+// 0 meaning Go's default minimum
+func withMinTLSVersion(client *http.Client, version uint16) {
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		if client.Transport != nil {
+			log.Printf("Warning: cannot set the minimum TLS version with a custom transport")
+			return
+		}
+		transport = http.DefaultTransport.(*http.Transport)
+	}
+	transport = transport.Clone()
+
+	config := &tls.Config{}
+	if transport.TLSClientConfig != nil {
+		config = transport.TLSClientConfig.Clone()
+	}
+	requireTLSVersion(config, version)
+	transport.TLSClientConfig = config
+	client.Transport = transport
+}
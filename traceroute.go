@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// Traceroute limits
+const (
+	tracerouteMaxHops    = 30
+	tracerouteHopTimeout = time.Second
+)
+
+// traceroute returns a textual route to host for diagnostics.
+// It uses raw ICMP with increasing TTLs, which needs CAP_NET_RAW, and falls
+// back to the system traceroute binary when raw sockets are unavailable.
+func traceroute(host string) (string, error) {
+	route, err := icmpTraceroute(host)
+	if err == nil {
+		return route, nil
+	}
+
+	path, lookErr := exec.LookPath("traceroute")
+	if lookErr != nil {
+		return "", fmt.Errorf("raw ICMP traceroute failed (%v) and no traceroute binary found", err)
+	}
+
+	cmd := exec.Command(path, "-n", "-w", "1", "-m", fmt.Sprint(tracerouteMaxHops), host)
+	output, cmdErr := cmd.CombinedOutput()
+	if cmdErr != nil && len(output) == 0 {
+		return "", fmt.Errorf("traceroute failed: %v", cmdErr)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// icmpTraceroute traces the route to an IPv4 host by sending ICMP echo
+// requests with increasing TTLs and recording the Time Exceeded senders
+func icmpTraceroute(host string) (string, error) {
+	dst, err := net.ResolveIPAddr("ip4", host)
+	if err != nil {
+		return "", err
+	}
+
+	conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	id := os.Getpid() & 0xffff
+	packetConn := conn.IPv4PacketConn()
+
+	var route strings.Builder
+	fmt.Fprintf(&route, "traceroute to %s (%s), %d hops max\n", host, dst.IP, tracerouteMaxHops)
+
+	buf := make([]byte, 1500)
+	for ttl := 1; ttl <= tracerouteMaxHops; ttl++ {
+		if err := packetConn.SetTTL(ttl); err != nil {
+			return "", err
+		}
+
+		msg := icmp.Message{
+			Type: ipv4.ICMPTypeEcho,
+			Body: &icmp.Echo{ID: id, Seq: ttl, Data: []byte("websitecheck")},
+		}
+		wb, err := msg.Marshal(nil)
+		if err != nil {
+			return "", err
+		}
+
+		start := time.Now()
+		if _, err := conn.WriteTo(wb, dst); err != nil {
+			return "", err
+		}
+
+		peer, reached, err := readTracerouteReply(conn, buf, id, ttl, start.Add(tracerouteHopTimeout))
+		if err != nil {
+			fmt.Fprintf(&route, "%2d  *\n", ttl)
+			continue
+		}
+		fmt.Fprintf(&route, "%2d  %s  %s\n", ttl, peer, time.Since(start).Round(100*time.Microsecond))
+		if reached {
+			break
+		}
+	}
+
+	return strings.TrimSpace(route.String()), nil
+}
+
+// readTracerouteReply waits for the reply to the probe with the given ID and sequence
+// Returns the replying hop and whether it was the destination itself
+func readTracerouteReply(conn *icmp.PacketConn, buf []byte, id, seq int, deadline time.Time) (net.Addr, bool, error) {
+	conn.SetReadDeadline(deadline)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			return nil, false, err
+		}
+
+		reply, err := icmp.ParseMessage(1, buf[:n])
+		if err != nil {
+			continue
+		}
+
+		switch body := reply.Body.(type) {
+		case *icmp.Echo:
+			if reply.Type == ipv4.ICMPTypeEchoReply && body.ID == id && body.Seq == seq {
+				return peer, true, nil
+			}
+		case *icmp.TimeExceeded:
+			if probeMatches(body.Data, id, seq) {
+				return peer, false, nil
+			}
+		case *icmp.DstUnreach:
+			if probeMatches(body.Data, id, seq) {
+				return peer, true, nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return nil, false, errors.New("timeout")
+		}
+	}
+}
+
+// probeMatches reports whether the original datagram quoted in an ICMP error was our probe
+func probeMatches(data []byte, id, seq int) bool {
+	header, err := ipv4.ParseHeader(data)
+	if err != nil || len(data) < header.Len+8 {
+		return false
+	}
+	quoted := data[header.Len:]
+	probe := []byte{byte(id >> 8), byte(id), byte(seq >> 8), byte(seq)}
+	return bytes.Equal(quoted[4:8], probe)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// triggerResult is the body of POST /checks/{url}/trigger
+type triggerResult struct {
+	URL           string        `json:"url"`
+	Up            bool          `json:"up"`
+	Time          time.Time     `json:"time"`
+	LocalTime     string        `json:"local_time,omitempty"`
+	StatusCode    int           `json:"status_code,omitempty"`
+	LatencyMS     int64         `json:"latency_ms,omitempty"`
+	FinalURL      string        `json:"final_url,omitempty"`
+	Error         string        `json:"error,omitempty"`
+	FailureReason FailureReason `json:"failure_reason,omitempty"`
+}
+
+// handleTrigger checks the URL in the path once and returns the result
+// The url path value must be escaped, e.g. https:%2F%2Fexample.com. The check
+// takes the cycle lock, so it waits for a scheduled check in progress, up to
+// -trigger-timeout, and a scheduled check waits for it. It does not move the
+// next scheduled check and is not recorded in the status
+func (s *apiServer) handleTrigger(w http.ResponseWriter, r *http.Request) {
+	url, ok := s.monitoredURL(w, r)
+	if !ok {
+		return
+	}
+	if s.trigger == nil {
+		http.Error(w, "checks of -tail-log cannot be triggered", http.StatusNotImplemented)
+		return
+	}
+
+	log.Printf("Running triggered check of %s (requested by %s)", url, r.RemoteAddr)
+	type outcome struct {
+		result *checkResult
+		err    error
+	}
+	// Checks that do not take a context are abandoned rather than cancelled on timeout
+	ctx, cancel := context.WithTimeout(r.Context(), s.triggerTimeout)
+	defer cancel()
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := s.trigger(ctx)
+		done <- outcome{result, err}
+	}()
+
+	var checked outcome
+	select {
+	case checked = <-done:
+	case <-ctx.Done():
+		if r.Context().Err() != nil {
+			return
+		}
+		checked.err = withReason(FailureTimeout, fmt.Errorf("check did not finish within %s", s.triggerTimeout))
+	}
+
+	now := time.Now().UTC()
+	response := triggerResult{URL: url, Up: checked.err == nil, Time: now, LocalTime: localTime(now)}
+	if checked.result != nil {
+		response.StatusCode = checked.result.StatusCode
+		response.LatencyMS = checked.result.Latency.Milliseconds()
+		response.FinalURL = checked.result.finalURL()
+	}
+	if checked.err != nil {
+		response.Error = checked.err.Error()
+		response.FailureReason = classifyError(checked.err, nil)
+		log.Printf("Triggered check of %s failed: %v", url, checked.err)
+	} else {
+		log.Printf("Triggered check of %s passed", url)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// monitors reports whether url is one of the scheduled checks
+func (s *apiServer) monitors(url string) bool {
+	for _, schedule := range s.schedules {
+		if scheduled, err := normalizeURL(schedule.url); err == nil && scheduled == url {
+			return true
+		}
+	}
+	return false
+}
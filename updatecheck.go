@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// latestReleaseURL is the GitHub API endpoint for the newest websitecheck release
+const latestReleaseURL = "https://api.github.com/repos/pbelx/websitecheck/releases/latest"
+
+// Update checks run once a day with their own short timeout
+const (
+	updateCheckInterval = 24 * time.Hour
+	updateCheckTimeout  = 10 * time.Second
+)
+
+// startUpdateCheck looks for a newer release now and then once a day in the background
+// Failures are only logged, so an unreachable GitHub never affects monitoring
+func startUpdateCheck(verbose bool) {
+	if _, ok := parseVersion(Version); !ok {
+		log.Printf("Not checking for updates: %q is not a release version", Version)
+		return
+	}
+	client := &http.Client{Timeout: updateCheckTimeout}
+	go func() {
+		for {
+			latest, err := latestRelease(client)
+			switch {
+			case err != nil:
+				log.Printf("Update check failed: %v", err)
+			case newerVersion(latest, Version):
+				log.Printf("NOTICE: websitecheck %s is available, this is %s", latest, Version)
+			case verbose:
+				log.Printf("websitecheck %s is the latest release", Version)
+			}
+			time.Sleep(updateCheckInterval)
+		}
+	}()
+}
+
+// latestRelease returns the tag of the newest release on GitHub
+func latestRelease(client *http.Client) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, latestReleaseURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "websitecheck/"+Version)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GitHub returned status %d", resp.StatusCode)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", fmt.Errorf("invalid release response: %v", err)
+	}
+	if release.TagName == "" {
+		return "", fmt.Errorf("release has no tag")
+	}
+	return release.TagName, nil
+}
+
+// parseVersion parses a version such as v1.2.3 into its numeric parts
+// Any pre-release or build suffix is ignored
+func parseVersion(version string) ([]int, bool) {
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	var parts []int
+	for _, field := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, len(parts) > 0
+}
+
+// newerVersion reports whether latest is a later version than current
+func newerVersion(latest, current string) bool {
+	l, ok := parseVersion(latest)
+	c, ok2 := parseVersion(current)
+	if !ok || !ok2 {
+		return false
+	}
+	for i := 0; i < max(len(l), len(c)); i++ {
+		var a, b int
+		if i < len(l) {
+			a = l[i]
+		}
+		if i < len(c) {
+			b = c[i]
+		}
+		if a != b {
+			return a > b
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// normalizeURL returns the canonical form of rawURL so that equivalent
+// spellings of a URL are checked, logged and stored the same way.
+// The scheme and host are lowercased, the fragment is removed because it is
+// never sent to the server, an empty HTTP path becomes "/" and the query
+// parameters are sorted.
+func normalizeURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.ForceQuery = false
+
+	if (u.Scheme == "http" || u.Scheme == "https") && u.Path == "" && u.Opaque == "" {
+		u.Path = "/"
+		u.RawPath = ""
+	}
+
+	if u.RawQuery != "" {
+		query, err := url.ParseQuery(u.RawQuery)
+		if err != nil {
+			return "", err
+		}
+		// Encode sorts by key; values for the same key keep their order
+		u.RawQuery = query.Encode()
+	}
+	return u.String(), nil
+}
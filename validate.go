@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// positiveFlags must be greater than zero
+var positiveFlags = []string{"interval", "timeout", "max-backoff", "initial-backoff", "tail-window", "openapi-workers", "history-memory", "min-interval", "benchmark-requests", "benchmark-concurrency", "failure-rate-window", "size-warmup-checks", "max-concurrent-elf", "redis-buffer-size", "log-buffer-lines", "trigger-timeout", "alertmanager-silence-duration", "compare-window", "max-discovered-urls", "eval-failures", "eval-window", "cache-hit-window"}
+
+// nonNegativeFlags must not be negative
+var nonNegativeFlags = []string{"backoff-increment", "backoff-reset-after", "elf-retries", "max-elf-executions", "min-csp-directives", "vault-renew-before", "dns-refresh", "test-latency-ms", "cert-critical-days", "cert-renew-wait", "heartbeat-interval", "self-check-interval", "max-goroutines", "max-heap-mb", "size-rebaseline-checks"}
+
+// flagValue returns the current value of a registered flag
+func flagValue(name string) any {
+	return flag.Lookup(name).Value.(flag.Getter).Get()
+}
+
+// validateConfig checks the parsed flags without starting the monitor
+// Every problem found is returned rather than stopping at the first
+func validateConfig() []error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	// The URL must parse and use a scheme the check mode understands
+	mode, err := parseMode(flagValue("mode").(string))
+	if err != nil {
+		fail("-mode: %v", err)
+	}
+	rawURL := flagValue("url").(string)
+	testMode := flagValue("test-mode").(bool)
+	configDir := flagValue("config-dir").(string)
+	if rawURL == "" && flagValue("tail-log").(string) == "" && !testMode && configDir == "" {
+		fail("-url is required")
+	} else if rawURL != "" && !testMode {
+		if mode == "" {
+			mode = ModeHTTP
+		}
+		if _, _, err := resolveMode(mode, rawURL); err != nil {
+			fail("-url: %v", err)
+		}
+	}
+
+	// Binaries must exist and be executable
+	checkExecutable := func(name string, required bool) {
+		path := flagValue(name).(string)
+		if path == "" {
+			if required {
+				fail("-%s is required", name)
+			}
+			return
+		}
+		if err := validateExecutable(path); err != nil {
+			fail("-%s: %v", name, err)
+		}
+	}
+	checkExecutable("elf", flagValue("healing-actions").(string) == "")
+	checkExecutable("dns-change-elf", false)
+	checkExecutable("cert-renew-elf", false)
+	checkExecutable("canary-elf", false)
+
+	// Numeric flags
+	for _, name := range positiveFlags {
+		if value := flagValue(name).(int); value <= 0 {
+			fail("-%s must be positive, got %d", name, value)
+		}
+	}
+	for _, name := range nonNegativeFlags {
+		if value := flagValue(name).(int); value < 0 {
+			fail("-%s must not be negative, got %d", name, value)
+		}
+	}
+	if retries := flagValue("retries").(int); retries < 1 {
+		fail("-retries must be at least 1, got %d", retries)
+	}
+	if factor := flagValue("backoff-factor").(float64); factor < 1.0 {
+		fail("-backoff-factor must be at least 1.0, got %g", factor)
+	}
+	if threshold := flagValue("compare-threshold").(float64); threshold < 0 {
+		fail("-compare-threshold must not be negative, got %g", threshold)
+	}
+	if _, err := parseBackoffMode(flagValue("backoff-mode").(string)); err != nil {
+		fail("-backoff-mode: %v", err)
+	}
+	if _, err := parseJitterMode(flagValue("jitter-mode").(string)); err != nil {
+		fail("-jitter-mode: %v", err)
+	}
+	if _, err := parseEvalMode(flagValue("eval-mode").(string)); err != nil {
+		fail("-eval-mode: %v", err)
+	}
+	if failures, window := flagValue("eval-failures").(int), flagValue("eval-window").(int); failures > window {
+		fail("-eval-failures %d must not exceed -eval-window %d", failures, window)
+	}
+	if score := flagValue("min-security-score").(int); score < 0 || score > 100 {
+		fail("-min-security-score must be between 0 and 100, got %d", score)
+	}
+	if code := flagValue("test-status-code").(int); code < 100 || code > 599 {
+		fail("-test-status-code must be between 100 and 599, got %d", code)
+	}
+	if threshold := flagValue("error-rate-threshold").(float64); threshold < 0 || threshold > 100 {
+		fail("-error-rate-threshold must be between 0 and 100, got %g", threshold)
+	}
+	if method := flagValue("method").(string); method == "" || strings.ContainsAny(method, " \t\r\n") {
+		fail("-method: invalid HTTP method %q", method)
+	}
+	if flagValue("request-body").(string) != "" && flagValue("request-body-file").(string) != "" {
+		fail("-request-body and -request-body-file are mutually exclusive")
+	}
+	if rate := flagValue("failure-rate-alert").(string); rate != "" {
+		if _, err := parseFailureRate(rate); err != nil {
+			fail("-failure-rate-alert: %v", err)
+		}
+	}
+	if stddev := flagValue("size-alert-stddev").(float64); stddev < 0 {
+		fail("-size-alert-stddev must not be negative, got %g", stddev)
+	}
+	if rate := flagValue("min-cache-hit-rate").(float64); rate < 0 || rate > 100 {
+		fail("-min-cache-hit-rate must be between 0 and 100, got %g", rate)
+	}
+	if slowdown := flagValue("canary-max-slowdown").(float64); slowdown < 0 {
+		fail("-canary-max-slowdown must not be negative, got %g", slowdown)
+	}
+
+	// Lists of URLs and addresses
+	if list := flagValue("mirror-urls").(string); list != "" {
+		if _, err := parseMirrors(list, "https"); err != nil {
+			fail("-mirror-urls: %v", err)
+		}
+	}
+	if list := flagValue("bind-addresses").(string); list != "" {
+		if _, err := parseBindAddresses(list); err != nil {
+			fail("-bind-addresses: %v", err)
+		}
+	}
+	if canaryURL := flagValue("canary-url").(string); canaryURL != "" {
+		if u, err := url.Parse(canaryURL); err != nil {
+			fail("-canary-url: %v", err)
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			fail("-canary-url: scheme must be http or https, got %q", u.Scheme)
+		}
+	}
+	for _, name := range []string{"compare-url", "vault-addr", "pushgateway-addr"} {
+		value := flagValue(name).(string)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "://") {
+			value = "http://" + value
+		}
+		if u, err := url.Parse(value); err != nil {
+			fail("-%s: %v", name, err)
+		} else if u.Host == "" && name != "compare-url" {
+			fail("-%s: missing host", name)
+		}
+	}
+	if version := flagValue("min-tls-version").(string); version != "" {
+		if _, err := parseTLSVersion(version); err != nil {
+			fail("-min-tls-version: %v", err)
+		}
+	}
+	if list := flagValue("require-cipher").(string); list != "" {
+		if _, err := parseCipherSuites(list); err != nil {
+			fail("-require-cipher: %v", err)
+		}
+	}
+	if list := flagValue("grpc-check-options").(string); list != "" {
+		if _, err := parsePluginOptions(list); err != nil {
+			fail("-grpc-check-options: %v", err)
+		}
+	}
+	if spec := flagValue("compound-checks").(string); spec != "" {
+		if _, err := parseCompoundSteps(spec); err != nil {
+			fail("-compound-checks: %v", err)
+		}
+	}
+
+	// Patterns must compile
+	if pattern := flagValue("log-pattern").(string); pattern != "" || flagValue("tail-log").(string) != "" {
+		if _, err := newLogLineParser(flagValue("log-format").(string), pattern); err != nil {
+			fail("-log-pattern: %v", err)
+		}
+	}
+	if pattern := flagValue("canary-body-regex").(string); pattern != "" {
+		if _, err := regexp.Compile(pattern); err != nil {
+			fail("-canary-body-regex: %v", err)
+		}
+	}
+
+	// Files must be readable and well formed
+	if configDir != "" {
+		if entries, err := os.ReadDir(configDir); err != nil {
+			fail("-config-dir: %v", err)
+		} else {
+			for _, entry := range entries {
+				if entry.IsDir() || !isCheckFile(entry.Name()) {
+					continue
+				}
+				path := filepath.Join(configDir, entry.Name())
+				if _, err := loadCheckFile(path); err != nil {
+					fail("-config-dir: %s: %v", path, err)
+				}
+			}
+		}
+	}
+	if path := flagValue("blocklist-file").(string); path != "" {
+		if _, err := loadBlocklist(path); err != nil {
+			fail("-blocklist-file: %v", err)
+		}
+	}
+	if path := flagValue("config").(string); path != "" {
+		if _, err := loadFlagSettings(path); err != nil {
+			fail("-config: %v", err)
+		}
+	}
+	if path := flagValue("tenants-file").(string); path != "" {
+		if _, err := loadTenantKeys(path); err != nil {
+			fail("-tenants-file: %v", err)
+		}
+	}
+	if path := flagValue("healing-actions").(string); path != "" {
+		if _, err := loadHealingRegistry(path); err != nil {
+			fail("-healing-actions: %v", err)
+		}
+	}
+	if path := flagValue("json-schema").(string); path != "" {
+		if _, err := loadJSONSchema(path); err != nil {
+			fail("-json-schema: %v", err)
+		}
+	}
+	if path := flagValue("path-params").(string); path != "" {
+		var params map[string]string
+		if data, err := os.ReadFile(path); err != nil {
+			fail("-path-params: %v", err)
+		} else if err := json.Unmarshal(data, &params); err != nil {
+			fail("-path-params: %v", err)
+		}
+	}
+	for _, name := range []string{"gql-query-file", "tail-log", "openapi-spec", "vault-token-file", "request-body-file"} {
+		if path := flagValue(name).(string); path != "" {
+			if f, err := os.Open(path); err != nil {
+				fail("-%s: %v", name, err)
+			} else {
+				f.Close()
+			}
+		}
+	}
+
+	// A certificate pin is a base64 SHA-256 digest
+	if pin := flagValue("cert-pin").(string); pin != "" {
+		if digest, err := base64.StdEncoding.DecodeString(pin); err != nil || len(digest) != 32 {
+			fail("-cert-pin must be a base64-encoded SHA-256 digest")
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// vaultSource reads HTTP credentials from a HashiCorp Vault secret
+// The secret may hold username and password fields, or a token or bearer_token field
+type vaultSource struct {
+	addr   string
+	path   string
+	token  string
+	client *http.Client
+}
+
+// newVaultSource creates a Vault credential source
+// The Vault token comes from VAULT_TOKEN, tokenFile, or ~/.vault-token in that order
+func newVaultSource(addr, path, tokenFile string, timeout time.Duration) (*vaultSource, error) {
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		if tokenFile == "" {
+			home, _ := os.UserHomeDir()
+			tokenFile = filepath.Join(home, ".vault-token")
+		}
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("no VAULT_TOKEN set and cannot read token file: %v", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token == "" {
+		return nil, errors.New("Vault token is empty")
+	}
+
+	return &vaultSource{
+		addr:   strings.TrimSuffix(addr, "/"),
+		path:   strings.Trim(path, "/"),
+		token:  token,
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// name implements credentialSource
+func (v *vaultSource) name() string {
+	return "Vault " + v.path
+}
+
+// fetch implements credentialSource using the lease duration as the lifetime
+func (v *vaultSource) fetch() (httpCredentials, time.Duration, error) {
+	req, err := http.NewRequest(http.MethodGet, v.addr+"/v1/"+v.path, nil)
+	if err != nil {
+		return httpCredentials{}, 0, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return httpCredentials{}, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return httpCredentials{}, 0, fmt.Errorf("Vault returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var secret struct {
+		LeaseDuration int            `json:"lease_duration"`
+		Data          map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return httpCredentials{}, 0, fmt.Errorf("decoding Vault response: %v", err)
+	}
+
+	// KV version 2 nests the secret under data.data
+	data := secret.Data
+	if nested, ok := data["data"].(map[string]any); ok {
+		data = nested
+	}
+
+	field := func(names ...string) string {
+		for _, name := range names {
+			if s, ok := data[name].(string); ok && s != "" {
+				return s
+			}
+		}
+		return ""
+	}
+	creds := httpCredentials{
+		Username:    field("username"),
+		Password:    field("password"),
+		BearerToken: field("bearer_token", "token"),
+	}
+	if creds == (httpCredentials{}) {
+		return httpCredentials{}, 0, errors.New("Vault secret has no username, password or token fields")
+	}
+
+	return creds, time.Duration(secret.LeaseDuration) * time.Second, nil
+}
@@ -0,0 +1,5 @@
+package main
+
+// Version is the websitecheck release, set at build time with
+// -ldflags "-X main.Version=v1.2.3"
+var Version = "dev"
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// watchdog exits the process when a check cycle runs for longer than its timeout
+// so a supervisor such as systemd or Docker can restart a stalled monitor.
+// Only time spent inside a cycle counts, not the sleep between cycles.
+type watchdog struct {
+	timeout time.Duration
+
+	// cycleStart is the start of the running cycle in Unix nanoseconds, or 0 between cycles
+	cycleStart atomic.Int64
+}
+
+// newWatchdog starts a watchdog that fires after timeout
+func newWatchdog(timeout time.Duration) *watchdog {
+	w := &watchdog{timeout: timeout}
+
+	interval := timeout / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			w.inspect()
+		}
+	}()
+	return w
+}
+
+// begin marks the start of a check cycle
+func (w *watchdog) begin() {
+	w.cycleStart.Store(time.Now().UnixNano())
+}
+
+// end marks the end of a check cycle
+func (w *watchdog) end() {
+	w.cycleStart.Store(0)
+}
+
+// inspect exits if the running cycle has exceeded the timeout
+func (w *watchdog) inspect() {
+	start := w.cycleStart.Load()
+	if start == 0 {
+		return
+	}
+	if elapsed := time.Since(time.Unix(0, start)); elapsed > w.timeout {
+		log.Printf("FATAL: check cycle has been running for %s, longer than the %s watchdog timeout; exiting so the monitor can be restarted", elapsed.Round(time.Second), w.timeout)
+		os.Exit(1)
+	}
+}
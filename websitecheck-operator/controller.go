@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// apiPort is the port the websitecheck API listens on inside each Pod
+// The operator reads GET /status from it to fill in the resource status
+const apiPort = 8080
+
+// defaultStatusInterval is how often the status is refreshed when the spec has no interval
+const defaultStatusInterval = 60 * time.Second
+
+// websiteCheckReconciler keeps a websitecheck Deployment in line with each WebsiteCheck
+type websiteCheckReconciler struct {
+	client.Client
+	image      string
+	httpClient *http.Client
+}
+
+// setupWithManager registers the reconciler for WebsiteCheck resources and the
+// Deployments they own, so edits to a Deployment are reverted
+func (r *websiteCheckReconciler) setupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&WebsiteCheck{}).
+		Owns(&appsv1.Deployment{}).
+		Complete(r)
+}
+
+// Reconcile creates or updates the Deployment for a WebsiteCheck and refreshes its status
+// Deleted resources need no work: the Deployment is garbage collected through its owner reference
+func (r *websiteCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var check WebsiteCheck
+	if err := r.Get(ctx, req.NamespacedName, &check); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: check.Name, Namespace: check.Namespace}}
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, deployment, func() error {
+		r.buildDeployment(&check, deployment)
+		return controllerutil.SetControllerReference(&check, deployment, r.Scheme())
+	})
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("reconciling Deployment %s: %w", req.NamespacedName, err)
+	}
+	if op != controllerutil.OperationResultNone {
+		logger.Info("Deployment reconciled", "deployment", req.NamespacedName, "operation", op)
+	}
+
+	interval := defaultStatusInterval
+	if check.Spec.Interval > 0 {
+		interval = time.Duration(check.Spec.Interval) * time.Second
+	}
+
+	status, err := r.podStatus(ctx, &check)
+	if err != nil {
+		logger.Info("Status not available yet", "error", err.Error())
+		return ctrl.Result{RequeueAfter: interval}, nil
+	}
+	status.ObservedGeneration = check.Generation
+	check.Status = *status
+	if err := r.Status().Update(ctx, &check); err != nil {
+		if apierrors.IsConflict(err) {
+			return ctrl.Result{Requeue: true}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// podLabels are the labels that select the Pods of a WebsiteCheck
+func podLabels(check *WebsiteCheck) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       "websitecheck",
+		"app.kubernetes.io/instance":   check.Name,
+		"app.kubernetes.io/managed-by": "websitecheck-operator",
+	}
+}
+
+// buildDeployment sets the desired state of the Deployment for check
+// Only the fields the operator owns are set so defaults filled in by the API server are kept
+func (r *websiteCheckReconciler) buildDeployment(check *WebsiteCheck, deployment *appsv1.Deployment) {
+	labels := podLabels(check)
+	image := check.Spec.Image
+	if image == "" {
+		image = r.image
+	}
+
+	replicas := int32(1)
+	deployment.Labels = labels
+	deployment.Spec.Replicas = &replicas
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+	deployment.Spec.Template.Labels = labels
+
+	container := corev1.Container{
+		Name:  "websitecheck",
+		Image: image,
+		Args:  deploymentArgs(check.Spec),
+		Ports: []corev1.ContainerPort{{Name: "api", ContainerPort: apiPort}},
+	}
+	if len(deployment.Spec.Template.Spec.Containers) == 1 {
+		existing := deployment.Spec.Template.Spec.Containers[0]
+		existing.Name = container.Name
+		existing.Image = container.Image
+		existing.Args = container.Args
+		existing.Ports = container.Ports
+		container = existing
+	}
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{container}
+}
+
+// deploymentArgs converts a spec into websitecheck command line flags
+// The typed fields take precedence over the same flag in Flags
+func deploymentArgs(spec WebsiteCheckSpec) []string {
+	flags := make(map[string]string, len(spec.Flags)+7)
+	for name, value := range spec.Flags {
+		flags[name] = value
+	}
+	flags["url"] = spec.URL
+	flags["elf"] = spec.ELF
+	if spec.Interval > 0 {
+		flags["interval"] = strconv.Itoa(spec.Interval)
+	}
+	if spec.Timeout > 0 {
+		flags["timeout"] = strconv.Itoa(spec.Timeout)
+	}
+	if spec.Retries > 0 {
+		flags["retries"] = strconv.Itoa(spec.Retries)
+	}
+	if spec.Mode != "" {
+		flags["mode"] = spec.Mode
+	}
+	flags["api-addr"] = ":" + strconv.Itoa(apiPort)
+
+	// Sort the flags so an unchanged spec never looks like an update
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := make([]string, 0, len(names))
+	for _, name := range names {
+		args = append(args, "-"+name+"="+flags[name])
+	}
+	return args
+}
+
+// podCheckStatus is the part of a GET /status entry the operator uses
+type podCheckStatus struct {
+	URL                 string    `json:"url"`
+	Up                  bool      `json:"up"`
+	LastCheck           time.Time `json:"last_check"`
+	LastError           string    `json:"last_error"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+}
+
+// podStatus reads the latest check outcome from a ready Pod of check
+func (r *websiteCheckReconciler) podStatus(ctx context.Context, check *WebsiteCheck) (*WebsiteCheckStatus, error) {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(check.Namespace), client.MatchingLabels(podLabels(check))); err != nil {
+		return nil, err
+	}
+
+	var podIP string
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodRunning && pod.Status.PodIP != "" && podReady(&pod) {
+			podIP = pod.Status.PodIP
+			break
+		}
+	}
+	if podIP == "" {
+		return nil, fmt.Errorf("no ready Pod for %s/%s", check.Namespace, check.Name)
+	}
+
+	statusURL := "http://" + net.JoinHostPort(podIP, strconv.Itoa(apiPort)) + "/status"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, statusURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned status %d", statusURL, resp.StatusCode)
+	}
+
+	var report struct {
+		Checks []podCheckStatus `json:"checks"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, fmt.Errorf("invalid status from %s: %v", statusURL, err)
+	}
+	if len(report.Checks) == 0 {
+		return nil, fmt.Errorf("no checks have run in %s/%s yet", check.Namespace, check.Name)
+	}
+
+	// The Pod reports the canonical URL, so fall back to the first check
+	latest := report.Checks[0]
+	for _, c := range report.Checks {
+		if c.URL == check.Spec.URL {
+			latest = c
+			break
+		}
+	}
+	lastCheck := metav1.NewTime(latest.LastCheck)
+	return &WebsiteCheckStatus{
+		LastCheck:           &lastCheck,
+		IsUp:                latest.Up,
+		ConsecutiveFailures: latest.ConsecutiveFailures,
+		LastError:           latest.LastError,
+	}, nil
+}
+
+// podReady reports whether the Pod's Ready condition is true
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
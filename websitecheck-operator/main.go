@@ -0,0 +1,64 @@
+// websitecheck-operator runs a websitecheck Deployment for every WebsiteCheck
+// resource in the cluster and reports each check's outcome in the resource status
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+func main() {
+	imageFlag := flag.String("image", "websitecheck:latest", "websitecheck image used when a WebsiteCheck does not set spec.image")
+	metricsAddrFlag := flag.String("metrics-addr", ":8081", "Address to serve controller metrics on (0 disables)")
+	leaderElectFlag := flag.Bool("leader-elect", false, "Use leader election so only one operator replica reconciles at a time")
+	namespaceFlag := flag.String("namespace", "", "Only watch WebsiteCheck resources in this namespace (default all namespaces)")
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New())
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if err := addToScheme(scheme); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	options := ctrl.Options{
+		Scheme:           scheme,
+		Metrics:          metricsserver.Options{BindAddress: *metricsAddrFlag},
+		LeaderElection:   *leaderElectFlag,
+		LeaderElectionID: "websitecheck-operator.websitecheck.pbelx.github.io",
+	}
+	if *namespaceFlag != "" {
+		options.Cache.DefaultNamespaces = map[string]cache.Config{*namespaceFlag: {}}
+	}
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), options)
+	if err != nil {
+		log.Fatalf("Error: Cannot start manager: %v", err)
+	}
+
+	reconciler := &websiteCheckReconciler{
+		Client:     mgr.GetClient(),
+		image:      *imageFlag,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	if err := reconciler.setupWithManager(mgr); err != nil {
+		log.Fatalf("Error: Cannot set up controller: %v", err)
+	}
+
+	log.Printf("Starting websitecheck operator (default image %s)", *imageFlag)
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
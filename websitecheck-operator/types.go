@@ -0,0 +1,108 @@
+package main
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// groupVersion is the API group and version of the WebsiteCheck resource
+var groupVersion = schema.GroupVersion{Group: "websitecheck.pbelx.github.io", Version: "v1alpha1"}
+
+// addToScheme registers the WebsiteCheck types
+func addToScheme(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(groupVersion, &WebsiteCheck{}, &WebsiteCheckList{})
+	metav1.AddToGroupVersion(scheme, groupVersion)
+	return nil
+}
+
+// WebsiteCheck describes one monitored website
+// The spec uses the same names as the websitecheck flags and ConfigMap keys
+type WebsiteCheck struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WebsiteCheckSpec   `json:"spec"`
+	Status WebsiteCheckStatus `json:"status,omitempty"`
+}
+
+// WebsiteCheckSpec is the configuration of the websitecheck Deployment
+type WebsiteCheckSpec struct {
+	URL      string `json:"url"`
+	ELF      string `json:"elf"`
+	Interval int    `json:"interval,omitempty"`
+	Timeout  int    `json:"timeout,omitempty"`
+	Retries  int    `json:"retries,omitempty"`
+	Mode     string `json:"mode,omitempty"`
+
+	// Flags holds any other websitecheck flag by name, e.g. "max-backoff": "600"
+	Flags map[string]string `json:"flags,omitempty"`
+
+	// Image overrides the operator's default websitecheck image
+	Image string `json:"image,omitempty"`
+}
+
+// WebsiteCheckStatus is the latest check outcome reported by the Pod
+type WebsiteCheckStatus struct {
+	LastCheck           *metav1.Time `json:"lastCheck,omitempty"`
+	IsUp                bool         `json:"isUp"`
+	ConsecutiveFailures int          `json:"consecutiveFailures"`
+	LastError           string       `json:"lastError,omitempty"`
+	ObservedGeneration  int64        `json:"observedGeneration,omitempty"`
+}
+
+// WebsiteCheckList is a list of WebsiteCheck resources
+type WebsiteCheckList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []WebsiteCheck `json:"items"`
+}
+
+// DeepCopyInto copies c into out
+func (c *WebsiteCheck) DeepCopyInto(out *WebsiteCheck) {
+	*out = *c
+	out.TypeMeta = c.TypeMeta
+	c.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if c.Spec.Flags != nil {
+		out.Spec.Flags = make(map[string]string, len(c.Spec.Flags))
+		for key, value := range c.Spec.Flags {
+			out.Spec.Flags[key] = value
+		}
+	}
+	if c.Status.LastCheck != nil {
+		out.Status.LastCheck = c.Status.LastCheck.DeepCopy()
+	}
+}
+
+// DeepCopy returns a copy of c
+func (c *WebsiteCheck) DeepCopy() *WebsiteCheck {
+	if c == nil {
+		return nil
+	}
+	out := new(WebsiteCheck)
+	c.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object
+func (c *WebsiteCheck) DeepCopyObject() runtime.Object {
+	return c.DeepCopy()
+}
+
+// DeepCopyObject implements runtime.Object
+func (l *WebsiteCheckList) DeepCopyObject() runtime.Object {
+	if l == nil {
+		return nil
+	}
+	out := new(WebsiteCheckList)
+	*out = *l
+	l.ListMeta.DeepCopyInto(&out.ListMeta)
+	if l.Items != nil {
+		out.Items = make([]WebsiteCheck, len(l.Items))
+		for i := range l.Items {
+			l.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+	return out
+}
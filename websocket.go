@@ -0,0 +1,52 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// websocketGUID is appended to the handshake key to compute Sec-WebSocket-Accept (RFC 6455)
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// checkWebSocket performs a WebSocket opening handshake with target, retrying like checkWebsiteDown
+// target is the http or https form of the ws:// or wss:// URL. The connection is
+// closed as soon as the server has accepted the upgrade
+func checkWebSocket(client *http.Client, target string, opts checkOptions) (*checkResult, error) {
+	return retryCheck("WebSocket", opts, func() (*checkResult, error) {
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		key := base64.StdEncoding.EncodeToString(nonce)
+
+		req, err := http.NewRequest(http.MethodGet, target, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", key)
+
+		start := time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		latency := time.Since(start)
+
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			return nil, fmt.Errorf("WebSocket handshake got status code %d instead of 101", resp.StatusCode)
+		}
+		digest := sha1.Sum([]byte(key + websocketGUID))
+		if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != base64.StdEncoding.EncodeToString(digest[:]) {
+			return nil, fmt.Errorf("WebSocket handshake returned an invalid Sec-WebSocket-Accept %q", accept)
+		}
+		return &checkResult{StatusCode: resp.StatusCode, Header: resp.Header, TLS: resp.TLS, Latency: latency}, nil
+	})
+}